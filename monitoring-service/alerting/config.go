@@ -0,0 +1,224 @@
+package alerting
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"monitoring-service/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultInterval = 5 * time.Minute
+	DefaultRepeat   = 6 * time.Hour
+)
+
+// Rule types.
+const (
+	RuleJailed      = "jailed"
+	RuleHeightLag   = "height_lag"
+	RuleNoClaims    = "no_claims"
+	RuleClaimExpiry = "claim_expiry"
+	RuleLowBalance  = "low_balance"
+)
+
+// Notifier types.
+const (
+	NotifierWebhook = "webhook"
+	NotifierSlack   = "slack"
+	NotifierSMTP    = "smtp"
+)
+
+// Config is the YAML configuration of the alerting engine.
+type Config struct {
+	// Interval is how often the rules are checked.
+	Interval time.Duration `yaml:"interval"`
+	// Repeat is how long an alert that is still firing waits before it is sent again. Zero
+	// defaults to DefaultRepeat, and a negative value sends it only once.
+	Repeat time.Duration `yaml:"repeat"`
+	// RateLimit caps the notifications sent across every rule and notifier.
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	// Nodes are the addresses every rule applies to, unless the rule lists its own.
+	Nodes     []string         `yaml:"nodes"`
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+	Rules     []RuleConfig     `yaml:"rules"`
+}
+
+// RateLimitConfig allows at most Max notifications in any Per long window. A zero Max means no limit.
+type RateLimitConfig struct {
+	Max int           `yaml:"max"`
+	Per time.Duration `yaml:"per"`
+}
+
+type NotifierConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// webhook and slack
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+
+	// smtp; Host is host:port, and Username may be left out for servers without auth
+	Host     string   `yaml:"host"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+type RuleConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// Nodes overrides Config.Nodes for the rule.
+	Nodes []string `yaml:"nodes"`
+	// Notify names the notifiers the rule sends to, by default all of them.
+	Notify []string `yaml:"notify"`
+
+	// Threshold is the POKT balance below which low_balance fires.
+	Threshold float64 `yaml:"threshold"`
+	// Blocks is how far behind the network height_lag fires, and how close to its expiry a
+	// claim without a proof has to be for claim_expiry to fire.
+	Blocks uint `yaml:"blocks"`
+	// Window is how long no_claims waits for a claim before it fires.
+	Window time.Duration `yaml:"window"`
+}
+
+// LoadConfig reads and validates the config in the file at path.
+func LoadConfig(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("LoadConfig: %w", err)
+	}
+	defer file.Close()
+
+	cfg, err := ParseConfig(file)
+	if err != nil {
+		return Config{}, fmt.Errorf("LoadConfig [%s]: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// ParseConfig reads and validates a YAML config, and fills in its defaults.
+func ParseConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		return Config{}, fmt.Errorf("ParseConfig: %w", err)
+	}
+
+	if err := cfg.normalize(); err != nil {
+		return Config{}, fmt.Errorf("ParseConfig: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) normalize() error {
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.Repeat == 0 {
+		c.Repeat = DefaultRepeat
+	}
+	if c.RateLimit.Max < 0 || (c.RateLimit.Max > 0 && c.RateLimit.Per <= 0) {
+		return fmt.Errorf("rateLimit needs a positive max and per")
+	}
+
+	var err error
+	if c.Nodes, err = normalizeAddresses(c.Nodes); err != nil {
+		return err
+	}
+
+	notifiers := make(map[string]bool, len(c.Notifiers))
+	for _, n := range c.Notifiers {
+		if n.Name == "" || notifiers[n.Name] {
+			return fmt.Errorf("notifier names must be set and unique, got %q", n.Name)
+		}
+		notifiers[n.Name] = true
+
+		switch n.Type {
+		case NotifierWebhook, NotifierSlack:
+			if n.URL == "" {
+				return fmt.Errorf("notifier %s: url is required", n.Name)
+			}
+		case NotifierSMTP:
+			if n.Host == "" || n.From == "" || len(n.To) == 0 {
+				return fmt.Errorf("notifier %s: host, from and to are required", n.Name)
+			}
+		default:
+			return fmt.Errorf("notifier %s: unknown type %q", n.Name, n.Type)
+		}
+	}
+
+	rules := make(map[string]bool, len(c.Rules))
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Name == "" || rules[r.Name] {
+			return fmt.Errorf("rule names must be set and unique, got %q", r.Name)
+		}
+		rules[r.Name] = true
+
+		if err = r.validate(); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+
+		if r.Nodes, err = normalizeAddresses(r.Nodes); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		if len(r.Nodes) == 0 {
+			r.Nodes = c.Nodes
+		}
+		if len(r.Nodes) == 0 {
+			return fmt.Errorf("rule %s: no nodes to check", r.Name)
+		}
+
+		for _, name := range r.Notify {
+			if !notifiers[name] {
+				return fmt.Errorf("rule %s: unknown notifier %q", r.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r RuleConfig) validate() error {
+	switch r.Type {
+	case RuleJailed:
+	case RuleHeightLag, RuleClaimExpiry:
+		if r.Blocks == 0 {
+			return fmt.Errorf("blocks is required")
+		}
+	case RuleNoClaims:
+		if r.Window <= 0 {
+			return fmt.Errorf("window is required")
+		}
+	case RuleLowBalance:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("threshold is required")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+
+	return nil
+}
+
+func normalizeAddresses(addresses []string) ([]string, error) {
+	normalized := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		a, err := store.NormalizeHex(strings.TrimSpace(a))
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, a)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,86 @@
+package alerting
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	Status   endpoint.Endpoint
+	SendTest endpoint.Endpoint
+}
+
+type alertResponse struct {
+	Rule     string     `json:"rule"`
+	Type     string     `json:"type"`
+	Address  string     `json:"address"`
+	Message  string     `json:"message"`
+	Since    time.Time  `json:"since"`
+	Notified *time.Time `json:"notified,omitempty"`
+}
+
+type statusResponse struct {
+	LastRun *time.Time      `json:"last_run,omitempty"`
+	Errors  []string        `json:"errors,omitempty"`
+	Firing  []alertResponse `json:"firing"`
+}
+
+func StatusEndpoint(e *Engine) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		status := e.Status()
+
+		resp := statusResponse{
+			Errors: status.Errors,
+			Firing: make([]alertResponse, len(status.Alerts)),
+		}
+		if !status.LastRun.IsZero() {
+			resp.LastRun = &status.LastRun
+		}
+		for i, a := range status.Alerts {
+			resp.Firing[i] = alertResponse{
+				Rule:    a.Rule,
+				Type:    a.Type,
+				Address: a.Address,
+				Message: a.Message,
+				Since:   a.Since,
+			}
+			if !a.Notified.IsZero() {
+				notified := a.Notified
+				resp.Firing[i].Notified = &notified
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+type notifierResultResponse struct {
+	Notifier string `json:"notifier"`
+	Sent     bool   `json:"sent"`
+	Error    string `json:"error,omitempty"`
+}
+
+func SendTestEndpoint(e *Engine) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		errs := e.SendTest(ctx)
+
+		resp := make([]notifierResultResponse, 0, len(e.notifiers))
+		for name := range e.notifiers {
+			result := notifierResultResponse{Notifier: name, Sent: true}
+			if err, failed := errs[name]; failed {
+				result.Sent = false
+				result.Error = err.Error()
+			}
+			resp = append(resp, result)
+		}
+
+		sort.Slice(resp, func(i, j int) bool {
+			return resp[i].Notifier < resp[j].Notifier
+		})
+
+		return resp, nil
+	}
+}
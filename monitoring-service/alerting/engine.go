@@ -0,0 +1,350 @@
+// Package alerting checks nodes against rules configured in YAML on a schedule, and notifies
+// webhooks, Slack-compatible webhooks and email when alerts fire and resolve.
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pchttp "monitoring-service/http"
+	"monitoring-service/pocket"
+
+	"github.com/go-kit/kit/log"
+)
+
+// NodeService supplies the node and claim data that rules are checked against.
+type NodeService interface {
+	Node(ctx context.Context, address string) (pocket.Node, error)
+	AccountClaimsAndProofs(ctx context.Context, address string) (claims, proofs map[string]pocket.Transaction, err error)
+	ClaimsStatusOf(ctx context.Context, claims, proofs map[string]pocket.Transaction) (pocket.ClaimsStatus, error)
+}
+
+// Alert is the state of a rule for a node.
+type Alert struct {
+	Rule     string
+	Type     string
+	Address  string
+	Firing   bool
+	Message  string
+	Since    time.Time
+	Notified time.Time
+}
+
+type alertKey struct {
+	rule    string
+	address string
+}
+
+// Engine checks every rule once per interval. A notification is sent when an alert starts firing,
+// again every Repeat while it keeps firing, and once when it resolves. Notifications over the
+// rate limit are dropped; a firing alert whose notification was dropped is sent on a later check.
+type Engine struct {
+	nodes     NodeService
+	cfg       Config
+	notifiers map[string]Notifier
+	logger    log.Logger
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	alerts   map[alertKey]*Alert
+	sent     []time.Time
+	lastRun  time.Time
+	lastErrs []string
+}
+
+// NewEngine returns an Engine for cfg, which must have been validated by ParseConfig. Webhooks
+// are posted with client.
+func NewEngine(nodes NodeService, cfg Config, client pchttp.Client, logger log.Logger) (*Engine, error) {
+	notifiers := make(map[string]Notifier, len(cfg.Notifiers))
+	for _, nc := range cfg.Notifiers {
+		n, err := NewNotifier(nc, client)
+		if err != nil {
+			return nil, fmt.Errorf("NewEngine: %w", err)
+		}
+		notifiers[nc.Name] = n
+	}
+
+	return NewEngineWithNotifiers(nodes, cfg, notifiers, logger), nil
+}
+
+// NewEngineWithNotifiers returns an Engine that sends to notifiers, by the names the rules in
+// cfg refer to them by, instead of the ones configured in cfg.
+func NewEngineWithNotifiers(nodes NodeService, cfg Config, notifiers map[string]Notifier, logger log.Logger) *Engine {
+	return &Engine{
+		nodes:     nodes,
+		cfg:       cfg,
+		notifiers: notifiers,
+		logger:    logger,
+		now:       time.Now,
+		alerts:    make(map[alertKey]*Alert),
+	}
+}
+
+// Run checks the rules straight away and then once per interval, until ctx is done.
+func (e *Engine) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.Check(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check evaluates every rule and sends the notifications that are due.
+func (e *Engine) Check(ctx context.Context) {
+	var errs []string
+	logError := func(err error) {
+		errs = append(errs, err.Error())
+		_ = e.logger.Log("level", "ERROR", "msg", err.Error())
+	}
+
+	states, err := e.collect(ctx)
+	if err != nil {
+		logError(err)
+	}
+
+	for _, rule := range e.cfg.Rules {
+		for _, address := range rule.Nodes {
+			st, ok := states[address]
+			if !ok {
+				// the node's data couldn't be fetched, so the alert stays as it was
+				continue
+			}
+
+			firing, message := rule.evaluate(st)
+			if err := e.update(ctx, rule, address, firing, message); err != nil {
+				logError(err)
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.lastRun = e.now()
+	e.lastErrs = errs
+	e.mu.Unlock()
+}
+
+// collect fetches the state of every node a rule applies to. Nodes that failed are left out.
+func (e *Engine) collect(ctx context.Context) (map[string]NodeState, error) {
+	withClaims := make(map[string]bool)
+	for _, rule := range e.cfg.Rules {
+		for _, address := range rule.Nodes {
+			withClaims[address] = withClaims[address] || needsClaims(rule.Type)
+		}
+	}
+
+	var errs []string
+	states := make(map[string]NodeState, len(withClaims))
+	for address, claims := range withClaims {
+		st, err := e.nodeState(ctx, address, claims)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		states[address] = st
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return states, fmt.Errorf("Engine.collect: %v", errs)
+	}
+
+	return states, nil
+}
+
+func (e *Engine) nodeState(ctx context.Context, address string, withClaims bool) (NodeState, error) {
+	node, err := e.nodes.Node(ctx, address)
+	if err != nil {
+		return NodeState{}, err
+	}
+
+	st := NodeState{Address: address, Node: node, Now: e.now()}
+	if !withClaims {
+		return st, nil
+	}
+
+	// the history is paged once, for both the status and the last claim
+	claims, proofs, err := e.nodes.AccountClaimsAndProofs(ctx, address)
+	if err != nil {
+		return NodeState{}, err
+	}
+	if st.Claims, err = e.nodes.ClaimsStatusOf(ctx, claims, proofs); err != nil {
+		return NodeState{}, err
+	}
+	for _, c := range claims {
+		if c.Time.After(st.LastClaim) {
+			st.LastClaim = c.Time
+		}
+	}
+
+	return st, nil
+}
+
+// update records the result of a rule for a node, and sends the notification that is due, if any.
+func (e *Engine) update(ctx context.Context, rule RuleConfig, address string, firing bool, message string) error {
+	now := e.now()
+	key := alertKey{rule: rule.Name, address: address}
+
+	e.mu.Lock()
+	alert, exists := e.alerts[key]
+	if !exists {
+		alert = &Alert{Rule: rule.Name, Type: rule.Type, Address: address}
+		e.alerts[key] = alert
+	}
+
+	var status string
+	switch {
+	case firing && !alert.Firing:
+		alert.Firing, alert.Since, alert.Notified = true, now, time.Time{}
+		status = StatusFiring
+	case firing && alert.Notified.IsZero():
+		status = StatusFiring
+	case firing && e.cfg.Repeat > 0 && now.Sub(alert.Notified) >= e.cfg.Repeat:
+		status = StatusFiring
+	case !firing && alert.Firing:
+		// an alert nobody was told about resolves quietly
+		if !alert.Notified.IsZero() {
+			status = StatusResolved
+		}
+		alert.Firing, alert.Notified = false, time.Time{}
+	}
+	if firing {
+		alert.Message = message
+	}
+	n := Notification{Status: status, Rule: rule.Name, Type: rule.Type, Address: address, Message: alert.Message, Since: alert.Since, Time: now}
+
+	if status == "" {
+		e.mu.Unlock()
+		return nil
+	}
+	if !e.allow(now) {
+		e.mu.Unlock()
+		_ = e.logger.Log("level", "WARN", "msg", fmt.Sprintf("Rate limited: %s", n.Summary()))
+		return nil
+	}
+	if status == StatusFiring {
+		alert.Notified = now
+	}
+	e.mu.Unlock()
+
+	sent, err := e.notify(ctx, rule.Notify, n)
+	if sent == 0 && status == StatusFiring {
+		// nobody heard of it, so it is sent again on the next check
+		e.mu.Lock()
+		alert.Notified = time.Time{}
+		e.mu.Unlock()
+	}
+
+	return err
+}
+
+// allow reports whether a notification may be sent at now, and counts it if so. e.mu must be held.
+func (e *Engine) allow(now time.Time) bool {
+	limit := e.cfg.RateLimit
+	if limit.Max == 0 {
+		return true
+	}
+
+	cutoff := now.Add(-limit.Per)
+	kept := e.sent[:0]
+	for _, t := range e.sent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	e.sent = kept
+
+	if len(e.sent) >= limit.Max {
+		return false
+	}
+	e.sent = append(e.sent, now)
+	return true
+}
+
+// notify sends n to the named notifiers, or all of them if names is empty, and returns how many
+// it was sent to.
+func (e *Engine) notify(ctx context.Context, names []string, n Notification) (int, error) {
+	if len(names) == 0 {
+		for name := range e.notifiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var sent int
+	var errs []string
+	for _, name := range names {
+		notifier, ok := e.notifiers[name]
+		if !ok {
+			continue
+		}
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		_ = e.logger.Log("level", "INFO", "msg", fmt.Sprintf("Sent %s to %d notifiers", n.Summary(), sent))
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("Engine.notify(%s): %v", n.Summary(), errs)
+	}
+
+	return sent, nil
+}
+
+// SendTest sends a test notification to every notifier, bypassing the rate limit, and returns the
+// error of each notifier that failed, by name.
+func (e *Engine) SendTest(ctx context.Context) map[string]error {
+	now := e.now()
+	n := Notification{Status: StatusTest, Rule: "test", Type: StatusTest, Message: "Test notification from the POKT calculator monitoring service", Since: now, Time: now}
+
+	errs := make(map[string]error)
+	for name, notifier := range e.notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs[name] = err
+		}
+	}
+
+	return errs
+}
+
+// Status is the state of the engine's alerts.
+type Status struct {
+	LastRun time.Time
+	Errors  []string
+	// Alerts are every alert that is firing, by rule and address.
+	Alerts []Alert
+}
+
+func (e *Engine) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	status := Status{LastRun: e.lastRun, Errors: e.lastErrs, Alerts: []Alert{}}
+	for _, a := range e.alerts {
+		if a.Firing {
+			status.Alerts = append(status.Alerts, *a)
+		}
+	}
+
+	sort.Slice(status.Alerts, func(i, j int) bool {
+		if status.Alerts[i].Rule != status.Alerts[j].Rule {
+			return status.Alerts[i].Rule < status.Alerts[j].Rule
+		}
+		return status.Alerts[i].Address < status.Alerts[j].Address
+	})
+
+	return status
+}
@@ -0,0 +1,332 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"monitoring-service/pocket"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	nodeA = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	nodeB = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// fakeNodes serves nodes and their claims from memory, and counts how often the claims are paged.
+type fakeNodes struct {
+	mu          sync.Mutex
+	nodes       map[string]pocket.Node
+	claims      map[string]map[string]pocket.Transaction
+	claimsCalls int
+}
+
+func newFakeNodes() *fakeNodes {
+	return &fakeNodes{nodes: make(map[string]pocket.Node), claims: make(map[string]map[string]pocket.Transaction)}
+}
+
+func (f *fakeNodes) setNode(node pocket.Node) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nodes[node.Address] = node
+}
+
+func (f *fakeNodes) Node(ctx context.Context, address string) (pocket.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[address]
+	if !ok {
+		return pocket.Node{}, errors.New("node not found")
+	}
+	return node, nil
+}
+
+func (f *fakeNodes) AccountClaimsAndProofs(ctx context.Context, address string) (claims, proofs map[string]pocket.Transaction, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claimsCalls++
+	return f.claims[address], map[string]pocket.Transaction{}, nil
+}
+
+func (f *fakeNodes) ClaimsStatusOf(ctx context.Context, claims, proofs map[string]pocket.Transaction) (pocket.ClaimsStatus, error) {
+	status := pocket.ClaimsStatus{Height: 1000}
+	for _, c := range claims {
+		status.Pending = append(status.Pending, pocket.UnprovenClaim{Claim: c, BlocksUntilExpiry: c.ExpireHeight - status.Height})
+	}
+	return status, nil
+}
+
+// webhook is a local webhook that records the notifications posted to it, and answers status.
+type webhook struct {
+	*httptest.Server
+	mu     sync.Mutex
+	status int
+	got    []Notification
+}
+
+func newWebhook(t *testing.T) *webhook {
+	t.Helper()
+
+	w := &webhook{status: http.StatusOK}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decoding notification: %v", err)
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.status == http.StatusOK {
+			w.got = append(w.got, n)
+		}
+		rw.WriteHeader(w.status)
+	}))
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+func (w *webhook) setStatus(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = status
+}
+
+// take returns the notifications received since the last call.
+func (w *webhook) take() []Notification {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	got := w.got
+	w.got = nil
+	return got
+}
+
+func (w *webhook) notifier() Notifier {
+	return WebhookNotifier{URL: w.URL, Client: w.Client()}
+}
+
+// testEngine returns an engine for rules that notifies hook, and a clock that can be moved.
+func testEngine(nodes NodeService, cfg Config, hook *webhook) (*Engine, *time.Time) {
+	e := NewEngineWithNotifiers(nodes, cfg, map[string]Notifier{"hook": hook.notifier()}, log.NewNopLogger())
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	return e, &now
+}
+
+func wantNotifications(t *testing.T, hook *webhook, want ...string) {
+	t.Helper()
+
+	got := hook.take()
+	var summaries []string
+	for _, n := range got {
+		summaries = append(summaries, n.Summary())
+	}
+	if strings.Join(summaries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("notifications:\n%s\nwant:\n%s", strings.Join(summaries, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestEngineFiresRepeatsAndResolves(t *testing.T) {
+	nodes, hook := newFakeNodes(), newWebhook(t)
+	nodes.setNode(pocket.Node{Address: nodeA, IsJailed: true})
+	cfg := Config{Repeat: time.Hour, Rules: []RuleConfig{{Name: "jail", Type: RuleJailed, Nodes: []string{nodeA}}}}
+	e, now := testEngine(nodes, cfg, hook)
+
+	e.Check(context.Background())
+	wantNotifications(t, hook, "[FIRING] jail: "+nodeA)
+	if s := e.Status(); len(s.Alerts) != 1 || s.Alerts[0].Message != "node is jailed" {
+		t.Errorf("Status: %+v, want the jail alert firing", s)
+	}
+
+	// still firing, but not due again yet
+	*now = now.Add(30 * time.Minute)
+	e.Check(context.Background())
+	wantNotifications(t, hook)
+
+	*now = now.Add(31 * time.Minute)
+	e.Check(context.Background())
+	wantNotifications(t, hook, "[FIRING] jail: "+nodeA)
+
+	nodes.setNode(pocket.Node{Address: nodeA})
+	e.Check(context.Background())
+	wantNotifications(t, hook, "[RESOLVED] jail: "+nodeA)
+	if s := e.Status(); len(s.Alerts) != 0 {
+		t.Errorf("Status: %+v, want no alerts", s)
+	}
+
+	e.Check(context.Background())
+	wantNotifications(t, hook)
+}
+
+func TestEngineRepeatsFailedNotifications(t *testing.T) {
+	nodes, hook := newFakeNodes(), newWebhook(t)
+	nodes.setNode(pocket.Node{Address: nodeA, IsJailed: true})
+	cfg := Config{Repeat: time.Hour, Rules: []RuleConfig{{Name: "jail", Type: RuleJailed, Nodes: []string{nodeA}}}}
+	e, _ := testEngine(nodes, cfg, hook)
+
+	hook.setStatus(http.StatusInternalServerError)
+	e.Check(context.Background())
+	if s := e.Status(); len(s.Errors) != 1 {
+		t.Errorf("Status errors: %v, want the failed notification", s.Errors)
+	}
+
+	// nobody was told, so the next check sends it without waiting for Repeat
+	hook.setStatus(http.StatusOK)
+	e.Check(context.Background())
+	wantNotifications(t, hook, "[FIRING] jail: "+nodeA)
+}
+
+func TestEngineRateLimit(t *testing.T) {
+	nodes, hook := newFakeNodes(), newWebhook(t)
+	nodes.setNode(pocket.Node{Address: nodeA, IsJailed: true})
+	nodes.setNode(pocket.Node{Address: nodeB, IsJailed: true})
+	cfg := Config{
+		Repeat:    6 * time.Hour,
+		RateLimit: RateLimitConfig{Max: 1, Per: time.Hour},
+		Rules:     []RuleConfig{{Name: "jail", Type: RuleJailed, Nodes: []string{nodeA, nodeB}}},
+	}
+	e, now := testEngine(nodes, cfg, hook)
+
+	e.Check(context.Background())
+	wantNotifications(t, hook, "[FIRING] jail: "+nodeA)
+
+	// the limit still holds within the hour
+	*now = now.Add(30 * time.Minute)
+	e.Check(context.Background())
+	wantNotifications(t, hook)
+
+	// the dropped alert is sent once the window has passed, and the first isn't due again yet
+	*now = now.Add(31 * time.Minute)
+	e.Check(context.Background())
+	wantNotifications(t, hook, "[FIRING] jail: "+nodeB)
+}
+
+func TestEngineClaimRules(t *testing.T) {
+	nodes, hook := newFakeNodes(), newWebhook(t)
+	nodes.setNode(pocket.Node{Address: nodeA})
+	e, now := testEngine(nodes, Config{
+		Repeat: time.Hour,
+		Rules: []RuleConfig{
+			{Name: "quiet", Type: RuleNoClaims, Nodes: []string{nodeA}, Window: time.Hour},
+			{Name: "expiring", Type: RuleClaimExpiry, Nodes: []string{nodeA}, Blocks: 5},
+		},
+	}, hook)
+	lastClaim := now.Add(-2 * time.Hour)
+	nodes.claims[nodeA] = map[string]pocket.Transaction{
+		"old": {Time: lastClaim.Add(-time.Hour), ExpireHeight: 1100, NumRelays: 10, PoktPerRelay: 0.5},
+		"new": {Time: lastClaim, ExpireHeight: 1003, NumRelays: 10, PoktPerRelay: 0.5},
+	}
+
+	e.Check(context.Background())
+	got := hook.take()
+	if len(got) != 2 {
+		t.Fatalf("got %d notifications, want 2: %+v", len(got), got)
+	}
+	for _, n := range got {
+		var want string
+		switch n.Rule {
+		case "quiet":
+			want = "no claims for 2h0m0s (last at 2022-06-01T10:00:00Z)"
+		case "expiring":
+			want = "1 claims without a proof expire within 5 blocks (5.00 POKT)"
+		}
+		if n.Message != want {
+			t.Errorf("%s: %q, want %q", n.Rule, n.Message, want)
+		}
+	}
+
+	// both rules are checked against a single page through the node's history
+	if nodes.claimsCalls != 1 {
+		t.Errorf("claims were fetched %d times in one check, want 1", nodes.claimsCalls)
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	var (
+		gotAddr string
+		gotTo   []string
+		gotMsg  string
+	)
+	send := func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, string(msg)
+		return nil
+	}
+	n := SMTPNotifier{Host: "mail.example.com:587", From: "alerts@example.com", To: []string{"ops@example.com"}, Send: send}
+
+	err := n.Notify(context.Background(), Notification{Status: StatusFiring, Rule: "jail", Type: RuleJailed, Address: nodeA, Message: "node is jailed"})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if gotAddr != n.Host || len(gotTo) != 1 || gotTo[0] != "ops@example.com" {
+		t.Errorf("sent to %s %v, want %s [ops@example.com]", gotAddr, gotTo, n.Host)
+	}
+	for _, want := range []string{"Subject: [FIRING] jail: " + nodeA + "\r\n", "node is jailed"} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message doesn't contain %q:\n%s", want, gotMsg)
+		}
+	}
+}
+
+func TestSendMailGivesUpWithContext(t *testing.T) {
+	// a server that accepts connections and never greets
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = SendMail(ctx, l.Addr().String(), nil, "alerts@example.com", []string{"ops@example.com"}, []byte("hi"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendMail to a silent server: %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SendMail took %s with a 50ms deadline", elapsed)
+	}
+}
+
+func TestHeightLagRule(t *testing.T) {
+	rule := RuleConfig{Name: "lag", Type: RuleHeightLag, Blocks: 5}
+	tests := []struct {
+		name        string
+		node        pocket.Node
+		wantFiring  bool
+		wantMessage string
+	}{
+		{"unreachable", pocket.Node{RPCError: "connection refused"}, true, "node's RPC is unreachable: connection refused"},
+		{"network unknown", pocket.Node{IsReachable: true, LatestBlockHeight: 90}, false, ""},
+		{"within tolerance", pocket.Node{IsReachable: true, LatestBlockHeight: 95, NetworkHeight: 100, LagBlocks: 5}, false, ""},
+		{"behind", pocket.Node{IsReachable: true, LatestBlockHeight: 94, NetworkHeight: 100, LagBlocks: 6}, true, "node is 6 blocks behind the network (height 94, network 100)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firing, message := rule.evaluate(NodeState{Node: tt.node})
+			if firing != tt.wantFiring || message != tt.wantMessage {
+				t.Errorf("evaluate: %t %q, want %t %q", firing, message, tt.wantFiring, tt.wantMessage)
+			}
+		})
+	}
+}
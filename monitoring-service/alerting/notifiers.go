@@ -0,0 +1,268 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	pchttp "monitoring-service/http"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+	// StatusTest is the status of the notifications sent by Engine.SendTest.
+	StatusTest = "test"
+
+	maxErrorBodyBytes = 512
+
+	// smtpTimeout bounds a whole SMTP conversation, when ctx doesn't end it sooner.
+	smtpTimeout = 30 * time.Second
+)
+
+// Notification is sent when an alert starts firing, is repeated or is resolved.
+type Notification struct {
+	Status  string    `json:"status"`
+	Rule    string    `json:"rule"`
+	Type    string    `json:"type"`
+	Address string    `json:"address"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+	Time    time.Time `json:"time"`
+}
+
+// Summary is a one line description of n, for chat and email subjects.
+func (n Notification) Summary() string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(n.Status), n.Rule, n.Address)
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NewNotifier returns the notifier configured by cfg. Webhooks are posted with client.
+func NewNotifier(cfg NotifierConfig, client pchttp.Client) (Notifier, error) {
+	switch cfg.Type {
+	case NotifierWebhook:
+		return WebhookNotifier{URL: cfg.URL, Headers: cfg.Headers, Client: client}, nil
+	case NotifierSlack:
+		return SlackNotifier{URL: cfg.URL, Client: client}, nil
+	case NotifierSMTP:
+		return SMTPNotifier{Host: cfg.Host, Username: cfg.Username, Password: cfg.Password, From: cfg.From, To: cfg.To, Send: SendMail}, nil
+	default:
+		return nil, fmt.Errorf("NewNotifier: unknown type %q", cfg.Type)
+	}
+}
+
+// WebhookNotifier posts every Notification as JSON to URL.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  pchttp.Client
+}
+
+func (w WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	if err := postJSON(ctx, w.Client, w.URL, w.Headers, n); err != nil {
+		return fmt.Errorf("WebhookNotifier.Notify: %w", err)
+	}
+
+	return nil
+}
+
+// SlackNotifier posts a text message to a Slack incoming webhook, or to anything accepting the
+// same payload, such as Mattermost or Discord's /slack webhooks.
+type SlackNotifier struct {
+	URL    string
+	Client pchttp.Client
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func (s SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	msg := slackMessage{Text: fmt.Sprintf("*%s*\n%s", n.Summary(), n.Message)}
+	if err := postJSON(ctx, s.Client, s.URL, nil, msg); err != nil {
+		return fmt.Errorf("SlackNotifier.Notify: %w", err)
+	}
+
+	return nil
+}
+
+func postJSON(ctx context.Context, client pchttp.Client, url string, headers map[string]string, body interface{}) error {
+	bodyB, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyB))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, msg)
+	}
+
+	return nil
+}
+
+// SendMailFunc sends an email, see SendMail.
+type SendMailFunc func(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// SMTPNotifier emails every Notification to To. Auth is only used when Username is set.
+type SMTPNotifier struct {
+	Host     string
+	Username string
+	Password string
+	From     string
+	To       []string
+	Send     SendMailFunc
+}
+
+func (s SMTPNotifier) Notify(ctx context.Context, n Notification) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Host)
+		if err != nil {
+			return fmt.Errorf("SMTPNotifier.Notify: %w", err)
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Summary())
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\nRule: %s (%s)\r\nNode: %s\r\nSince: %s\r\n",
+		n.Message, n.Rule, n.Type, n.Address, n.Since.UTC().Format(time.RFC3339))
+
+	if err := s.Send(ctx, s.Host, auth, s.From, s.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("SMTPNotifier.Notify: %w", err)
+	}
+
+	return nil
+}
+
+// SendMail is smtp.SendMail, except that it gives up when ctx is done or after smtpTimeout,
+// rather than waiting on an unresponsive server forever.
+func SendMail(ctx context.Context, addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// the deadline covers a timeout, closing the connection covers ctx being cancelled
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return withContextErr(ctx, err)
+	}
+	defer c.Close()
+
+	if err = sendMail(c, host, a, from, to, msg); err != nil {
+		return withContextErr(ctx, err)
+	}
+
+	return nil
+}
+
+// sendMail has the same conversation with c as smtp.SendMail.
+func sendMail(c *smtp.Client, host string, a smtp.Auth, from string, to []string, msg []byte) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// withContextErr wraps err with ctx's error when ctx ended the conversation, as the error of a
+// closed connection doesn't say why it was closed. The connection's deadline is ctx's, so it
+// may time out just before ctx does.
+func withContextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %s", ctx.Err(), err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %s", context.DeadlineExceeded, err)
+	}
+
+	return err
+}
@@ -0,0 +1,77 @@
+package alerting
+
+import (
+	"fmt"
+	"time"
+
+	"monitoring-service/pocket"
+)
+
+const upoktPerPokt = 1000000
+
+// NodeState is what the rules are checked against for a node.
+type NodeState struct {
+	Address string
+	Node    pocket.Node
+	// Claims and LastClaim are only set when a rule for the node needs them.
+	Claims    pocket.ClaimsStatus
+	LastClaim time.Time
+	Now       time.Time
+}
+
+// needsClaims reports whether a rule of type needs the node's claims.
+func needsClaims(ruleType string) bool {
+	return ruleType == RuleNoClaims || ruleType == RuleClaimExpiry
+}
+
+// evaluate returns whether r fires for the node in st, and a message describing why.
+func (r RuleConfig) evaluate(st NodeState) (firing bool, message string) {
+	switch r.Type {
+	case RuleJailed:
+		return st.Node.IsJailed, "node is jailed"
+
+	case RuleHeightLag:
+		// a node that doesn't answer is as good as out of sync
+		if !st.Node.IsReachable {
+			return true, fmt.Sprintf("node's RPC is unreachable: %s", st.Node.RPCError)
+		}
+		// without the network's height the lag isn't known, which is no reason to fire
+		if st.Node.NetworkHeight > 0 && st.Node.LagBlocks > r.Blocks {
+			return true, fmt.Sprintf("node is %d blocks behind the network (height %d, network %d)",
+				st.Node.LagBlocks, st.Node.LatestBlockHeight, st.Node.NetworkHeight)
+		}
+		return false, ""
+
+	case RuleNoClaims:
+		if st.LastClaim.IsZero() {
+			return true, "node has no claims"
+		}
+		if since := st.Now.Sub(st.LastClaim); since > r.Window {
+			return true, fmt.Sprintf("no claims for %s (last at %s)", since.Truncate(time.Minute), st.LastClaim.UTC().Format(time.RFC3339))
+		}
+		return false, ""
+
+	case RuleClaimExpiry:
+		var expiring int
+		var pokt float64
+		for _, c := range st.Claims.Pending {
+			if c.BlocksUntilExpiry <= r.Blocks {
+				expiring++
+				pokt += c.Claim.PoktAmount()
+			}
+		}
+		if expiring > 0 {
+			return true, fmt.Sprintf("%d claims without a proof expire within %d blocks (%.2f POKT)", expiring, r.Blocks, pokt)
+		}
+		return false, ""
+
+	case RuleLowBalance:
+		balance := float64(st.Node.Balance) / upoktPerPokt
+		if balance < r.Threshold {
+			return true, fmt.Sprintf("balance is %.2f POKT, below %.2f", balance, r.Threshold)
+		}
+		return false, ""
+	}
+
+	return false, ""
+}
@@ -0,0 +1,39 @@
+package alerting
+
+import (
+	"net/http"
+
+	"monitoring-service/api"
+)
+
+const (
+	statusEndpointPath   = "/alerts"
+	sendTestEndpointPath = "/alerts/test"
+)
+
+type transport struct {
+	Engine *Engine
+	Routes []api.Route
+}
+
+func NewTransport(e *Engine) transport {
+	return transport{
+		Engine: e,
+		Routes: []api.Route{
+			{
+				Method:   http.MethodGet,
+				Path:     statusEndpointPath,
+				Endpoint: StatusEndpoint(e),
+				Decoder:  api.DecodeEmptyRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodPost,
+				Path:     sendTestEndpointPath,
+				Endpoint: SendTestEndpoint(e),
+				Decoder:  api.DecodeEmptyRequest,
+				Encoder:  api.EncodeResponse,
+			},
+		},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/go-kit/kit/endpoint"
 
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/transport"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
@@ -17,6 +18,9 @@ import (
 type Router struct {
 	Mux    *mux.Router
 	Logger kitlog.Logger
+
+	requests metrics.Counter
+	duration metrics.Histogram
 }
 
 type Route struct {
@@ -37,6 +41,13 @@ func NewRouter(logger kitlog.Logger) Router {
 	}
 }
 
+// Instrument counts the requests to, and observes the duration of, every route added afterwards,
+// by method, route path and status.
+func (router *Router) Instrument(requests metrics.Counter, duration metrics.Histogram) {
+	router.requests = requests
+	router.duration = duration
+}
+
 func (router *Router) AddRoutes(routes []Route) {
 	for _, r := range routes {
 		router.AddRoute(r)
@@ -49,12 +60,17 @@ func (router *Router) AddRoute(rt Route) {
 		kithttp.ServerErrorHandler(transport.NewLogErrorHandler(router.Logger)),
 	}
 
-	router.Mux.Handle(rt.Path, kithttp.NewServer(
+	var handler http.Handler = kithttp.NewServer(
 		rt.Endpoint,
 		rt.Decoder,
 		rt.Encoder,
 		options...,
-	)).Methods(rt.Method)
+	)
+	if router.requests != nil {
+		handler = instrumentRoute(handler, rt, router.requests, router.duration)
+	}
+
+	router.Mux.Handle(rt.Path, handler).Methods(rt.Method)
 
 	router.Logger.Log("Route", fmt.Sprintf("%s %s", rt.Method, rt.Path))
 }
@@ -67,7 +83,7 @@ func commonMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", fmt.Sprintf("%s, %s, %s", http.MethodGet, http.MethodPost, http.MethodOptions))
+		w.Header().Set("Access-Control-Allow-Methods", fmt.Sprintf("%s, %s, %s, %s, %s", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions))
 		next.ServeHTTP(w, r)
 	})
 }
@@ -77,21 +93,28 @@ type errorWrapperResponse struct {
 }
 
 type errorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    ErrorCode `json:"code"`
+	Status  int       `json:"status"`
+	Message string    `json:"message"`
+	Details Details   `json:"details,omitempty"`
 }
 
+// EncodeError writes err using the JSON error contract shared with the UI. The status and
+// code come from the first *Error in the chain, or from anything implementing
+// kithttp.StatusCoder (such as the provider's upstream errors), and default to a 500.
 func EncodeError(_ context.Context, err error, w http.ResponseWriter) {
-	httpCode := http.StatusInternalServerError
+	apiErr := errorFor(err)
 
 	resp := errorWrapperResponse{
 		Error: errorResponse{
-			Code:    httpCode,
+			Code:    apiErr.Code,
+			Status:  apiErr.Status,
 			Message: err.Error(),
+			Details: apiErr.Details,
 		},
 	}
 
-	w.WriteHeader(httpCode)
+	w.WriteHeader(apiErr.Status)
 	json.NewEncoder(w).Encode(resp)
 }
 
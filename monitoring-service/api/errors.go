@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// ErrorCode is the machine-readable error code sent to clients in the "code" field.
+type ErrorCode string
+
+const (
+	CodeInvalidArgument     ErrorCode = "invalid_argument"
+	CodeNotFound            ErrorCode = "not_found"
+	CodeRateLimited         ErrorCode = "rate_limited"
+	CodeUpstreamUnavailable ErrorCode = "upstream_unavailable"
+	CodeInternal            ErrorCode = "internal"
+)
+
+// Details carries extra context about an error, such as the name of an offending param.
+type Details map[string]interface{}
+
+// Error is an error that knows how it should be presented to API clients.
+type Error struct {
+	Code    ErrorCode
+	Status  int
+	Message string
+	Details Details
+}
+
+func NewError(code ErrorCode, status int, message string, details Details) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+		Details: details,
+	}
+}
+
+// InvalidArgument returns a 400 error for a missing or malformed request param.
+func InvalidArgument(message string, details Details) *Error {
+	return NewError(CodeInvalidArgument, http.StatusBadRequest, message, details)
+}
+
+// NotFound returns a 404 error for a resource that does not exist.
+func NotFound(message string, details Details) *Error {
+	return NewError(CodeNotFound, http.StatusNotFound, message, details)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// errorFor finds the most specific Error in err's chain. Errors which only implement
+// kithttp.StatusCoder are given the code matching their status; anything else is internal.
+func errorFor(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	status := http.StatusInternalServerError
+	var sc kithttp.StatusCoder
+	if errors.As(err, &sc) {
+		status = sc.StatusCode()
+	}
+
+	return NewError(codeForStatus(status), status, err.Error(), nil)
+}
+
+func codeForStatus(status int) ErrorCode {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusTooManyRequests:
+		return CodeRateLimited
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return CodeUpstreamUnavailable
+	case status >= http.StatusBadRequest && status < http.StatusInternalServerError:
+		return CodeInvalidArgument
+	default:
+		return CodeInternal
+	}
+}
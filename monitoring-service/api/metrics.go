@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+// instrumentRoute labels the requests to next by rt's path template rather than the request
+// path, so that path variables don't make a series per value.
+func instrumentRoute(next http.Handler, rt Route, requests metrics.Counter, duration metrics.Histogram) http.Handler {
+	requests = requests.With("method", rt.Method, "route", rt.Path)
+	duration = duration.With("method", rt.Method, "route", rt.Path)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		requests.With("status", strconv.Itoa(sw.status)).Add(1)
+		duration.Observe(time.Since(begin).Seconds())
+	})
+}
+
+// statusWriter records the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,413 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"monitoring-service/timer"
+
+	"github.com/go-kit/kit/log"
+)
+
+const (
+	DefaultConcurrency      = 8
+	DefaultProgressInterval = 10 * time.Second
+	DefaultVerifySample     = 100
+)
+
+// BlockTimeSource reads block times from the network. It shouldn't be backed by the store, or
+// verification compares the store with itself.
+type BlockTimeSource interface {
+	Height(ctx context.Context) (uint, error)
+	BlockTime(ctx context.Context, height uint) (time.Time, error)
+}
+
+// BlockTimeStore is where block times are cached, along with the height up to which every
+// block time has been stored.
+type BlockTimeStore interface {
+	Get(height uint) (t time.Time, exists bool, err error)
+	Set(height uint, t time.Time) error
+	HighWaterMark() (height uint, exists bool, err error)
+	SetHighWaterMark(height uint) error
+}
+
+// Backfiller fetches the block times missing from a store.
+type Backfiller struct {
+	source           BlockTimeSource
+	store            BlockTimeStore
+	concurrency      int
+	progressInterval time.Duration
+	logger           log.Logger
+}
+
+func New(source BlockTimeSource, store BlockTimeStore, concurrency int, progressInterval time.Duration, logger log.Logger) *Backfiller {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	if progressInterval <= 0 {
+		progressInterval = DefaultProgressInterval
+	}
+
+	return &Backfiller{
+		source:           source,
+		store:            store,
+		concurrency:      concurrency,
+		progressInterval: progressInterval,
+		logger:           logger,
+	}
+}
+
+// Range resolves the heights to work on. A zero from starts after the high-water mark, and a
+// zero to ends at the current height.
+func (b *Backfiller) Range(ctx context.Context, from, to uint) (uint, uint, error) {
+	if from == 0 {
+		mark, exists, err := b.store.HighWaterMark()
+		if err != nil {
+			return 0, 0, fmt.Errorf("Backfiller.Range: %w", err)
+		}
+		from = 1
+		if exists {
+			from = mark + 1
+		}
+	}
+
+	if to == 0 {
+		height, err := b.source.Height(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Backfiller.Range: %w", err)
+		}
+		to = height
+	}
+
+	return from, to, nil
+}
+
+// Result summarises a backfill.
+type Result struct {
+	From          uint
+	To            uint
+	NumFetched    int
+	NumCached     int
+	NumFailed     int
+	HighWaterMark uint
+	Took          time.Duration
+}
+
+// Run stores the block time of every height in [from, to] that isn't stored yet, at most
+// b.concurrency at a time. Heights that fail are skipped and counted. The high-water mark is
+// advanced while every height below it has been stored, so a rerun starts where this one stopped.
+func (b *Backfiller) Run(ctx context.Context, from, to uint) (Result, error) {
+	res := Result{From: from, To: to}
+	if from > to {
+		return res, nil
+	}
+
+	mark, markExists, err := b.store.HighWaterMark()
+	if err != nil {
+		return res, fmt.Errorf("Backfiller.Run: %w", err)
+	}
+	res.HighWaterMark = mark
+
+	// the mark can only move if this range picks up where it is
+	tracker := newMarkTracker(mark, from, markExists && from <= mark+1 || !markExists && from <= 1)
+
+	heights := make(chan uint)
+	go func() {
+		defer close(heights)
+		for h := from; h <= to; h++ {
+			select {
+			case heights <- h:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	record := func(h uint, cached bool, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			res.NumFailed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			_ = b.logger.Log("level", "ERROR", "msg", err.Error())
+			return
+		case cached:
+			res.NumCached++
+		default:
+			res.NumFetched++
+		}
+		tracker.done(h)
+	}
+
+	// checkpoint persists the high-water mark if it moved, so an interrupted run isn't repeated
+	checkpoint := func() error {
+		mu.Lock()
+		newMark, moved := tracker.mark()
+		mu.Unlock()
+		if !moved || newMark <= res.HighWaterMark {
+			return nil
+		}
+
+		if err := b.store.SetHighWaterMark(newMark); err != nil {
+			return err
+		}
+		res.HighWaterMark = newMark
+		return nil
+	}
+
+	t := timer.Start()
+	stopProgress := b.reportProgress(to-from+1, func() (done int) {
+		if err := checkpoint(); err != nil {
+			_ = b.logger.Log("level", "ERROR", "msg", err.Error())
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		return res.NumFetched + res.NumCached + res.NumFailed
+	}, t)
+
+	for w := 0; w < b.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h := range heights {
+				cached, err := b.fetch(ctx, h)
+				record(h, cached, err)
+			}
+		}()
+	}
+	wg.Wait()
+	stopProgress()
+	res.Took = t.Elapsed()
+
+	if err := checkpoint(); err != nil {
+		return res, fmt.Errorf("Backfiller.Run: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		return res, fmt.Errorf("Backfiller.Run: %w", ctx.Err())
+	}
+	if firstErr != nil {
+		return res, fmt.Errorf("Backfiller.Run: %d heights failed, first error: %w", res.NumFailed, firstErr)
+	}
+
+	return res, nil
+}
+
+func (b *Backfiller) fetch(ctx context.Context, height uint) (cached bool, err error) {
+	if _, exists, _ := b.store.Get(height); exists {
+		return true, nil
+	}
+
+	t, err := b.source.BlockTime(ctx, height)
+	if err != nil {
+		return false, fmt.Errorf("fetch(%d): %w", height, err)
+	}
+
+	if err = b.store.Set(height, t); err != nil {
+		return false, fmt.Errorf("fetch(%d): %w", height, err)
+	}
+
+	return false, nil
+}
+
+// reportProgress logs how far the work has got, and an ETA, every progress interval until the
+// returned func is called. tick is called on every report and returns the number of heights done.
+func (b *Backfiller) reportProgress(total uint, tick func() int, t timer.Timer) (stop func()) {
+	ticker := time.NewTicker(b.progressInterval)
+	quit := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case <-ticker.C:
+				n := tick()
+				elapsed := t.Elapsed()
+				rate := float64(n) / elapsed.Seconds()
+				eta := "unknown"
+				if rate > 0 {
+					eta = (time.Duration(float64(int(total)-n)/rate) * time.Second).Round(time.Second).String()
+				}
+				_ = b.logger.Log("level", "INFO", "msg", fmt.Sprintf("%d/%d heights (%.1f%%), %.1f/s, ETA %s",
+					n, total, 100*float64(n)/float64(total), rate, eta))
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(quit)
+		<-finished
+	}
+}
+
+// Mismatch is a stored block time that differs from the network's.
+type Mismatch struct {
+	Height  uint
+	Stored  time.Time
+	Network time.Time
+}
+
+// VerifyResult summarises a verification.
+type VerifyResult struct {
+	NumChecked int
+	NumMissing int
+	Mismatches []Mismatch
+}
+
+// Verify compares the stored block times of up to sample random heights in [from, to] with the
+// network's. When fix is set, mismatched times are overwritten with the network's.
+func (b *Backfiller) Verify(ctx context.Context, from, to uint, sample int, fix bool) (VerifyResult, error) {
+	var res VerifyResult
+	if from > to {
+		return res, nil
+	}
+	if sample < 1 {
+		sample = DefaultVerifySample
+	}
+
+	span := to - from + 1
+	if uint(sample) > span {
+		sample = int(span)
+	}
+
+	picked := make(map[uint]bool, sample)
+	for len(picked) < sample {
+		picked[from+uint(rand.Int63n(int64(span)))] = true
+	}
+
+	heights := make([]uint, 0, sample)
+	for h := range picked {
+		heights = append(heights, h)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, b.concurrency)
+	for _, h := range heights {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(h uint) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			m, checked, err := b.verify(ctx, h, fix)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				if firstErr == nil {
+					firstErr = err
+				}
+			case !checked:
+				res.NumMissing++
+			default:
+				res.NumChecked++
+				if m != nil {
+					res.Mismatches = append(res.Mismatches, *m)
+				}
+			}
+		}(h)
+	}
+	wg.Wait()
+
+	sort.Slice(res.Mismatches, func(i, j int) bool {
+		return res.Mismatches[i].Height < res.Mismatches[j].Height
+	})
+
+	if ctx.Err() != nil {
+		return res, fmt.Errorf("Backfiller.Verify: %w", ctx.Err())
+	}
+	if firstErr != nil {
+		return res, fmt.Errorf("Backfiller.Verify: %w", firstErr)
+	}
+
+	return res, nil
+}
+
+func (b *Backfiller) verify(ctx context.Context, height uint, fix bool) (*Mismatch, bool, error) {
+	stored, exists, _ := b.store.Get(height)
+	if !exists {
+		return nil, false, nil
+	}
+
+	network, err := b.source.BlockTime(ctx, height)
+	if err != nil {
+		return nil, false, fmt.Errorf("verify(%d): %w", height, err)
+	}
+
+	if stored.Equal(network) {
+		return nil, true, nil
+	}
+
+	if fix {
+		if err = b.store.Set(height, network); err != nil {
+			return nil, false, fmt.Errorf("verify(%d): %w", height, err)
+		}
+	}
+
+	return &Mismatch{Height: height, Stored: stored, Network: network}, true, nil
+}
+
+// markTracker follows the highest height below which every height has been stored.
+type markTracker struct {
+	enabled bool
+	next    uint
+	pending map[uint]bool
+}
+
+func newMarkTracker(mark, from uint, enabled bool) *markTracker {
+	next := from
+	if mark+1 > next {
+		next = mark + 1
+	}
+	return &markTracker{enabled: enabled, next: next, pending: make(map[uint]bool)}
+}
+
+func (t *markTracker) done(h uint) {
+	if !t.enabled || h < t.next {
+		return
+	}
+
+	t.pending[h] = true
+	for t.pending[t.next] {
+		delete(t.pending, t.next)
+		t.next++
+	}
+}
+
+// mark returns the new high-water mark, if it moved.
+func (t *markTracker) mark() (uint, bool) {
+	if !t.enabled || t.next == 0 {
+		return 0, false
+	}
+	return t.next - 1, true
+}
@@ -0,0 +1,247 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"monitoring-service/inmem"
+)
+
+// fakeSource is a chain of height blocks, one a minute, whose failing heights can't be fetched.
+type fakeSource struct {
+	mu      sync.Mutex
+	height  uint
+	failing map[uint]bool
+	fetched map[uint]int
+}
+
+func newFakeSource(height uint, failing ...uint) *fakeSource {
+	s := &fakeSource{height: height, failing: make(map[uint]bool), fetched: make(map[uint]int)}
+	for _, h := range failing {
+		s.failing[h] = true
+	}
+	return s
+}
+
+func blockTime(height uint) time.Time {
+	return time.Unix(int64(height)*60, 0).UTC()
+}
+
+func (s *fakeSource) Height(ctx context.Context) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.height, nil
+}
+
+func (s *fakeSource) BlockTime(ctx context.Context, height uint) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched[height]++
+	if s.failing[height] || height > s.height {
+		return time.Time{}, errors.New("block unavailable")
+	}
+	return blockTime(height), nil
+}
+
+func (s *fakeSource) setHeight(height uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.height = height
+}
+
+func (s *fakeSource) heal() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = make(map[uint]bool)
+}
+
+func (s *fakeSource) fetchCount(height uint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetched[height]
+}
+
+func newTestBackfiller(source BlockTimeSource, store BlockTimeStore) *Backfiller {
+	return New(source, store, 3, time.Hour, log.NewNopLogger())
+}
+
+func wantMark(t *testing.T, store BlockTimeStore, want uint) {
+	t.Helper()
+
+	mark, exists, err := store.HighWaterMark()
+	if err != nil || !exists || mark != want {
+		t.Errorf("HighWaterMark: %d, %t, %v, want %d, true, nil", mark, exists, err, want)
+	}
+}
+
+func wantStored(t *testing.T, store BlockTimeStore, from, to uint) {
+	t.Helper()
+
+	for h := from; h <= to; h++ {
+		got, exists, err := store.Get(h)
+		if err != nil || !exists || !got.Equal(blockTime(h)) {
+			t.Errorf("Get(%d): %s, %t, %v, want %s", h, got, exists, err, blockTime(h))
+		}
+	}
+}
+
+func runRange(t *testing.T, b *Backfiller) (Result, error) {
+	t.Helper()
+
+	from, to, err := b.Range(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	return b.Run(context.Background(), from, to)
+}
+
+func TestRunStoresTheWholeRange(t *testing.T) {
+	// 10 heights over 3 workers leave a last batch of one, the height the fetcher used to miss
+	source, store := newFakeSource(10), inmem.NewBlockTimesRepo()
+	b := newTestBackfiller(source, store)
+
+	from, to, err := b.Range(context.Background(), 0, 0)
+	if err != nil || from != 1 || to != 10 {
+		t.Fatalf("Range: %d, %d, %v, want 1, 10, nil", from, to, err)
+	}
+
+	res, err := b.Run(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.NumFetched != 10 || res.NumCached != 0 || res.NumFailed != 0 || res.HighWaterMark != 10 {
+		t.Errorf("Run: %+v, want 10 fetched and a mark of 10", res)
+	}
+	wantStored(t, store, 1, 10)
+	wantMark(t, store, 10)
+	if n := source.fetchCount(11); n != 0 {
+		t.Errorf("height 11, past the range, was fetched %d times", n)
+	}
+}
+
+func TestRunResumesFromHighWaterMark(t *testing.T) {
+	source, store := newFakeSource(5), inmem.NewBlockTimesRepo()
+	b := newTestBackfiller(source, store)
+
+	if _, err := runRange(t, b); err != nil {
+		t.Fatalf("first Run: %v", err)
+	}
+	wantMark(t, store, 5)
+
+	source.setHeight(12)
+	from, to, err := b.Range(context.Background(), 0, 0)
+	if err != nil || from != 6 || to != 12 {
+		t.Fatalf("Range after a run to 5: %d, %d, %v, want 6, 12, nil", from, to, err)
+	}
+
+	res, err := b.Run(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if res.NumFetched != 7 || res.NumCached != 0 {
+		t.Errorf("second Run: %+v, want 7 fetched", res)
+	}
+	wantStored(t, store, 1, 12)
+	wantMark(t, store, 12)
+	for h := uint(1); h <= 12; h++ {
+		if n := source.fetchCount(h); n != 1 {
+			t.Errorf("height %d was fetched %d times, want once", h, n)
+		}
+	}
+}
+
+func TestRunMarkStopsBeforeFailedHeight(t *testing.T) {
+	source, store := newFakeSource(10, 4), inmem.NewBlockTimesRepo()
+	b := newTestBackfiller(source, store)
+
+	res, err := runRange(t, b)
+	if err == nil {
+		t.Fatal("Run with a failing height: nil error")
+	}
+	if res.NumFetched != 9 || res.NumFailed != 1 {
+		t.Errorf("Run: %+v, want 9 fetched and 1 failed", res)
+	}
+	// heights past the failed one are stored, but the mark can't pass it
+	wantStored(t, store, 5, 10)
+	wantMark(t, store, 3)
+
+	// a rerun starts at the failed height, and only fetches that one
+	source.heal()
+	res, err = runRange(t, b)
+	if err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	if res.From != 4 || res.NumFetched != 1 || res.NumCached != 6 {
+		t.Errorf("rerun: %+v, want from 4, 1 fetched and 6 cached", res)
+	}
+	wantStored(t, store, 1, 10)
+	wantMark(t, store, 10)
+}
+
+func TestRunAfterGapLeavesMark(t *testing.T) {
+	source, store := newFakeSource(10), inmem.NewBlockTimesRepo()
+	b := newTestBackfiller(source, store)
+
+	// a range that starts past the mark leaves a gap below it, so the mark doesn't move
+	if _, err := b.Run(context.Background(), 6, 10); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, exists, _ := store.HighWaterMark(); exists {
+		t.Error("Run(6, 10) on an empty store set a high-water mark")
+	}
+
+	// filling the gap carries the mark over the heights already stored
+	if _, err := runRange(t, b); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	wantMark(t, store, 10)
+}
+
+func TestVerify(t *testing.T) {
+	source, store := newFakeSource(10), inmem.NewBlockTimesRepo()
+	b := newTestBackfiller(source, store)
+
+	for h := uint(1); h <= 8; h++ {
+		if err := store.Set(h, blockTime(h)); err != nil {
+			t.Fatalf("Set(%d): %v", h, err)
+		}
+	}
+	wrong := blockTime(3).Add(time.Hour)
+	if err := store.Set(3, wrong); err != nil {
+		t.Fatalf("Set(3): %v", err)
+	}
+
+	// sampling the whole range checks every height, and 9 and 10 aren't stored
+	res, err := b.Verify(context.Background(), 1, 10, 10, false)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if res.NumChecked != 8 || res.NumMissing != 2 || len(res.Mismatches) != 1 {
+		t.Fatalf("Verify: %+v, want 8 checked, 2 missing and 1 mismatch", res)
+	}
+	if m := res.Mismatches[0]; m.Height != 3 || !m.Stored.Equal(wrong) || !m.Network.Equal(blockTime(3)) {
+		t.Errorf("mismatch: %+v, want height 3 stored at %s, network %s", m, wrong, blockTime(3))
+	}
+	if got, _, _ := store.Get(3); !got.Equal(wrong) {
+		t.Errorf("Verify without fix changed the stored time to %s", got)
+	}
+
+	res, err = b.Verify(context.Background(), 1, 10, 10, true)
+	if err != nil {
+		t.Fatalf("Verify with fix: %v", err)
+	}
+	if len(res.Mismatches) != 1 {
+		t.Errorf("Verify with fix: %d mismatches, want 1", len(res.Mismatches))
+	}
+	wantStored(t, store, 1, 8)
+
+	res, err = b.Verify(context.Background(), 1, 10, 10, false)
+	if err != nil || len(res.Mismatches) != 0 {
+		t.Errorf("Verify after fix: %+v, %v, want no mismatches", res, err)
+	}
+}
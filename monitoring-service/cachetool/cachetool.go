@@ -0,0 +1,108 @@
+// Package cachetool moves cached block times and params in and out of a store, copies stores
+// between backends and reports what a store holds. It backs the pokt-cache command.
+package cachetool
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitoring-service/store"
+)
+
+// HeightRange limits the heights a command works on. A zero From or To leaves that side open.
+type HeightRange struct {
+	From uint
+	To   uint
+}
+
+// ParseHeightRange parses "<from>-<to>", where either side may be left out: "100-", "-200".
+// An empty string is the whole chain.
+func ParseHeightRange(s string) (HeightRange, error) {
+	if s == "" {
+		return HeightRange{}, nil
+	}
+
+	fromS, toS, ok := strings.Cut(s, "-")
+	if !ok {
+		return HeightRange{}, fmt.Errorf("invalid height range %q, expected <from>-<to>", s)
+	}
+
+	var (
+		hr  HeightRange
+		err error
+	)
+	if hr.From, err = parseHeight(fromS); err != nil {
+		return HeightRange{}, fmt.Errorf("invalid height range %q: %w", s, err)
+	}
+	if hr.To, err = parseHeight(toS); err != nil {
+		return HeightRange{}, fmt.Errorf("invalid height range %q: %w", s, err)
+	}
+	if hr.To > 0 && hr.From > hr.To {
+		return HeightRange{}, fmt.Errorf("invalid height range %q: from is after to", s)
+	}
+
+	return hr, nil
+}
+
+func parseHeight(s string) (uint, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	h, err := strconv.ParseUint(s, 10, 64)
+	return uint(h), err
+}
+
+// Contains reports whether height is in the range.
+func (hr HeightRange) Contains(height uint) bool {
+	return height >= hr.From && (hr.To == 0 || height <= hr.To)
+}
+
+// Overlaps reports whether any height of the interval [from, to) is in the range.
+func (hr HeightRange) Overlaps(from, to uint) bool {
+	return to > hr.From && (hr.To == 0 || from <= hr.To)
+}
+
+func (hr HeightRange) String() string {
+	if hr.From == 0 && hr.To == 0 {
+		return "all heights"
+	}
+	if hr.To == 0 {
+		return fmt.Sprintf("heights from %d", hr.From)
+	}
+	return fmt.Sprintf("heights %d to %d", hr.From, hr.To)
+}
+
+var errStop = errors.New("stop")
+
+// AdvanceHighWaterMark moves the block times high-water mark up over every height stored right
+// after it, so that the block time fetcher doesn't revisit heights that were imported or copied.
+func AdvanceHighWaterMark(r store.BlockTimesRepo) (uint, error) {
+	mark, _, err := r.HighWaterMark()
+	if err != nil {
+		return 0, fmt.Errorf("AdvanceHighWaterMark: %w", err)
+	}
+
+	next := mark + 1
+	err = r.Range(next, 0, func(height uint, _ time.Time) error {
+		if height != next {
+			return errStop
+		}
+		next++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return 0, fmt.Errorf("AdvanceHighWaterMark: %w", err)
+	}
+
+	if next-1 > mark {
+		if err = r.SetHighWaterMark(next - 1); err != nil {
+			return 0, fmt.Errorf("AdvanceHighWaterMark: %w", err)
+		}
+	}
+
+	return next - 1, nil
+}
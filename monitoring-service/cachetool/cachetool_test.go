@@ -0,0 +1,122 @@
+package cachetool
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+	"monitoring-service/store/backend"
+
+	"github.com/go-kit/kit/log"
+)
+
+const testAddress = "00000000000000000000000000000000000000ab"
+
+func openStore(t *testing.T, kind store.Kind) store.Store {
+	t.Helper()
+
+	s, err := backend.Open(kind, backend.DefaultPath(kind, t.TempDir()), log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Open %s: %v", kind, err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s
+}
+
+func allParams(value string) pocket.AllParams {
+	group := func(key string) pocket.ParamGroup {
+		return pocket.ParamGroup{{Key: key, Value: value}}
+	}
+
+	return pocket.AllParams{
+		AppParams:    group("application/MaxApplications"),
+		AuthParams:   group("auth/MaxMemoCharacters"),
+		GovParams:    group("gov/acl"),
+		NodeParams:   group("pos/RelaysToTokensMultiplier"),
+		PocketParams: group("pocketcore/ClaimExpiration"),
+	}
+}
+
+func blockTime(height uint) time.Time {
+	return time.Unix(int64(height)*900, 0).UTC()
+}
+
+// fill stores the block times of 1-5, 8, 9 and 12, params that change at 8, the transactions and
+// checkpoint of testAddress, and a fleet.
+func fill(t *testing.T, s store.Store) {
+	t.Helper()
+
+	for _, h := range []uint{1, 2, 3, 4, 5, 8, 9, 12} {
+		if err := s.BlockTimes().Set(h, blockTime(h)); err != nil {
+			t.Fatalf("BlockTimes.Set: %v", err)
+		}
+	}
+	for _, interval := range []store.ParamsInterval{{From: 1, To: 8, Params: allParams("a")}, {From: 8, To: 20, Params: allParams("b")}} {
+		if err := s.Params().SetInterval(interval); err != nil {
+			t.Fatalf("Params.SetInterval: %v", err)
+		}
+	}
+
+	txs := []pocket.Transaction{
+		{Hash: "aa01", Height: 3, Type: pocket.TypeClaim, NumRelays: 10},
+		{Hash: "aa02", Height: 4, Type: pocket.TypeProof, NumRelays: 10},
+	}
+	if err := s.Transactions().SetTransactions(testAddress, txs); err != nil {
+		t.Fatalf("Transactions.SetTransactions: %v", err)
+	}
+	if err := s.Transactions().SetCheckpoint(testAddress, 9); err != nil {
+		t.Fatalf("Transactions.SetCheckpoint: %v", err)
+	}
+
+	fleet := pocket.Fleet{Name: "fleet", Addresses: []string{testAddress}, CreatedAt: blockTime(1), UpdatedAt: blockTime(2)}
+	if err := s.Fleets().Set(fleet); err != nil {
+		t.Fatalf("Fleets.Set: %v", err)
+	}
+}
+
+// blockTimes returns the stored block times as unix seconds by height.
+func blockTimes(t *testing.T, s store.Store) map[uint]int64 {
+	t.Helper()
+
+	times := make(map[uint]int64)
+	err := s.BlockTimes().Range(0, 0, func(height uint, bt time.Time) error {
+		times[height] = bt.Unix()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("BlockTimes.Range: %v", err)
+	}
+
+	return times
+}
+
+func wantBlockTimes(heights ...uint) map[uint]int64 {
+	times := make(map[uint]int64)
+	for _, h := range heights {
+		times[h] = blockTime(h).Unix()
+	}
+	return times
+}
+
+func checkParams(t *testing.T, s store.Store, want []store.ParamsInterval) {
+	t.Helper()
+
+	got, err := s.Params().Intervals()
+	if err != nil {
+		t.Fatalf("Params.Intervals: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("params intervals: %+v, want %+v", got, want)
+	}
+}
+
+func checkHighWaterMark(t *testing.T, s store.Store, want uint) {
+	t.Helper()
+
+	if mark, exists, err := s.BlockTimes().HighWaterMark(); err != nil || !exists || mark != want {
+		t.Errorf("high-water mark: %d, %t, %v, want %d", mark, exists, err, want)
+	}
+}
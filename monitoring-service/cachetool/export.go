@@ -0,0 +1,258 @@
+package cachetool
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+)
+
+// Format is the encoding of an export.
+type Format string
+
+const (
+	NDJSON Format = "ndjson"
+	CSV    Format = "csv"
+)
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case NDJSON, CSV:
+		return Format(s), nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected %s or %s", s, NDJSON, CSV)
+	}
+}
+
+const (
+	RecordBlockTime = "block_time"
+	RecordParams    = "params"
+)
+
+// Record is one line of an export: either the time of the block at Height, or the params of
+// every height in [Height, To).
+type Record struct {
+	Type   string            `json:"type"`
+	Height uint              `json:"height"`
+	To     uint              `json:"to,omitempty"`
+	Time   *time.Time        `json:"time,omitempty"`
+	Params *pocket.AllParams `json:"params,omitempty"`
+}
+
+// csvHeader are the columns of a CSV export. value holds the block time, or the params as JSON.
+var csvHeader = []string{"type", "height", "to", "value"}
+
+// Counts are the number of records exported or imported.
+type Counts struct {
+	BlockTimes      int
+	ParamsIntervals int
+}
+
+// Export writes the block times in hr, and the params intervals overlapping it, to w.
+func Export(w io.Writer, s store.Store, format Format, hr HeightRange) (Counts, error) {
+	var (
+		counts Counts
+		write  func(Record) error
+		flush  func() error
+	)
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return counts, fmt.Errorf("Export: %w", err)
+		}
+		write = func(rec Record) error { return cw.Write(toCSV(rec)) }
+		flush = func() error { cw.Flush(); return cw.Error() }
+	default:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		write = func(rec Record) error { return enc.Encode(rec) }
+		flush = bw.Flush
+	}
+
+	err := s.BlockTimes().Range(hr.From, hr.To, func(height uint, t time.Time) error {
+		counts.BlockTimes++
+		return write(Record{Type: RecordBlockTime, Height: height, Time: &t})
+	})
+	if err != nil {
+		return counts, fmt.Errorf("Export: %w", err)
+	}
+
+	intervals, err := s.Params().Intervals()
+	if err != nil {
+		return counts, fmt.Errorf("Export: %w", err)
+	}
+	for _, interval := range intervals {
+		if !hr.Overlaps(interval.From, interval.To) {
+			continue
+		}
+
+		params := interval.Params
+		if err = write(Record{Type: RecordParams, Height: interval.From, To: interval.To, Params: &params}); err != nil {
+			return counts, fmt.Errorf("Export: %w", err)
+		}
+		counts.ParamsIntervals++
+	}
+
+	if err = flush(); err != nil {
+		return counts, fmt.Errorf("Export: %w", err)
+	}
+
+	return counts, nil
+}
+
+func toCSV(rec Record) []string {
+	var to, value string
+	switch rec.Type {
+	case RecordBlockTime:
+		value = rec.Time.Format(time.RFC3339Nano)
+	case RecordParams:
+		to = strconv.FormatUint(uint64(rec.To), 10)
+		paramsB, _ := json.Marshal(rec.Params)
+		value = string(paramsB)
+	}
+
+	return []string{rec.Type, strconv.FormatUint(uint64(rec.Height), 10), to, value}
+}
+
+func fromCSV(row []string) (Record, error) {
+	if len(row) != len(csvHeader) {
+		return Record{}, fmt.Errorf("expected %d columns, got %d", len(csvHeader), len(row))
+	}
+
+	height, err := strconv.ParseUint(row[1], 10, 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("invalid height: %w", err)
+	}
+	rec := Record{Type: row[0], Height: uint(height)}
+
+	switch rec.Type {
+	case RecordBlockTime:
+		t, err := time.Parse(time.RFC3339Nano, row[3])
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid time: %w", err)
+		}
+		rec.Time = &t
+	case RecordParams:
+		to, err := strconv.ParseUint(row[2], 10, 64)
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid to: %w", err)
+		}
+		rec.To = uint(to)
+
+		var params pocket.AllParams
+		if err = json.Unmarshal([]byte(row[3]), &params); err != nil {
+			return Record{}, fmt.Errorf("invalid params: %w", err)
+		}
+		rec.Params = &params
+	}
+
+	return rec, nil
+}
+
+// Import stores the records read from r that are in hr. Block times replace stored ones, and
+// params intervals are merged with the stored intervals. The high-water mark is then advanced
+// over any block times that now follow it.
+func Import(r io.Reader, s store.Store, format Format, hr HeightRange) (Counts, error) {
+	var counts Counts
+	apply := func(line int, rec Record) error {
+		if err := importRecord(s, rec, hr, &counts); err != nil {
+			return fmt.Errorf("Import: line %d: %w", line, err)
+		}
+		return nil
+	}
+
+	switch format {
+	case CSV:
+		cr := csv.NewReader(r)
+		for line := 1; ; line++ {
+			row, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return counts, fmt.Errorf("Import: %w", err)
+			}
+			if line == 1 && strings.Join(row, ",") == strings.Join(csvHeader, ",") {
+				continue
+			}
+
+			rec, err := fromCSV(row)
+			if err != nil {
+				return counts, fmt.Errorf("Import: line %d: %w", line, err)
+			}
+			if err = apply(line, rec); err != nil {
+				return counts, err
+			}
+		}
+	default:
+		scanner := bufio.NewScanner(r)
+		// a params record is a few kB, well within the limit
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(strings.TrimSpace(scanner.Text())) == 0 {
+				continue
+			}
+
+			var rec Record
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				return counts, fmt.Errorf("Import: line %d: %w", line, err)
+			}
+			if err := apply(line, rec); err != nil {
+				return counts, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return counts, fmt.Errorf("Import: %w", err)
+		}
+	}
+
+	if _, err := AdvanceHighWaterMark(s.BlockTimes()); err != nil {
+		return counts, fmt.Errorf("Import: %w", err)
+	}
+
+	return counts, nil
+}
+
+func importRecord(s store.Store, rec Record, hr HeightRange, counts *Counts) error {
+	switch rec.Type {
+	case RecordBlockTime:
+		if rec.Time == nil || rec.Height == 0 {
+			return fmt.Errorf("block time record needs a height and a time")
+		}
+		if !hr.Contains(rec.Height) {
+			return nil
+		}
+		if err := s.BlockTimes().Set(rec.Height, *rec.Time); err != nil {
+			return err
+		}
+		counts.BlockTimes++
+	case RecordParams:
+		if rec.Params == nil || rec.Height == 0 || rec.To <= rec.Height {
+			return fmt.Errorf("params record needs params and a height before to")
+		}
+		if err := rec.Params.Validate(); err != nil {
+			return fmt.Errorf("invalid params: %w", err)
+		}
+		if !hr.Overlaps(rec.Height, rec.To) {
+			return nil
+		}
+		interval := store.ParamsInterval{From: rec.Height, To: rec.To, Params: *rec.Params}
+		if err := s.Params().SetInterval(interval); err != nil {
+			return err
+		}
+		counts.ParamsIntervals++
+	default:
+		return fmt.Errorf("unknown record type %q", rec.Type)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package cachetool
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"monitoring-service/store"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := openStore(t, store.Memory)
+	fill(t, src)
+
+	tests := []struct {
+		hr             HeightRange
+		wantBlockTimes map[uint]int64
+		wantParams     []store.ParamsInterval
+		wantMark       uint
+	}{
+		{
+			HeightRange{},
+			wantBlockTimes(1, 2, 3, 4, 5, 8, 9, 12),
+			[]store.ParamsInterval{{From: 1, To: 8, Params: allParams("a")}, {From: 8, To: 20, Params: allParams("b")}},
+			5,
+		},
+		// the intervals overlapping the range are kept whole
+		{
+			HeightRange{From: 8, To: 10},
+			wantBlockTimes(8, 9),
+			[]store.ParamsInterval{{From: 8, To: 20, Params: allParams("b")}},
+			0,
+		},
+		{
+			HeightRange{To: 7},
+			wantBlockTimes(1, 2, 3, 4, 5),
+			[]store.ParamsInterval{{From: 1, To: 8, Params: allParams("a")}},
+			5,
+		},
+	}
+
+	for _, format := range []Format{NDJSON, CSV} {
+		for _, tt := range tests {
+			t.Run(string(format)+" "+tt.hr.String(), func(t *testing.T) {
+				var buf bytes.Buffer
+				exported, err := Export(&buf, src, format, tt.hr)
+				if err != nil {
+					t.Fatalf("Export: %v", err)
+				}
+				if exported.BlockTimes != len(tt.wantBlockTimes) || exported.ParamsIntervals != len(tt.wantParams) {
+					t.Errorf("exported %+v, want %d block times and %d params intervals", exported, len(tt.wantBlockTimes), len(tt.wantParams))
+				}
+
+				dst := openStore(t, store.SQLite)
+				imported, err := Import(&buf, dst, format, HeightRange{})
+				if err != nil {
+					t.Fatalf("Import: %v", err)
+				}
+				if imported != exported {
+					t.Errorf("imported %+v, exported %+v", imported, exported)
+				}
+
+				if got := blockTimes(t, dst); !reflect.DeepEqual(got, tt.wantBlockTimes) {
+					t.Errorf("block times: %v, want %v", got, tt.wantBlockTimes)
+				}
+				checkParams(t, dst, tt.wantParams)
+				// the mark is advanced over the block times that follow it without a gap
+				if tt.wantMark > 0 {
+					checkHighWaterMark(t, dst, tt.wantMark)
+				} else if mark, _, _ := dst.BlockTimes().HighWaterMark(); mark != 0 {
+					t.Errorf("high-water mark %d over a gap", mark)
+				}
+			})
+		}
+	}
+}
+
+func TestImportRange(t *testing.T) {
+	src := openStore(t, store.Memory)
+	fill(t, src)
+
+	var buf bytes.Buffer
+	if _, err := Export(&buf, src, NDJSON, HeightRange{}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	// only the records in the range are imported, and merged with what is stored
+	dst := openStore(t, store.Memory)
+	if err := dst.Params().SetInterval(store.ParamsInterval{From: 20, To: 30, Params: allParams("b")}); err != nil {
+		t.Fatalf("Params.SetInterval: %v", err)
+	}
+	counts, err := Import(&buf, dst, NDJSON, HeightRange{From: 9})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if counts.BlockTimes != 2 || counts.ParamsIntervals != 1 {
+		t.Errorf("imported %+v, want 2 block times and 1 params interval", counts)
+	}
+	if got, want := blockTimes(t, dst), wantBlockTimes(9, 12); !reflect.DeepEqual(got, want) {
+		t.Errorf("block times: %v, want %v", got, want)
+	}
+	checkParams(t, dst, []store.ParamsInterval{{From: 8, To: 30, Params: allParams("b")}})
+}
+
+func TestImportRejectsInvalidRecords(t *testing.T) {
+	for name, input := range map[string]string{
+		"unknown type":     `{"type":"block","height":1}`,
+		"no time":          `{"type":"block_time","height":1}`,
+		"empty interval":   `{"type":"params","height":5,"to":5,"params":{}}`,
+		"invalid params":   `{"type":"params","height":5,"to":6,"params":{}}`,
+		"not json":         `{"type":`,
+		"height zero time": `{"type":"block_time","height":0,"time":"2022-01-01T00:00:00Z"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Import(bytes.NewBufferString(input+"\n"), openStore(t, store.Memory), NDJSON, HeightRange{}); err == nil {
+				t.Errorf("Import of %s: nil error", input)
+			}
+		})
+	}
+}
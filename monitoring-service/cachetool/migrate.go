@@ -0,0 +1,99 @@
+package cachetool
+
+import (
+	"fmt"
+	"time"
+
+	"monitoring-service/store"
+)
+
+// MigrateResult counts what was copied by Migrate.
+type MigrateResult struct {
+	Counts
+	Addresses     int
+	Transactions  int
+	Fleets        int
+	HighWaterMark uint
+}
+
+// Migrate copies src into dst: the block times in hr, the params intervals overlapping it, and
+// every indexed address's transactions and checkpoint, and every fleet. The high-water mark of
+// dst is advanced over the block times it then holds, rather than copied, so that a partial copy
+// isn't mistaken for a complete one.
+func Migrate(dst, src store.Store, hr HeightRange) (MigrateResult, error) {
+	var res MigrateResult
+
+	err := src.BlockTimes().Range(hr.From, hr.To, func(height uint, t time.Time) error {
+		res.BlockTimes++
+		return dst.BlockTimes().Set(height, t)
+	})
+	if err != nil {
+		return res, fmt.Errorf("Migrate: block times: %w", err)
+	}
+
+	intervals, err := src.Params().Intervals()
+	if err != nil {
+		return res, fmt.Errorf("Migrate: params: %w", err)
+	}
+	for _, interval := range intervals {
+		if !hr.Overlaps(interval.From, interval.To) {
+			continue
+		}
+		if err = dst.Params().SetInterval(interval); err != nil {
+			return res, fmt.Errorf("Migrate: params [%d, %d): %w", interval.From, interval.To, err)
+		}
+		res.ParamsIntervals++
+	}
+
+	addresses, err := src.Transactions().Addresses()
+	if err != nil {
+		return res, fmt.Errorf("Migrate: transactions: %w", err)
+	}
+	for _, address := range addresses {
+		if err = migrateAddress(dst.Transactions(), src.Transactions(), address, &res); err != nil {
+			return res, fmt.Errorf("Migrate: transactions [%s]: %w", address, err)
+		}
+	}
+
+	fleets, err := src.Fleets().List()
+	if err != nil {
+		return res, fmt.Errorf("Migrate: fleets: %w", err)
+	}
+	for _, f := range fleets {
+		if err = dst.Fleets().Set(f); err != nil {
+			return res, fmt.Errorf("Migrate: fleets: %w", err)
+		}
+		res.Fleets++
+	}
+
+	if res.HighWaterMark, err = AdvanceHighWaterMark(dst.BlockTimes()); err != nil {
+		return res, fmt.Errorf("Migrate: %w", err)
+	}
+
+	return res, nil
+}
+
+func migrateAddress(dst, src store.TransactionsRepo, address string, res *MigrateResult) error {
+	txs, err := src.AccountTransactions(address)
+	if err != nil {
+		return err
+	}
+	if err = dst.SetTransactions(address, txs); err != nil {
+		return err
+	}
+
+	// the checkpoint goes last, so an interrupted copy is re-indexed rather than left with gaps
+	checkpoint, exists, err := src.Checkpoint(address)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err = dst.SetCheckpoint(address, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	res.Addresses++
+	res.Transactions += len(txs)
+	return nil
+}
@@ -0,0 +1,76 @@
+package cachetool
+
+import (
+	"reflect"
+	"testing"
+
+	"monitoring-service/store"
+)
+
+func TestMigrate(t *testing.T) {
+	for _, kinds := range [][2]store.Kind{{store.Memory, store.SQLite}, {store.SQLite, store.Memory}} {
+		src, dst := openStore(t, kinds[0]), openStore(t, kinds[1])
+		t.Run(string(kinds[0])+" to "+string(kinds[1]), func(t *testing.T) {
+			fill(t, src)
+			// a mark in the source isn't copied, as the destination may be missing heights below it
+			if err := src.BlockTimes().SetHighWaterMark(12); err != nil {
+				t.Fatalf("SetHighWaterMark: %v", err)
+			}
+
+			res, err := Migrate(dst, src, HeightRange{})
+			if err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			want := MigrateResult{Counts: Counts{BlockTimes: 8, ParamsIntervals: 2}, Addresses: 1, Transactions: 2, Fleets: 1, HighWaterMark: 5}
+			if res != want {
+				t.Errorf("Migrate: %+v, want %+v", res, want)
+			}
+
+			if got, want := blockTimes(t, dst), blockTimes(t, src); !reflect.DeepEqual(got, want) {
+				t.Errorf("block times: %v, want %v", got, want)
+			}
+			checkParams(t, dst, []store.ParamsInterval{{From: 1, To: 8, Params: allParams("a")}, {From: 8, To: 20, Params: allParams("b")}})
+			checkHighWaterMark(t, dst, 5)
+
+			txs, err := dst.Transactions().AccountTransactions(testAddress)
+			if err != nil {
+				t.Fatalf("AccountTransactions: %v", err)
+			}
+			var hashes []string
+			for _, tx := range txs {
+				hashes = append(hashes, tx.Hash)
+			}
+			if !reflect.DeepEqual(hashes, []string{"aa02", "aa01"}) {
+				t.Errorf("transactions: %v, want [aa02 aa01]", hashes)
+			}
+			if checkpoint, exists, err := dst.Transactions().Checkpoint(testAddress); err != nil || !exists || checkpoint != 9 {
+				t.Errorf("checkpoint: %d, %t, %v, want 9", checkpoint, exists, err)
+			}
+
+			f, exists, err := dst.Fleets().Get("fleet")
+			if err != nil || !exists || !reflect.DeepEqual(f.Addresses, []string{testAddress}) || !f.CreatedAt.Equal(blockTime(1)) {
+				t.Errorf("fleet: %+v, %t, %v", f, exists, err)
+			}
+		})
+	}
+}
+
+func TestMigrateRange(t *testing.T) {
+	src, dst := openStore(t, store.Memory), openStore(t, store.SQLite)
+	fill(t, src)
+
+	res, err := Migrate(dst, src, HeightRange{From: 4, To: 8})
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if res.BlockTimes != 3 || res.ParamsIntervals != 2 {
+		t.Errorf("Migrate: %+v, want 3 block times and 2 params intervals", res)
+	}
+	if got, want := blockTimes(t, dst), wantBlockTimes(4, 5, 8); !reflect.DeepEqual(got, want) {
+		t.Errorf("block times: %v, want %v", got, want)
+	}
+	// nothing below 4 was copied, so there is no mark
+	if mark, _, _ := dst.BlockTimes().HighWaterMark(); mark != 0 {
+		t.Errorf("high-water mark %d, want none", mark)
+	}
+}
@@ -0,0 +1,111 @@
+package cachetool
+
+import (
+	"fmt"
+	"time"
+
+	"monitoring-service/store"
+)
+
+// Gap is a run of heights, [From, To], with no cached block time.
+type Gap struct {
+	From uint
+	To   uint
+}
+
+// Stats describes what a store holds.
+type Stats struct {
+	BlockTimes       int
+	FirstHeight      uint
+	LastHeight       uint
+	HighWaterMark    uint
+	HasHighWaterMark bool
+
+	// MissingHeights and Gaps cover the heights between the range's bounds, or the first and last
+	// cached height where the range is open. Gaps holds at most the maxGaps passed to GetStats.
+	MissingHeights uint
+	NumGaps        int
+	Gaps           []Gap
+
+	ParamsIntervals int
+	ParamsHeights   uint
+
+	Addresses    int
+	Transactions int
+	Fleets       int
+}
+
+// GetStats counts the entries of every repository and finds the heights in hr that have no
+// cached block time.
+func GetStats(s store.Store, hr HeightRange, maxGaps int) (Stats, error) {
+	var st Stats
+
+	var err error
+	if st.HighWaterMark, st.HasHighWaterMark, err = s.BlockTimes().HighWaterMark(); err != nil {
+		return st, fmt.Errorf("GetStats: %w", err)
+	}
+
+	// the next height expected, which is the first of a gap if it isn't the one stored
+	next := hr.From
+	addGap := func(from, to uint) {
+		st.MissingHeights += to - from + 1
+		st.NumGaps++
+		if len(st.Gaps) < maxGaps {
+			st.Gaps = append(st.Gaps, Gap{From: from, To: to})
+		}
+	}
+
+	err = s.BlockTimes().Range(hr.From, hr.To, func(height uint, _ time.Time) error {
+		if st.BlockTimes == 0 {
+			st.FirstHeight = height
+			if next == 0 {
+				next = height
+			}
+		}
+		if height > next {
+			addGap(next, height-1)
+		}
+		next = height + 1
+		st.BlockTimes++
+		st.LastHeight = height
+		return nil
+	})
+	if err != nil {
+		return st, fmt.Errorf("GetStats: %w", err)
+	}
+	if hr.To > 0 && next > 0 && next <= hr.To {
+		addGap(next, hr.To)
+	}
+
+	intervals, err := s.Params().Intervals()
+	if err != nil {
+		return st, fmt.Errorf("GetStats: %w", err)
+	}
+	for _, interval := range intervals {
+		if hr.Overlaps(interval.From, interval.To) {
+			st.ParamsIntervals++
+			st.ParamsHeights += interval.To - interval.From
+		}
+	}
+
+	addresses, err := s.Transactions().Addresses()
+	if err != nil {
+		return st, fmt.Errorf("GetStats: %w", err)
+	}
+	st.Addresses = len(addresses)
+	for _, address := range addresses {
+		txs, err := s.Transactions().AccountTransactions(address)
+		if err != nil {
+			return st, fmt.Errorf("GetStats: %w", err)
+		}
+		st.Transactions += len(txs)
+	}
+
+	fleets, err := s.Fleets().List()
+	if err != nil {
+		return st, fmt.Errorf("GetStats: %w", err)
+	}
+	st.Fleets = len(fleets)
+
+	return st, nil
+}
@@ -0,0 +1,69 @@
+package cachetool
+
+import (
+	"reflect"
+	"testing"
+
+	"monitoring-service/store"
+)
+
+func TestGetStats(t *testing.T) {
+	s := openStore(t, store.SQLite)
+	fill(t, s)
+	if err := s.BlockTimes().SetHighWaterMark(5); err != nil {
+		t.Fatalf("SetHighWaterMark: %v", err)
+	}
+
+	// block times are stored at 1-5, 8, 9 and 12
+	tests := []struct {
+		name        string
+		hr          HeightRange
+		maxGaps     int
+		wantFirst   uint
+		wantLast    uint
+		wantCount   int
+		wantMissing uint
+		wantNumGaps int
+		wantGaps    []Gap
+	}{
+		{"all heights", HeightRange{}, 10, 1, 12, 8, 4, 2, []Gap{{6, 7}, {10, 11}}},
+		{"gap before the first", HeightRange{From: 7}, 10, 8, 12, 3, 3, 2, []Gap{{7, 7}, {10, 11}}},
+		{"gap after the last", HeightRange{From: 9, To: 15}, 10, 9, 12, 2, 5, 2, []Gap{{10, 11}, {13, 15}}},
+		{"no gaps", HeightRange{From: 2, To: 5}, 10, 2, 5, 4, 0, 0, nil},
+		{"more gaps than listed", HeightRange{To: 20}, 1, 1, 12, 8, 12, 3, []Gap{{6, 7}}},
+		{"nothing stored", HeightRange{From: 30, To: 39}, 10, 0, 0, 0, 10, 1, []Gap{{30, 39}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := GetStats(s, tt.hr, tt.maxGaps)
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+
+			if st.BlockTimes != tt.wantCount || st.FirstHeight != tt.wantFirst || st.LastHeight != tt.wantLast {
+				t.Errorf("%d block times from %d to %d, want %d from %d to %d",
+					st.BlockTimes, st.FirstHeight, st.LastHeight, tt.wantCount, tt.wantFirst, tt.wantLast)
+			}
+			if st.MissingHeights != tt.wantMissing || st.NumGaps != tt.wantNumGaps || !reflect.DeepEqual(st.Gaps, tt.wantGaps) {
+				t.Errorf("%d heights missing in %d gaps %v, want %d in %d gaps %v",
+					st.MissingHeights, st.NumGaps, st.Gaps, tt.wantMissing, tt.wantNumGaps, tt.wantGaps)
+			}
+			if st.HighWaterMark != 5 || !st.HasHighWaterMark {
+				t.Errorf("high-water mark %d, %t, want 5", st.HighWaterMark, st.HasHighWaterMark)
+			}
+		})
+	}
+
+	st, err := GetStats(s, HeightRange{From: 10}, 10)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	// only the params interval overlapping the range counts, but as a whole
+	if st.ParamsIntervals != 1 || st.ParamsHeights != 12 {
+		t.Errorf("%d params intervals covering %d heights, want 1 covering 12", st.ParamsIntervals, st.ParamsHeights)
+	}
+	if st.Addresses != 1 || st.Transactions != 2 || st.Fleets != 1 {
+		t.Errorf("%d addresses with %d transactions and %d fleets, want 1, 2 and 1", st.Addresses, st.Transactions, st.Fleets)
+	}
+}
@@ -1,92 +1,145 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"monitoring-service/db"
-	pchttp "monitoring-service/http"
-	"monitoring-service/provider/pocket"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
-	"monitoring-service/monitoring"
+	"monitoring-service/backfill"
+	pchttp "monitoring-service/http"
+	"monitoring-service/provider/pocket"
+	"monitoring-service/store"
+	"monitoring-service/store/backend"
 
-	"git.mills.io/prologic/bitcask"
 	"github.com/go-kit/kit/log"
 )
 
 const (
-	batchSize        = 8
 	defaultPocketURL = "https://mainnet.gateway.pokt.network/v1/lb/61d4a60d431851003b628aa8/v1"
 )
 
+// uncachedBlockTimes keeps the provider from reading or writing the DB, so that the backfiller
+// decides what is stored and verification reads from the network.
+type uncachedBlockTimes struct{}
+
+func (uncachedBlockTimes) Get(uint) (time.Time, bool, error) { return time.Time{}, false, nil }
+func (uncachedBlockTimes) Set(uint, time.Time) error         { return nil }
+func (uncachedBlockTimes) Range(uint, uint, func(uint, time.Time) error) error {
+	return nil
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run returns the exit code, after the DB has been closed.
+func run() int {
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-	defaultDBPath, err := os.Getwd()
+	workDir, err := os.Getwd()
 	if err != nil {
 		_ = logger.Log("ERROR: failed to get working directory")
 		panic(err)
 	}
 
-	dbPath := flag.String("dbPath", defaultDBPath+"/../.pokt-calculator-db", "Path to DB data")
-	pocketRpcURL := flag.String("pocketURL", defaultPocketURL, "Pocket network RPC URL")
+	storeKind := flag.String("store", string(store.Bitcask), "Storage backend: bitcask, sqlite or memory")
+	dbPath := flag.String("dbPath", "", "Path to DB data (default: .pokt-calculator-db for bitcask, .pokt-calculator.sqlite for sqlite, in the parent of the working directory)")
+	pocketRpcURL := flag.String("pocketURL", defaultPocketURL, "Pocket network RPC URL (comma separated for multiple endpoints)")
+	rpcCooldown := flag.Duration("rpcCooldown", pocket.DefaultEndpointCooldown, "How long an unhealthy RPC endpoint is skipped")
+	rpcTimeout := flag.Duration("rpcTimeout", pocket.DefaultEndpointTimeout, "Timeout for a single RPC call to one endpoint")
+	rpcRetries := flag.Int("rpcRetries", pocket.DefaultRetryAttempts, "Max attempts for an RPC call that was rate limited or failed on every endpoint")
+	rpcRetryDelay := flag.Duration("rpcRetryDelay", pocket.DefaultRetryBaseDelay, "Initial delay between RPC retries, doubled on each attempt")
+	rpcRetryMaxDelay := flag.Duration("rpcRetryMaxDelay", pocket.DefaultRetryMaxDelay, "Max delay between RPC retries")
+	concurrency := flag.Int("concurrency", backfill.DefaultConcurrency, "Max concurrent block fetches")
+	from := flag.Uint("from", 0, "First height to fetch (default: the height after the last completed run)")
+	to := flag.Uint("to", 0, "Last height to fetch (default: the current height)")
+	progress := flag.Duration("progress", backfill.DefaultProgressInterval, "How often progress is reported")
+	verify := flag.Bool("verify", false, "Check a random sample of cached block times against the network instead of fetching")
+	verifySample := flag.Int("verifySample", backfill.DefaultVerifySample, "Number of heights checked by -verify")
+	verifyFix := flag.Bool("verifyFix", false, "With -verify, overwrite cached block times that differ from the network")
 	flag.Parse()
 
-	fmt.Println("Start...")
 	clientWithoutLogger := http.Client{}
 	httpClient := pchttp.NewClientWithLogger(clientWithoutLogger, logger)
 
 	// db
-	_ = logger.Log("bitcask DB", *dbPath)
-	bitcaskDB, err := bitcask.Open(*dbPath)
+	kind, err := store.ParseKind(*storeKind)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 2
+	}
+	if *dbPath == "" {
+		*dbPath = backend.DefaultPath(kind, filepath.Join(workDir, ".."))
+	}
+	_ = logger.Log("store", kind, "path", *dbPath)
+	dataStore, err := backend.Open(kind, *dbPath, logger)
 	if err != nil {
 		_ = logger.Log("ERROR opening database")
 		panic(err)
 	}
-	defer func(bitcaskDB *bitcask.Bitcask) {
-		err := bitcaskDB.Close()
-		if err != nil {
+	defer func() {
+		if err := dataStore.Close(); err != nil {
 			_ = logger.Log("ERROR closing database")
 		}
-	}(bitcaskDB)
-	blockTimesRepo := db.NewBlockTimesRepo(bitcaskDB)
-	paramsRepo := db.NewParamsRepo(bitcaskDB)
+	}()
+	blockTimesRepo := dataStore.BlockTimes()
+	paramsRepo := dataStore.Params()
 
-	// provider + MonitoringService
-	prv := pocket.NewPocketProvider(httpClient, *pocketRpcURL, blockTimesRepo, paramsRepo)
+	// provider
+	endpoints := pocket.NewEndpointPool(pocket.ParseEndpointURLs(*pocketRpcURL), *rpcCooldown, *rpcTimeout)
+	retry := pocket.RetryPolicy{MaxAttempts: *rpcRetries, BaseDelay: *rpcRetryDelay, MaxDelay: *rpcRetryMaxDelay}
+	prv := pocket.NewPocketProvider(httpClient, endpoints, retry, uncachedBlockTimes{}, paramsRepo, dataStore.Stakes())
 
-	pocketProvider := prv.WithLogger(logger)
-	nodeSvc := monitoring.NewService(pocketProvider)
+	// an interrupted run keeps the progress it made
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	height, err := nodeSvc.Height()
-	maxHeight := int(height)
+	backfiller := backfill.New(prv, blockTimesRepo, *concurrency, *progress, logger)
+	fromHeight, toHeight, err := backfiller.Range(ctx, *from, *to)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
 
-	for i := 1; i <= maxHeight; i++ {
-		thisBatchSize := batchSize
-		numLeft := maxHeight - i
-		if numLeft < batchSize {
-			thisBatchSize = numLeft
+	if *verify {
+		// by default, check the heights that have been backfilled
+		if *from == 0 {
+			fromHeight = 1
+		}
+		if mark, exists, err := blockTimesRepo.HighWaterMark(); err == nil && exists && *to == 0 {
+			toHeight = mark
 		}
 
-		fmt.Printf("This batch size: %d\n", thisBatchSize)
-
-		heights := make([]uint, thisBatchSize)
-		for j := 0; j < thisBatchSize; j++ {
-			heights[j] = uint(i + j)
+		_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("Verifying %d block times between %d and %d", *verifySample, fromHeight, toHeight))
+		res, err := backfiller.Verify(ctx, fromHeight, toHeight, *verifySample, *verifyFix)
+		for _, m := range res.Mismatches {
+			_ = logger.Log("level", "WARN", "msg", fmt.Sprintf("Block %d: cached %s, network %s", m.Height, m.Stored, m.Network))
 		}
+		_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("Checked %d block times, %d not cached, %d mismatched (fixed: %t)",
+			res.NumChecked, res.NumMissing, len(res.Mismatches), *verifyFix))
+		if err != nil {
+			_ = logger.Log("level", "ERROR", "msg", err.Error())
+			return 1
+		}
+		if len(res.Mismatches) > 0 && !*verifyFix {
+			return 2
+		}
+		return 0
+	}
 
-		(func() {
-			fmt.Printf("fetching blocks %v\n", heights)
-			if _, err := nodeSvc.BlockTimes(heights); err != nil {
-				fmt.Printf("ERROR: %s", err)
-			}
-			for _, n := range heights {
-				if _, err := nodeSvc.ParamsAtHeight(int64(n), false); err != nil {
-					fmt.Printf("ERROR: %s", err)
-				}
-			}
-		})()
-		i = i + batchSize - 1
+	_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("Fetching block times from %d to %d", fromHeight, toHeight))
+	res, err := backfiller.Run(ctx, fromHeight, toHeight)
+	_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("Fetched %d block times, %d already cached, %d failed, high-water mark %d (took %s)",
+		res.NumFetched, res.NumCached, res.NumFailed, res.HighWaterMark, res.Took.Round(time.Second)))
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
 	}
+	return 0
 }
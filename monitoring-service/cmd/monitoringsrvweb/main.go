@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net"
@@ -8,71 +9,179 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-
-	"git.mills.io/prologic/bitcask"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/go-kit/kit/log"
 	"github.com/oklog/oklog/pkg/group"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 
+	"monitoring-service/alerting"
 	"monitoring-service/api"
-	"monitoring-service/db"
+	"monitoring-service/exporter"
+	"monitoring-service/fleet"
+	"monitoring-service/follower"
 	pchttp "monitoring-service/http"
+	"monitoring-service/indexer"
+	"monitoring-service/metrics"
 	"monitoring-service/monitoring"
 	"monitoring-service/provider/pocket"
+	"monitoring-service/relaysuite"
+	"monitoring-service/store"
+	"monitoring-service/store/backend"
 )
 
 const (
 	defaultPort      = "7878"
 	defaultHost      = "localhost"
+	alertTimeout     = 10 * time.Second
 	defaultPocketURL = "https://mainnet.gateway.pokt.network/v1/lb/61d4a60d431851003b628aa8/v1"
+	// {chain} is replaced by the portal prefix of the chain under test
+	defaultRelayReferenceURL = "https://{chain}.gateway.pokt.network/v1/lb/61d4a60d431851003b628aa8"
 )
 
 func main() {
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
 
-	defaultDBPath, err := os.Getwd()
+	workDir, err := os.Getwd()
 	if err != nil {
 		_ = logger.Log("ERROR: failed to get working directory")
 		panic(err)
 	}
 
 	httpAddr := flag.String("listen", defaultHost+":"+defaultPort, "HTTP listen address")
-	dbPath := flag.String("dbPath", defaultDBPath+"/.pokt-calculator-db", "Path to DB data")
-	pocketRpcURL := flag.String("pocketURL", defaultPocketURL, "Pocket network RPC URL")
+	storeKind := flag.String("store", string(store.Bitcask), "Storage backend: bitcask, sqlite or memory")
+	dbPath := flag.String("dbPath", "", "Path to DB data (default: .pokt-calculator-db for bitcask, .pokt-calculator.sqlite for sqlite, in the working directory)")
+	pocketRpcURL := flag.String("pocketURL", defaultPocketURL, "Pocket network RPC URL (comma separated for multiple endpoints)")
+	rpcCooldown := flag.Duration("rpcCooldown", pocket.DefaultEndpointCooldown, "How long an unhealthy RPC endpoint is skipped")
+	rpcTimeout := flag.Duration("rpcTimeout", pocket.DefaultEndpointTimeout, "Timeout for a single RPC call to one endpoint")
+	rpcRetries := flag.Int("rpcRetries", pocket.DefaultRetryAttempts, "Max attempts for an RPC call that was rate limited or failed on every endpoint")
+	rpcRetryDelay := flag.Duration("rpcRetryDelay", pocket.DefaultRetryBaseDelay, "Initial delay between RPC retries, doubled on each attempt")
+	rpcRetryMaxDelay := flag.Duration("rpcRetryMaxDelay", pocket.DefaultRetryMaxDelay, "Max delay between RPC retries")
+	concurrency := flag.Int("concurrency", monitoring.DefaultConcurrency, "Max concurrent upstream lookups per request")
+	indexAddresses := flag.String("index", "", "Node addresses to index claims and proofs for in the background (comma separated)")
+	indexInterval := flag.Duration("indexInterval", indexer.DefaultInterval, "How often the indexer checks for new transactions")
+	indexReorgDepth := flag.Uint("indexReorgDepth", indexer.DefaultReorgDepth, "Number of blocks below the checkpoint the indexer re-checks on every run")
+	follow := flag.Bool("follow", false, "Fetch the block time and params of every new block in the background")
+	followInterval := flag.Duration("followInterval", follower.DefaultInterval, "How often the follower checks for new blocks")
+	followMaxCatchUp := flag.Uint("followMaxCatchUp", follower.DefaultMaxCatchUp, "Max number of blocks behind the tip the follower catches up on when it starts")
+	exportAddresses := flag.String("export", "", "Node addresses to publish balance, status and reward metrics for on /metrics (comma separated)")
+	exportInterval := flag.Duration("exportInterval", exporter.DefaultInterval, "How often the exporter collects node metrics")
+	alertsConfig := flag.String("alerts", "", "Path to a YAML file of alerting rules and notifiers (default: no alerting)")
+	syncReferenceURLs := flag.String("syncReferenceURLs", "", "Pocket network RPC URLs whose highest height node sync status is checked against (comma separated, default: -pocketURL)")
+	syncTolerance := flag.Uint("syncTolerance", monitoring.DefaultSyncTolerance, "Number of blocks a node can be behind the network and still count as synced")
+	relayReferenceURL := flag.String("relayReferenceURL", defaultRelayReferenceURL, "URL the relay suite compares chain heights with, {chain} being replaced by the chain's portal prefix (empty to skip)")
+	relayTimeout := flag.Duration("relayTimeout", relaysuite.DefaultTimeout, "Timeout for a single relay of the relay suite")
 	flag.Parse()
 
 	router := api.NewRouter(logger)
 
+	// metrics
+	instruments := metrics.New()
+	router.Instrument(instruments.HTTPRequests, instruments.HTTPDuration)
+	router.Mux.Handle(metrics.Path, metrics.Handler()).Methods(http.MethodGet)
+
 	_ = logger.Log("transport", "HTTP", "MySQL Connect", "Success")
 
 	// accounts
 	clientWithoutLogger := http.Client{}
-	httpClient := pchttp.NewClientWithLogger(clientWithoutLogger, logger)
+	httpClient := pchttp.NewClientWithMetrics(pchttp.NewClientWithLogger(clientWithoutLogger, logger), instruments.RPCDuration)
 
 	// db
-	_ = logger.Log("bitcask DB", *dbPath)
-	bitcaskDB, err := bitcask.Open(*dbPath)
+	kind, err := store.ParseKind(*storeKind)
+	if err != nil {
+		_ = logger.Log("ERROR", err)
+		os.Exit(2)
+	}
+	if *dbPath == "" {
+		*dbPath = backend.DefaultPath(kind, workDir)
+	}
+	_ = logger.Log("store", kind, "path", *dbPath)
+	dataStore, err := backend.Open(kind, *dbPath, logger)
 	if err != nil {
 		_ = logger.Log("ERROR opening database")
 		panic(err)
 	}
-	defer func(bitcaskDB *bitcask.Bitcask) {
-		err := bitcaskDB.Close()
-		if err != nil {
+	defer func() {
+		if err := dataStore.Close(); err != nil {
 			_ = logger.Log("ERROR closing database")
 		}
-	}(bitcaskDB)
-	blockTimesRepo := db.NewBlockTimesRepo(bitcaskDB)
-	paramsRepo := db.NewParamsRepo(bitcaskDB)
+	}()
+	blockTimesRepo := metrics.InstrumentBlockTimes(dataStore.BlockTimes(), instruments.CacheLookups)
+	paramsRepo := metrics.InstrumentParams(dataStore.Params(), instruments.CacheLookups)
+	transactionsRepo := dataStore.Transactions()
+	stakesRepo := dataStore.Stakes()
+	fleetsRepo := dataStore.Fleets()
 
 	// provider
-	prv := pocket.NewPocketProvider(httpClient, *pocketRpcURL, blockTimesRepo, paramsRepo)
+	endpoints := pocket.NewEndpointPool(pocket.ParseEndpointURLs(*pocketRpcURL), *rpcCooldown, *rpcTimeout)
+	retry := pocket.RetryPolicy{MaxAttempts: *rpcRetries, BaseDelay: *rpcRetryDelay, MaxDelay: *rpcRetryMaxDelay}
+	prv := pocket.NewPocketProvider(httpClient, endpoints, retry, blockTimesRepo, paramsRepo, stakesRepo)
 	pocketProvider := prv.WithLogger(logger)
-	nodeSvc := monitoring.NewService(pocketProvider)
+
+	// indexer
+	var txIndex monitoring.TransactionIndex
+	trackedAddresses := indexer.ParseAddresses(*indexAddresses)
+	indexSvc := monitoring.NewService(pocketProvider, *concurrency, nil)
+	idx := indexer.New(&indexSvc, transactionsRepo, trackedAddresses, *indexInterval, *indexReorgDepth, logger)
+	idx.Instrument(instruments.IndexerLag, instruments.IndexerLastSuccess)
+	if len(trackedAddresses) > 0 {
+		txIndex = idx
+	}
+	indexerTransport := indexer.NewTransport(idx)
+	router.AddRoutes(indexerTransport.Routes)
+
+	// sync references each get a pool of their own, so that every one of them is asked for its height
+	var syncReferences []monitoring.HeightProvider
+	for _, url := range pocket.ParseEndpointURLs(*syncReferenceURLs) {
+		refEndpoints := pocket.NewEndpointPool([]string{url}, *rpcCooldown, *rpcTimeout)
+		ref := pocket.NewPocketProvider(httpClient, refEndpoints, retry, blockTimesRepo, paramsRepo, stakesRepo)
+		syncReferences = append(syncReferences, ref.WithLogger(logger))
+	}
+
+	nodeSvc := monitoring.NewService(pocketProvider, *concurrency, txIndex).WithSync(syncReferences, *syncTolerance)
 	//accountsSvc = accounts.NewLoggingService(logger, accountsSvc)
 	nodeTransport := monitoring.NewTransport(nodeSvc)
 	router.AddRoutes(nodeTransport.Routes)
+
+	// exporter
+	exportedAddresses := indexer.ParseAddresses(*exportAddresses)
+	nodeExporter := exporter.New(&nodeSvc, exportedAddresses, *exportInterval, logger)
+	if len(exportedAddresses) > 0 {
+		stdprometheus.MustRegister(nodeExporter)
+	}
+
+	// alerting
+	var alertEngine *alerting.Engine
+	if *alertsConfig != "" {
+		cfg, err := alerting.LoadConfig(*alertsConfig)
+		if err != nil {
+			_ = logger.Log("ERROR", err)
+			os.Exit(2)
+		}
+		// webhooks get a client of their own, so their URLs, which often hold a secret, aren't logged
+		alertEngine, err = alerting.NewEngine(&nodeSvc, cfg, &http.Client{Timeout: alertTimeout}, logger)
+		if err != nil {
+			_ = logger.Log("ERROR", err)
+			os.Exit(2)
+		}
+		alertingTransport := alerting.NewTransport(alertEngine)
+		router.AddRoutes(alertingTransport.Routes)
+	}
+
+	tipFollower := follower.New(pocketProvider, blockTimesRepo, *followInterval, *followMaxCatchUp, logger)
+	tipFollower.Instrument(instruments.FollowerLag)
+	followerTransport := follower.NewTransport(tipFollower)
+	router.AddRoutes(followerTransport.Routes)
+
+	relaySuiteSvc := relaysuite.NewService(pocketProvider, httpClient, *relayReferenceURL, *relayTimeout)
+	relaySuiteTransport := relaysuite.NewTransport(relaySuiteSvc)
+	router.AddRoutes(relaySuiteTransport.Routes)
+
+	fleetSvc := fleet.NewService(&nodeSvc, fleetsRepo, *concurrency)
+	fleetTransport := fleet.NewTransport(fleetSvc)
+	router.AddRoutes(fleetTransport.Routes)
 	//createAccountFixtures(accountsSvc, logger)
 
 	var g group.Group
@@ -93,6 +202,46 @@ func main() {
 			}
 		})
 	}
+	if txIndex != nil {
+		// The indexer keeps the tracked addresses' transactions up to date until shutdown.
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			_ = logger.Log("indexer", "started", "addresses", *indexAddresses)
+			return idx.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+	if len(exportedAddresses) > 0 {
+		// The exporter refreshes the node metrics served on /metrics until shutdown.
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			_ = logger.Log("exporter", "started", "addresses", *exportAddresses)
+			return nodeExporter.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+	if alertEngine != nil {
+		// The alerting engine checks its rules until shutdown.
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			_ = logger.Log("alerting", "started", "config", *alertsConfig)
+			return alertEngine.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+	if *follow {
+		// The follower keeps the cache up to date with the tip until shutdown.
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			_ = logger.Log("follower", "started", "interval", *followInterval)
+			return tipFollower.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
 	{
 		// This function just sits and waits for ctrl-C.
 		cancelInterrupt := make(chan struct{})
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"monitoring-service/cachetool"
+	"monitoring-service/store"
+	"monitoring-service/store/backend"
+
+	"github.com/go-kit/kit/log"
+)
+
+const usage = `Usage: pokt-cache <command> [flags]
+
+Commands:
+  export   write cached block times and params as NDJSON or CSV
+  import   read block times and params written by export
+  migrate  copy every repository from one store to another
+  stats    count what a store holds and find missing block times
+  compact  reclaim the space of deleted and overwritten data
+
+Run pokt-cache <command> -h for the flags of a command.
+`
+
+// defaultMaxGaps is the number of gaps listed by stats.
+const defaultMaxGaps = 20
+
+type command func(args []string, logger log.Logger) int
+
+var commands = map[string]command{
+	"export":  runExport,
+	"import":  runImport,
+	"migrate": runMigrate,
+	"stats":   runStats,
+	"compact": runCompact,
+}
+
+func main() {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	os.Exit(cmd(os.Args[2:], logger))
+}
+
+// storeFlags are the flags that pick the store a command works on.
+type storeFlags struct {
+	kind string
+	path string
+}
+
+func addStoreFlags(fs *flag.FlagSet, kindName, pathName string) *storeFlags {
+	f := &storeFlags{}
+	fs.StringVar(&f.kind, kindName, string(store.Bitcask), "Storage backend: bitcask, sqlite or memory")
+	fs.StringVar(&f.path, pathName, "", "Path to DB data (default: .pokt-calculator-db for bitcask, .pokt-calculator.sqlite for sqlite, in the working directory)")
+	return f
+}
+
+func (f *storeFlags) open(logger log.Logger) (store.Store, error) {
+	kind, err := store.ParseKind(f.kind)
+	if err != nil {
+		return nil, err
+	}
+
+	path := f.path
+	if path == "" {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		path = backend.DefaultPath(kind, workDir)
+	}
+
+	_ = logger.Log("store", kind, "path", path)
+	return backend.Open(kind, path, logger)
+}
+
+func heightsFlag(fs *flag.FlagSet) *string {
+	return fs.String("heights", "", "Height range to work on, as <from>-<to>; either side may be left out (default: every height)")
+}
+
+// openStore parses the store and height flags, logging any error.
+func openStore(sf *storeFlags, heights string, logger log.Logger) (store.Store, cachetool.HeightRange, bool) {
+	hr, err := cachetool.ParseHeightRange(heights)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return nil, hr, false
+	}
+
+	s, err := sf.open(logger)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return nil, hr, false
+	}
+
+	return s, hr, true
+}
+
+func closeStore(s store.Store, logger log.Logger) {
+	if err := s.Close(); err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+	}
+}
+
+// formatFor returns the format named by the flag, or else the one implied by the file name.
+func formatFor(name, file string) (cachetool.Format, error) {
+	if name != "" {
+		return cachetool.ParseFormat(name)
+	}
+	if strings.HasSuffix(strings.ToLower(file), ".csv") {
+		return cachetool.CSV, nil
+	}
+	return cachetool.NDJSON, nil
+}
+
+func runExport(args []string, logger log.Logger) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	sf := addStoreFlags(fs, "store", "dbPath")
+	heights := heightsFlag(fs)
+	formatName := fs.String("format", "", "Output format, ndjson or csv (default: csv for a -out ending in .csv, ndjson otherwise)")
+	out := fs.String("out", "", "File to write (default: stdout)")
+	_ = fs.Parse(args)
+
+	format, err := formatFor(*formatName, *out)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 2
+	}
+
+	s, hr, ok := openStore(sf, *heights, logger)
+	if !ok {
+		return 1
+	}
+	defer closeStore(s, logger)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			_ = logger.Log("level", "ERROR", "msg", err.Error())
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	counts, err := cachetool.Export(w, s, format, hr)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
+
+	_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("Exported %d block times and %d params intervals (%s)",
+		counts.BlockTimes, counts.ParamsIntervals, hr))
+	return 0
+}
+
+func runImport(args []string, logger log.Logger) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	sf := addStoreFlags(fs, "store", "dbPath")
+	heights := heightsFlag(fs)
+	formatName := fs.String("format", "", "Input format, ndjson or csv (default: csv for an -in ending in .csv, ndjson otherwise)")
+	in := fs.String("in", "", "File to read (default: stdin)")
+	_ = fs.Parse(args)
+
+	format, err := formatFor(*formatName, *in)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			_ = logger.Log("level", "ERROR", "msg", err.Error())
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	s, hr, ok := openStore(sf, *heights, logger)
+	if !ok {
+		return 1
+	}
+	defer closeStore(s, logger)
+
+	counts, err := cachetool.Import(r, s, format, hr)
+	_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("Imported %d block times and %d params intervals (%s)",
+		counts.BlockTimes, counts.ParamsIntervals, hr))
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func runMigrate(args []string, logger log.Logger) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := addStoreFlags(fs, "from", "fromPath")
+	to := addStoreFlags(fs, "to", "toPath")
+	heights := heightsFlag(fs)
+	_ = fs.Parse(args)
+
+	if from.kind == to.kind && from.path == to.path {
+		_ = logger.Log("level", "ERROR", "msg", "-from and -to are the same store")
+		return 2
+	}
+
+	src, hr, ok := openStore(from, *heights, logger)
+	if !ok {
+		return 1
+	}
+	defer closeStore(src, logger)
+
+	dst, _, ok := openStore(to, *heights, logger)
+	if !ok {
+		return 1
+	}
+	defer closeStore(dst, logger)
+
+	res, err := cachetool.Migrate(dst, src, hr)
+	_ = logger.Log("level", "INFO", "msg", fmt.Sprintf(
+		"Copied %d block times, %d params intervals, %d transactions of %d addresses and %d fleets (%s), high-water mark %d",
+		res.BlockTimes, res.ParamsIntervals, res.Transactions, res.Addresses, res.Fleets, hr, res.HighWaterMark))
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func runStats(args []string, logger log.Logger) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	sf := addStoreFlags(fs, "store", "dbPath")
+	heights := heightsFlag(fs)
+	maxGaps := fs.Int("maxGaps", defaultMaxGaps, "Max number of gaps listed")
+	_ = fs.Parse(args)
+
+	s, hr, ok := openStore(sf, *heights, logger)
+	if !ok {
+		return 1
+	}
+	defer closeStore(s, logger)
+
+	st, err := cachetool.GetStats(s, hr, *maxGaps)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
+
+	fmt.Printf("block times:      %d (%s)\n", st.BlockTimes, hr)
+	if st.BlockTimes > 0 {
+		fmt.Printf("  heights:        %d to %d\n", st.FirstHeight, st.LastHeight)
+	}
+	if st.HasHighWaterMark {
+		fmt.Printf("  high-water mark: %d\n", st.HighWaterMark)
+	}
+	fmt.Printf("  missing:        %d heights in %d gaps\n", st.MissingHeights, st.NumGaps)
+	for _, g := range st.Gaps {
+		if g.From == g.To {
+			fmt.Printf("    %d\n", g.From)
+		} else {
+			fmt.Printf("    %d to %d\n", g.From, g.To)
+		}
+	}
+	if st.NumGaps > len(st.Gaps) {
+		fmt.Printf("    ... and %d more\n", st.NumGaps-len(st.Gaps))
+	}
+	fmt.Printf("params intervals: %d (covering %d heights)\n", st.ParamsIntervals, st.ParamsHeights)
+	fmt.Printf("transactions:     %d of %d indexed addresses\n", st.Transactions, st.Addresses)
+	fmt.Printf("fleets:           %d\n", st.Fleets)
+
+	return 0
+}
+
+func runCompact(args []string, logger log.Logger) int {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	sf := addStoreFlags(fs, "store", "dbPath")
+	_ = fs.Parse(args)
+
+	s, err := sf.open(logger)
+	if err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
+	defer closeStore(s, logger)
+
+	c, ok := s.(store.Compacter)
+	if !ok {
+		_ = logger.Log("level", "INFO", "msg", fmt.Sprintf("The %s store has nothing to compact", sf.kind))
+		return 0
+	}
+
+	if err = c.Compact(); err != nil {
+		_ = logger.Log("level", "ERROR", "msg", err.Error())
+		return 1
+	}
+
+	_ = logger.Log("level", "INFO", "msg", "Compacted")
+	return 0
+}
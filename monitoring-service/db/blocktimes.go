@@ -1,6 +1,7 @@
 package db
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -8,6 +9,11 @@ import (
 	"git.mills.io/prologic/bitcask"
 )
 
+var (
+	blockTimeKeyPrefix = []byte("bt:")
+	highWaterMarkKey   = []byte("meta:btmark")
+)
+
 type BlockTimesRepo struct {
 	db *bitcask.Bitcask
 }
@@ -16,11 +22,15 @@ func NewBlockTimesRepo(db *bitcask.Bitcask) BlockTimesRepo {
 	return BlockTimesRepo{db: db}
 }
 
+// Get returns the block time of height. A corrupt entry is reported with an error wrapping
+// store.ErrCorrupt and exists == false, so that the caller refetches and overwrites it.
 func (r BlockTimesRepo) Get(height uint) (t time.Time, exists bool, err error) {
-	heightB, _ := json.Marshal(height)
-	blkTimeB, err := r.db.Get(heightB)
+	blkTimeB, exists, err := get(r.db, blockTimeKey(height))
 	if err != nil {
-		return time.Time{}, false, fmt.Errorf("BlockTimesRepo.Get [%d]: %s", height, err)
+		return time.Time{}, false, fmt.Errorf("BlockTimesRepo.Get [%d]: %w", height, err)
+	}
+	if !exists {
+		return time.Time{}, false, nil
 	}
 
 	var blockTime time.Time
@@ -32,12 +42,74 @@ func (r BlockTimesRepo) Get(height uint) (t time.Time, exists bool, err error) {
 }
 
 func (r BlockTimesRepo) Set(height uint, t time.Time) error {
-	heightB, _ := json.Marshal(height)
 	timeB, _ := json.Marshal(t)
-	err := r.db.Put(heightB, timeB)
+	err := put(r.db, blockTimeKey(height), timeB)
 	if err != nil {
 		return fmt.Errorf("BlockTimesRepo.Set: %s", err)
 	}
 
 	return nil
 }
+
+// HighWaterMark returns the height up to which every block time has been stored.
+func (r BlockTimesRepo) HighWaterMark() (height uint, exists bool, err error) {
+	heightB, exists, err := get(r.db, highWaterMarkKey)
+	if err != nil {
+		return 0, false, fmt.Errorf("BlockTimesRepo.HighWaterMark: %w", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+	if len(heightB) != 8 {
+		return 0, false, fmt.Errorf("BlockTimesRepo.HighWaterMark: invalid value")
+	}
+
+	return uint(binary.BigEndian.Uint64(heightB)), true, nil
+}
+
+func (r BlockTimesRepo) SetHighWaterMark(height uint) error {
+	heightB := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightB, uint64(height))
+	if err := put(r.db, highWaterMarkKey, heightB); err != nil {
+		return fmt.Errorf("BlockTimesRepo.SetHighWaterMark [%d]: %s", height, err)
+	}
+
+	return nil
+}
+
+// Range calls fn with every stored block time in [from, to], in height order. A zero to means no
+// upper bound. Corrupt entries are skipped, as they will be refetched.
+func (r BlockTimesRepo) Range(from, to uint, fn func(height uint, t time.Time) error) error {
+	end := uint64(to) + 1
+	if to == 0 {
+		end = 1<<64 - 1
+	}
+
+	var keys [][]byte
+	err := r.db.Range(blockTimeKey(from), heightKey(blockTimeKeyPrefix, end-1), func(key []byte) error {
+		k := make([]byte, len(key))
+		copy(k, key)
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("BlockTimesRepo.Range: %s", err)
+	}
+
+	for _, k := range keys {
+		height := uint(binary.BigEndian.Uint64(k[len(blockTimeKeyPrefix):]))
+		t, exists, err := r.Get(height)
+		if err != nil || !exists {
+			continue
+		}
+		if err = fn(height, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func blockTimeKey(height uint) []byte {
+	return heightKey(blockTimeKeyPrefix, uint64(height))
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"monitoring-service/store"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+const checksumSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// seal prefixes a value with the CRC-32C of its bytes.
+func seal(value []byte) []byte {
+	sealed := make([]byte, checksumSize+len(value))
+	binary.BigEndian.PutUint32(sealed, crc32.Checksum(value, castagnoli))
+	copy(sealed[checksumSize:], value)
+	return sealed
+}
+
+// unseal returns the value of a sealed entry, or an error wrapping store.ErrCorrupt if the
+// checksum doesn't match.
+func unseal(sealed []byte) ([]byte, error) {
+	if len(sealed) < checksumSize {
+		return nil, fmt.Errorf("%w: value shorter than its checksum", store.ErrCorrupt)
+	}
+
+	value := sealed[checksumSize:]
+	if binary.BigEndian.Uint32(sealed) != crc32.Checksum(value, castagnoli) {
+		return nil, fmt.Errorf("%w: checksum mismatch", store.ErrCorrupt)
+	}
+
+	return value, nil
+}
+
+// get reads and unseals the value of key. exists is false if the key isn't stored.
+func get(db *bitcask.Bitcask, key []byte) (value []byte, exists bool, err error) {
+	if !db.Has(key) {
+		return nil, false, nil
+	}
+
+	sealed, err := db.Get(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err = unseal(sealed)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func put(db *bitcask.Bitcask, key, value []byte) error {
+	return db.Put(key, seal(value))
+}
+
+// heightKey appends a height to prefix as 8 big endian bytes, so that keys sort by height.
+func heightKey(prefix []byte, height uint64) []byte {
+	keyB := make([]byte, len(prefix)+8)
+	copy(keyB, prefix)
+	binary.BigEndian.PutUint64(keyB[len(prefix):], height)
+	return keyB
+}
+
+// scanKeys collects the keys matching prefix before any of them are read, as bitcask holds
+// its read lock for the duration of a scan.
+func scanKeys(db *bitcask.Bitcask, prefix []byte) ([][]byte, error) {
+	var keys [][]byte
+	err := db.Scan(prefix, func(key []byte) error {
+		k := make([]byte, len(key))
+		copy(k, key)
+		keys = append(keys, k)
+		return nil
+	})
+
+	return keys, err
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"monitoring-service/pocket"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+var fleetKeyPrefix = []byte("fleet:")
+
+type FleetsRepo struct {
+	db *bitcask.Bitcask
+}
+
+func NewFleetsRepo(db *bitcask.Bitcask) FleetsRepo {
+	return FleetsRepo{db: db}
+}
+
+func (r FleetsRepo) Get(name string) (f pocket.Fleet, exists bool, err error) {
+	fleetB, exists, err := get(r.db, r.key(name))
+	if err != nil {
+		return pocket.Fleet{}, false, fmt.Errorf("FleetsRepo.Get [%s]: %w", name, err)
+	}
+	if !exists {
+		return pocket.Fleet{}, false, nil
+	}
+
+	if err = json.Unmarshal(fleetB, &f); err != nil {
+		return pocket.Fleet{}, false, fmt.Errorf("FleetsRepo.Get: failed to parse json for %s: %s", name, err)
+	}
+
+	return f, true, nil
+}
+
+func (r FleetsRepo) Set(f pocket.Fleet) error {
+	fleetB, _ := json.Marshal(f)
+	if err := put(r.db, r.key(f.Name), fleetB); err != nil {
+		return fmt.Errorf("FleetsRepo.Set [%s]: %s", f.Name, err)
+	}
+
+	return nil
+}
+
+func (r FleetsRepo) Delete(name string) error {
+	if err := r.db.Delete(r.key(name)); err != nil {
+		return fmt.Errorf("FleetsRepo.Delete [%s]: %s", name, err)
+	}
+
+	return nil
+}
+
+// List returns every fleet, ordered by name.
+func (r FleetsRepo) List() ([]pocket.Fleet, error) {
+	keys, err := scanKeys(r.db, fleetKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("FleetsRepo.List: %s", err)
+	}
+
+	fleets := make([]pocket.Fleet, 0, len(keys))
+	for _, k := range keys {
+		fleetB, _, err := get(r.db, k)
+		if err != nil {
+			return nil, fmt.Errorf("FleetsRepo.List [%s]: %w", k[len(fleetKeyPrefix):], err)
+		}
+
+		var f pocket.Fleet
+		if err = json.Unmarshal(fleetB, &f); err != nil {
+			return nil, fmt.Errorf("FleetsRepo.List: failed to parse json for %s: %s", k, err)
+		}
+		fleets = append(fleets, f)
+	}
+
+	sort.Slice(fleets, func(i, j int) bool {
+		return fleets[i].Name < fleets[j].Name
+	})
+
+	return fleets, nil
+}
+
+func (r FleetsRepo) key(name string) []byte {
+	return append(append([]byte{}, fleetKeyPrefix...), name...)
+}
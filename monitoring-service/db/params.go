@@ -1,26 +1,52 @@
 package db
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	"monitoring-service/pocket"
+	"monitoring-service/store"
 
 	"git.mills.io/prologic/bitcask"
 )
 
+const legacyAllParamsName = "pocketAllParams"
+
+var (
+	paramKeyPrefix          = []byte("param:")
+	paramsIntervalKeyPrefix = []byte("paramsiv:")
+)
+
+// ParamsRepo stores network params in bitcask. The params of every height are stored as
+// validity intervals, see store.ParamsIntervals.
 type ParamsRepo struct {
+	*store.ParamsIntervals
 	db *bitcask.Bitcask
 }
 
+// intervalValue is the stored value of an interval, keyed by its From height.
+type intervalValue struct {
+	To     uint             `json:"to"`
+	Params pocket.AllParams `json:"params"`
+}
+
 func NewParamsRepo(db *bitcask.Bitcask) ParamsRepo {
-	return ParamsRepo{db: db}
+	return ParamsRepo{ParamsIntervals: store.NewParamsIntervals(bitcaskIntervals{db: db}), db: db}
 }
 
 func (r ParamsRepo) Get(name string, height int64) (p pocket.Params, exists bool, err error) {
-	keyB := r.key(name, height)
-	paramsB, err := r.db.Get(keyB)
+	paramsB, exists, err := get(r.db, paramKey(name, height))
 	if err != nil {
-		return pocket.Params{}, false, fmt.Errorf("ParamsRepo.Get [%s, %d]: %s", name, height, err)
+		return pocket.Params{}, false, fmt.Errorf("ParamsRepo.Get [%s, %d]: %w", name, height, err)
+	}
+	if !exists {
+		return pocket.Params{}, false, nil
 	}
 
 	var params pocket.Params
@@ -32,9 +58,8 @@ func (r ParamsRepo) Get(name string, height int64) (p pocket.Params, exists bool
 }
 
 func (r ParamsRepo) Set(name string, height int64, p pocket.Params) error {
-	keyB := r.key(name, height)
 	paramsB, _ := json.Marshal(p)
-	err := r.db.Put(keyB, paramsB)
+	err := put(r.db, paramKey(name, height), paramsB)
 	if err != nil {
 		return fmt.Errorf("ParamsRepo.Set [%s, %d]: %s", name, height, err)
 	}
@@ -42,46 +67,120 @@ func (r ParamsRepo) Set(name string, height int64, p pocket.Params) error {
 	return nil
 }
 
-func (r ParamsRepo) GetAll(height int64) (params pocket.AllParams, exists bool, err error) {
-	keyB := r.key("pocketAllParams", height)
-	paramsB, err := r.db.Get(keyB)
+// MigrateSnapshots folds the params snapshots stored per height by earlier versions into
+// intervals, deleting the snapshots, and returns how many were migrated.
+func (r ParamsRepo) MigrateSnapshots() (int, error) {
+	var keys [][]byte
+	err := r.db.Scan([]byte(`"`), func(key []byte) error {
+		if bytes.HasSuffix(key, []byte(legacyAllParamsName+`"`)) {
+			k := make([]byte, len(key))
+			copy(k, key)
+			keys = append(keys, k)
+		}
+		return nil
+	})
 	if err != nil {
-		return pocket.AllParams{}, false, fmt.Errorf("ParamsRepo.GetAll: %s", err)
+		return 0, fmt.Errorf("ParamsRepo.MigrateSnapshots: %s", err)
 	}
 
-	if err = json.Unmarshal(paramsB, &params); err != nil {
-		return pocket.AllParams{}, false, fmt.Errorf("ParamsRepo.GetAll: %s", err)
+	type snapshot struct {
+		height uint
+		key    []byte
+	}
+	snapshots := make([]snapshot, 0, len(keys))
+	for _, k := range keys {
+		var name string
+		if err := json.Unmarshal(k, &name); err != nil {
+			continue
+		}
+		height, err := strconv.ParseUint(strings.TrimSuffix(name, legacyAllParamsName), 10, 64)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{height: uint(height), key: k})
 	}
 
-	if err = params.Validate(); err != nil {
-		return pocket.AllParams{}, false, fmt.Errorf("ParamsRepo.Getall: %s", err)
+	// in height order, so that snapshots of adjacent heights with the same params merge
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].height < snapshots[j].height
+	})
+
+	for _, s := range snapshots {
+		paramsB, err := r.db.Get(s.key)
+		if err != nil {
+			return 0, fmt.Errorf("ParamsRepo.MigrateSnapshots [%d]: %s", s.height, err)
+		}
+
+		var params pocket.AllParams
+		// height 0 meant the latest params, which can't be placed in an interval
+		if json.Unmarshal(paramsB, &params) == nil && params.Validate() == nil && s.height > 0 {
+			if err = r.SetAll(int64(s.height), params); err != nil {
+				return 0, fmt.Errorf("ParamsRepo.MigrateSnapshots [%d]: %s", s.height, err)
+			}
+		}
+
+		if err = r.db.Delete(s.key); err != nil {
+			return 0, fmt.Errorf("ParamsRepo.MigrateSnapshots [%d]: %s", s.height, err)
+		}
 	}
 
-	return params, true, nil
+	return len(snapshots), nil
 }
 
-func (r ParamsRepo) DelAll(height int64) error {
-	keyB := r.key("pocketAllParams", height)
-	if err := r.db.Delete(keyB); err != nil {
-		return fmt.Errorf("DelAll: %s", err)
-	}
-
-	return nil
+// bitcaskIntervals stores params intervals under paramsIntervalKeyPrefix and their From height.
+type bitcaskIntervals struct {
+	db *bitcask.Bitcask
 }
 
-func (r ParamsRepo) SetAll(height int64, params pocket.AllParams) error {
-	keyB := r.key("pocketAllParams", height)
-	paramsB, _ := json.Marshal(params)
-	err := r.db.Put(keyB, paramsB)
+// LoadIntervals returns the stored intervals. Corrupt intervals are deleted, so that the params
+// of their heights are refetched rather than failing every lookup.
+func (b bitcaskIntervals) LoadIntervals() ([]store.ParamsInterval, error) {
+	keys, err := scanKeys(b.db, paramsIntervalKeyPrefix)
 	if err != nil {
-		return fmt.Errorf("ParamsRepo.SetAll [%d]: %s", height, err)
+		return nil, err
 	}
 
-	return nil
+	list := make([]store.ParamsInterval, 0, len(keys))
+	for _, k := range keys {
+		valueB, _, err := get(b.db, k)
+		if errors.Is(err, store.ErrCorrupt) {
+			if err = b.db.Delete(k); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var value intervalValue
+		if err = json.Unmarshal(valueB, &value); err != nil {
+			return nil, fmt.Errorf("failed to parse json for %x: %s", k, err)
+		}
+		list = append(list, store.ParamsInterval{
+			From:   uint(binary.BigEndian.Uint64(k[len(paramsIntervalKeyPrefix):])),
+			To:     value.To,
+			Params: value.Params,
+		})
+	}
+
+	return list, nil
+}
+
+func (b bitcaskIntervals) PutInterval(interval store.ParamsInterval) error {
+	valueB, _ := json.Marshal(intervalValue{To: interval.To, Params: interval.Params})
+	return put(b.db, intervalKey(interval.From), valueB)
+}
+
+func (b bitcaskIntervals) DeleteInterval(from uint) error {
+	return b.db.Delete(intervalKey(from))
+}
+
+func intervalKey(from uint) []byte {
+	return heightKey(paramsIntervalKeyPrefix, uint64(from))
 }
 
-func (r ParamsRepo) key(name string, height int64) []byte {
-	key := fmt.Sprintf("%d%s", height, name)
-	keyB, _ := json.Marshal(key)
-	return keyB
+// paramKey is the height followed by the name, so that no two pairs share a key.
+func paramKey(name string, height int64) []byte {
+	return append(heightKey(paramKeyPrefix, uint64(height)), name...)
 }
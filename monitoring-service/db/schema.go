@@ -0,0 +1,155 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+// SchemaVersion is the version of the key layout written by this package. Version 1 namespaces
+// every key by the repository it belongs to and seals every value with a checksum. Earlier
+// versions, which recorded no version, are migrated by Store.Migrate.
+const SchemaVersion = 1
+
+var schemaVersionKey = []byte("meta:schema")
+
+// Version returns the schema version recorded in the DB, or 0 for the legacy layout.
+func (s *Store) Version() (int, error) {
+	versionB, exists, err := get(s.db, schemaVersionKey)
+	if err != nil {
+		return 0, fmt.Errorf("Store.Version: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	if len(versionB) != 8 {
+		return 0, fmt.Errorf("Store.Version: invalid value")
+	}
+
+	return int(binary.BigEndian.Uint64(versionB)), nil
+}
+
+// Migrate rewrites a DB of the legacy layout to the current schema and records its version,
+// returning how many entries were migrated. It does nothing to a DB that is up to date, and fails
+// for one written by a newer version. An interrupted migration is resumed by the next call.
+func (s *Store) Migrate() (int, error) {
+	version, err := s.Version()
+	if err != nil {
+		return 0, fmt.Errorf("Store.Migrate: %w", err)
+	}
+	if version > SchemaVersion {
+		return 0, fmt.Errorf("Store.Migrate: schema version %d is newer than the supported %d", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return 0, nil
+	}
+
+	migrated, err := migrateLegacyKeys(s.db)
+	if err != nil {
+		return 0, fmt.Errorf("Store.Migrate: %s", err)
+	}
+
+	snapshots, err := s.params.MigrateSnapshots()
+	if err != nil {
+		return 0, fmt.Errorf("Store.Migrate: %s", err)
+	}
+
+	versionB := make([]byte, 8)
+	binary.BigEndian.PutUint64(versionB, SchemaVersion)
+	if err = put(s.db, schemaVersionKey, versionB); err != nil {
+		return 0, fmt.Errorf("Store.Migrate: %s", err)
+	}
+
+	return migrated + snapshots, nil
+}
+
+// migrateLegacyKeys moves the block times and single params of the legacy layout to their
+// namespaced keys, sealing their values. Params snapshots are left for ParamsRepo.MigrateSnapshots.
+func migrateLegacyKeys(db *bitcask.Bitcask) (int, error) {
+	var keys [][]byte
+	err := db.Fold(func(key []byte) error {
+		k := make([]byte, len(key))
+		copy(k, key)
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, k := range keys {
+		var newKey []byte
+		switch {
+		case isDigits(k):
+			height, err := strconv.ParseUint(string(k), 10, 64)
+			if err != nil {
+				continue
+			}
+			newKey = heightKey(blockTimeKeyPrefix, height)
+		case bytes.HasPrefix(k, []byte(`"`)):
+			name, height, ok := parseLegacyParamKey(k)
+			if !ok {
+				continue
+			}
+			newKey = paramKey(name, height)
+		default:
+			continue
+		}
+
+		value, err := db.Get(k)
+		if err != nil {
+			return migrated, fmt.Errorf("[%q]: %s", k, err)
+		}
+
+		if err = put(db, newKey, value); err != nil {
+			return migrated, fmt.Errorf("[%q]: %s", k, err)
+		}
+		if err = db.Delete(k); err != nil {
+			return migrated, fmt.Errorf("[%q]: %s", k, err)
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
+
+// parseLegacyParamKey splits a legacy params key, the JSON string of the height followed by the
+// name, taking every leading digit as the height. ok is false for params snapshots.
+func parseLegacyParamKey(k []byte) (name string, height int64, ok bool) {
+	var key string
+	if err := json.Unmarshal(k, &key); err != nil || strings.HasSuffix(key, legacyAllParamsName) {
+		return "", 0, false
+	}
+
+	i := 0
+	if strings.HasPrefix(key, "-") {
+		i++
+	}
+	for i < len(key) && key[i] >= '0' && key[i] <= '9' {
+		i++
+	}
+	height, err := strconv.ParseInt(key[:i], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return key[i:], height, true
+}
+
+func isDigits(k []byte) bool {
+	if len(k) == 0 {
+		return false
+	}
+	for _, c := range k {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
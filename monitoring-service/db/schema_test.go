@@ -0,0 +1,135 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+func testAllParams(value string) pocket.AllParams {
+	group := func(key string) pocket.ParamGroup {
+		return pocket.ParamGroup{{Key: key, Value: value}}
+	}
+
+	return pocket.AllParams{
+		AppParams:    group("application/MaxApplications"),
+		AuthParams:   group("auth/MaxMemoCharacters"),
+		GovParams:    group("gov/acl"),
+		NodeParams:   group("pos/RelaysToTokensMultiplier"),
+		PocketParams: group("pocketcore/ClaimExpiration"),
+	}
+}
+
+// writeBaseline writes entries the way the repos did before the schema was versioned: block times
+// under the JSON of their height, and params under the JSON string of the height and the name.
+func writeBaseline(t *testing.T, path string, entries map[string]interface{}) {
+	t.Helper()
+
+	db, err := bitcask.Open(path)
+	if err != nil {
+		t.Fatalf("bitcask.Open: %v", err)
+	}
+	defer db.Close()
+
+	for key, value := range entries {
+		valueB, _ := json.Marshal(value)
+		if err = db.Put([]byte(key), valueB); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+}
+
+func legacyParamKey(name string, height int64) string {
+	keyB, _ := json.Marshal(fmt.Sprintf("%d%s", height, name))
+	return string(keyB)
+}
+
+func TestMigrateFromBaseline(t *testing.T) {
+	path := t.TempDir()
+	at := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	relayParams := pocket.Params{RelaysToTokensMultiplier: 8461, ClaimExpirationBlocks: 120}
+	a, b := testAllParams("a"), testAllParams("b")
+
+	writeBaseline(t, path, map[string]interface{}{
+		"5":                                     at,
+		"6":                                     at.Add(time.Minute),
+		legacyParamKey("relayParams", 5):        relayParams,
+		legacyParamKey(legacyAllParamsName, 10): a,
+		legacyParamKey(legacyAllParamsName, 11): a,
+		legacyParamKey(legacyAllParamsName, 12): b,
+		legacyParamKey(legacyAllParamsName, 0):  b, // the latest params, which aren't migrated
+		legacyParamKey(legacyAllParamsName, 13): "not params",
+	})
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	if version, err := s.Version(); err != nil || version != 0 {
+		t.Fatalf("Version before Migrate: %d, %v, want 0, nil", version, err)
+	}
+	migrated, err := s.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	// the snapshots that can't be placed in an interval are dropped
+	if migrated != 8 {
+		t.Errorf("Migrate: %d entries, want 3 entries and 5 snapshots", migrated)
+	}
+	if version, err := s.Version(); err != nil || version != SchemaVersion {
+		t.Errorf("Version after Migrate: %d, %v, want %d, nil", version, err, SchemaVersion)
+	}
+
+	for h, want := range map[uint]time.Time{5: at, 6: at.Add(time.Minute)} {
+		if got, exists, err := s.BlockTimes().Get(h); err != nil || !exists || !got.Equal(want) {
+			t.Errorf("BlockTimes.Get(%d): %s, %t, %v, want %s", h, got, exists, err, want)
+		}
+	}
+	if got, exists, err := s.Params().Get("relayParams", 5); err != nil || !exists || got != relayParams {
+		t.Errorf("Params.Get: %+v, %t, %v, want %+v", got, exists, err, relayParams)
+	}
+
+	intervals, err := s.Params().Intervals()
+	want := []store.ParamsInterval{{From: 10, To: 12, Params: a}, {From: 12, To: 13, Params: b}}
+	if err != nil || !reflect.DeepEqual(intervals, want) {
+		t.Errorf("Params.Intervals: %+v, %v, want %+v", intervals, err, want)
+	}
+
+	// every legacy key was moved or dropped
+	for _, k := range []string{"5", "6", legacyParamKey("relayParams", 5), legacyParamKey(legacyAllParamsName, 10),
+		legacyParamKey(legacyAllParamsName, 0), legacyParamKey(legacyAllParamsName, 13)} {
+		if s.db.Has([]byte(k)) {
+			t.Errorf("legacy key %s is still stored", k)
+		}
+	}
+
+	if migrated, err = s.Migrate(); err != nil || migrated != 0 {
+		t.Errorf("Migrate of a migrated DB: %d, %v, want 0, nil", migrated, err)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	versionB := make([]byte, 8)
+	versionB[7] = SchemaVersion + 1
+	if err = put(s.db, schemaVersionKey, versionB); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, err = s.Migrate(); err == nil {
+		t.Error("Migrate of a newer schema: nil error")
+	}
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+var stakeKeyPrefix = []byte("stake:")
+
+// StakesRepo caches the stake of a node per height, keyed by the raw bytes of the address
+// followed by the height.
+type StakesRepo struct {
+	db *bitcask.Bitcask
+}
+
+func NewStakesRepo(db *bitcask.Bitcask) StakesRepo {
+	return StakesRepo{db: db}
+}
+
+func (r StakesRepo) Get(address string, height uint) (stake uint, exists bool, err error) {
+	keyB, err := r.key(address, height)
+	if err != nil {
+		return 0, false, fmt.Errorf("StakesRepo.Get: %s", err)
+	}
+
+	stakeB, exists, err := get(r.db, keyB)
+	if err != nil {
+		return 0, false, fmt.Errorf("StakesRepo.Get [%s, %d]: %w", address, height, err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+	if len(stakeB) != 8 {
+		return 0, false, fmt.Errorf("StakesRepo.Get [%s, %d]: invalid stake value", address, height)
+	}
+
+	return uint(binary.BigEndian.Uint64(stakeB)), true, nil
+}
+
+func (r StakesRepo) Set(address string, height uint, stake uint) error {
+	keyB, err := r.key(address, height)
+	if err != nil {
+		return fmt.Errorf("StakesRepo.Set: %s", err)
+	}
+
+	stakeB := make([]byte, 8)
+	binary.BigEndian.PutUint64(stakeB, uint64(stake))
+	if err = put(r.db, keyB, stakeB); err != nil {
+		return fmt.Errorf("StakesRepo.Set [%s, %d]: %s", address, height, err)
+	}
+
+	return nil
+}
+
+func (r StakesRepo) key(address string, height uint) ([]byte, error) {
+	addrB, err := hex.DecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %s", address, err)
+	}
+
+	return heightKey(append(append([]byte{}, stakeKeyPrefix...), addrB...), uint64(height)), nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+
+	"monitoring-service/store"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+// Store is a bitcask DB and the repositories kept in it.
+type Store struct {
+	db           *bitcask.Bitcask
+	blockTimes   BlockTimesRepo
+	params       ParamsRepo
+	transactions TransactionsRepo
+	stakes       StakesRepo
+	fleets       FleetsRepo
+}
+
+// Open opens, or creates, the bitcask DB in the directory at path.
+func Open(path string) (*Store, error) {
+	bitcaskDB, err := bitcask.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Open [%s]: %s", path, err)
+	}
+
+	return &Store{
+		db:           bitcaskDB,
+		blockTimes:   NewBlockTimesRepo(bitcaskDB),
+		params:       NewParamsRepo(bitcaskDB),
+		transactions: NewTransactionsRepo(bitcaskDB),
+		stakes:       NewStakesRepo(bitcaskDB),
+		fleets:       NewFleetsRepo(bitcaskDB),
+	}, nil
+}
+
+func (s *Store) BlockTimes() store.BlockTimesRepo     { return s.blockTimes }
+func (s *Store) Params() store.ParamsRepo             { return s.params }
+func (s *Store) Transactions() store.TransactionsRepo { return s.transactions }
+func (s *Store) Stakes() store.StakesRepo             { return s.stakes }
+func (s *Store) Fleets() store.FleetsRepo             { return s.fleets }
+
+// Merge rewrites the DB files without deleted and overwritten values, to reclaim their space.
+func (s *Store) Merge() error {
+	if err := s.db.Merge(); err != nil {
+		return fmt.Errorf("Store.Merge: %s", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("Store.Close: %s", err)
+	}
+
+	return nil
+}
+
+// Compact reclaims the space of deleted and overwritten values, see Merge.
+func (s *Store) Compact() error {
+	return s.Merge()
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"testing"
+
+	"monitoring-service/store"
+	"monitoring-service/store/storetest"
+)
+
+func TestCorruption(t *testing.T) {
+	path := t.TempDir()
+	reopen := func() store.Store {
+		s, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		return s
+	}
+
+	// values put without their checksum fail it
+	storetest.CheckCorruption(t, reopen, storetest.Corruption{
+		BlockTime: func(s store.Store, height uint) error {
+			return s.(*Store).db.Put(blockTimeKey(height), []byte("not a block time"))
+		},
+		ParamsInterval: func(s store.Store, from uint) error {
+			return s.(*Store).db.Put(intervalKey(from), []byte("not an interval"))
+		},
+	})
+}
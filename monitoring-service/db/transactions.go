@@ -0,0 +1,430 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"monitoring-service/pocket"
+
+	"git.mills.io/prologic/bitcask"
+)
+
+var (
+	txKeyPrefix         = []byte("tx:")
+	checkpointKeyPrefix = []byte("txcp:")
+	replaceKeyPrefix    = []byte("txrp:")
+	replaceHashesPrefix = []byte("txrh:")
+)
+
+// replaceChunkSize is how many hashes are kept in one value of a pending replace, which keeps it
+// well within bitcask's 64KB value limit.
+const replaceChunkSize = 512
+
+// pendingReplace records, since bitcask has no transactions, that the transactions of an address
+// above Height are being replaced. The new transactions are stored under their own keys first, and
+// the hashes to keep are written in Chunks values of up to replaceChunkSize hashes before this
+// record, so that only the deletes are left once it exists. One left behind by an interrupted
+// replace is applied by the next write for its address, and reads leave out what it deletes.
+type pendingReplace struct {
+	Height uint `json:"height"`
+	Chunks int  `json:"chunks"`
+}
+
+// TransactionsRepo stores enriched transactions per account. Keys use the raw bytes of the
+// address and tx hash so that they fit within bitcask's 64 byte key limit.
+type TransactionsRepo struct {
+	db *bitcask.Bitcask
+}
+
+func NewTransactionsRepo(db *bitcask.Bitcask) TransactionsRepo {
+	return TransactionsRepo{db: db}
+}
+
+// AccountTransactions returns every stored transaction for address, newest first. It only reads,
+// leaving out the transactions that an interrupted replace has yet to delete.
+func (r TransactionsRepo) AccountTransactions(address string) ([]pocket.Transaction, error) {
+	txs, err := r.accountTransactions(address)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, keep, exists, err := r.loadReplace(address)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions: %s", err)
+	}
+	if !exists {
+		return txs, nil
+	}
+
+	kept := txs[:0]
+	for _, tx := range txs {
+		if tx.Height <= pending.Height || keep[strings.ToLower(tx.Hash)] {
+			kept = append(kept, tx)
+		}
+	}
+
+	return kept, nil
+}
+
+func (r TransactionsRepo) accountTransactions(address string) ([]pocket.Transaction, error) {
+	prefix, err := r.accountPrefix(address)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions: %s", err)
+	}
+
+	keys, err := scanKeys(r.db, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions [%s]: %s", address, err)
+	}
+
+	txs := make([]pocket.Transaction, 0, len(keys))
+	for _, k := range keys {
+		txB, _, err := get(r.db, k)
+		if err != nil {
+			return nil, fmt.Errorf("TransactionsRepo.AccountTransactions [%s]: %w", address, err)
+		}
+
+		var tx pocket.Transaction
+		if err = json.Unmarshal(txB, &tx); err != nil {
+			return nil, fmt.Errorf("TransactionsRepo.AccountTransactions: failed to parse json for %s: %s", address, err)
+		}
+		txs = append(txs, tx)
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Height > txs[j].Height
+	})
+
+	return txs, nil
+}
+
+// SetTransactions stores txs for address, replacing any stored transaction with the same hash.
+func (r TransactionsRepo) SetTransactions(address string, txs []pocket.Transaction) error {
+	if err := r.finishReplace(address); err != nil {
+		return fmt.Errorf("TransactionsRepo.SetTransactions: %s", err)
+	}
+
+	return r.setTransactions(address, txs)
+}
+
+func (r TransactionsRepo) setTransactions(address string, txs []pocket.Transaction) error {
+	for _, tx := range txs {
+		keyB, err := r.txKey(address, tx.Hash)
+		if err != nil {
+			return fmt.Errorf("TransactionsRepo.SetTransactions: %s", err)
+		}
+
+		txB, _ := json.Marshal(tx)
+		if err = put(r.db, keyB, txB); err != nil {
+			return fmt.Errorf("TransactionsRepo.SetTransactions [%s, %s]: %s", address, tx.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteTransactionsAbove removes the stored transactions for address with a height greater than height.
+func (r TransactionsRepo) DeleteTransactionsAbove(address string, height uint) error {
+	if err := r.finishReplace(address); err != nil {
+		return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove: %s", err)
+	}
+
+	return r.deleteTransactionsAbove(address, height)
+}
+
+func (r TransactionsRepo) deleteTransactionsAbove(address string, height uint) error {
+	txs, err := r.accountTransactions(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove: %s", err)
+	}
+
+	for _, tx := range txs {
+		if tx.Height <= height {
+			continue
+		}
+
+		keyB, err := r.txKey(address, tx.Hash)
+		if err != nil {
+			return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove: %s", err)
+		}
+		if err = r.db.Delete(keyB); err != nil {
+			return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove [%s, %s]: %s", address, tx.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceTransactionsAbove removes the stored transactions for address with a height greater than
+// height and stores txs. The deletes are recorded before they are made, so an interrupted replace
+// is completed rather than left half done, see pendingReplace.
+func (r TransactionsRepo) ReplaceTransactionsAbove(address string, height uint, txs []pocket.Transaction) error {
+	if err := r.finishReplace(address); err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %s", err)
+	}
+
+	keep := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		if _, err := r.txKey(address, tx.Hash); err != nil {
+			return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %s", err)
+		}
+		keep[strings.ToLower(tx.Hash)] = true
+	}
+
+	if err := r.setTransactions(address, txs); err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %s", err)
+	}
+
+	pending, err := r.recordReplace(address, height, keep)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove [%s, %d]: %s", address, height, err)
+	}
+
+	if err = r.applyReplace(address, pending, keep); err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %s", err)
+	}
+
+	return nil
+}
+
+// recordReplace writes the hashes to keep, then the pendingReplace that refers to them.
+func (r TransactionsRepo) recordReplace(address string, height uint, keep map[string]bool) (pendingReplace, error) {
+	hashes := make([]string, 0, len(keep))
+	for hash := range keep {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	pending := pendingReplace{Height: height}
+	for from := 0; from < len(hashes); from += replaceChunkSize {
+		to := from + replaceChunkSize
+		if to > len(hashes) {
+			to = len(hashes)
+		}
+
+		keyB, err := r.replaceHashesKey(address, pending.Chunks)
+		if err != nil {
+			return pendingReplace{}, err
+		}
+		chunkB, _ := json.Marshal(hashes[from:to])
+		if err = put(r.db, keyB, chunkB); err != nil {
+			return pendingReplace{}, err
+		}
+		pending.Chunks++
+	}
+
+	keyB, err := r.replaceKey(address)
+	if err != nil {
+		return pendingReplace{}, err
+	}
+	pendingB, _ := json.Marshal(pending)
+	if err = put(r.db, keyB, pendingB); err != nil {
+		return pendingReplace{}, err
+	}
+
+	return pending, nil
+}
+
+// loadReplace returns the pending replace of address and the lower case hashes it keeps.
+func (r TransactionsRepo) loadReplace(address string) (pending pendingReplace, keep map[string]bool, exists bool, err error) {
+	keyB, err := r.replaceKey(address)
+	if err != nil {
+		return pendingReplace{}, nil, false, err
+	}
+
+	pendingB, exists, err := get(r.db, keyB)
+	if err != nil {
+		return pendingReplace{}, nil, false, fmt.Errorf("pending replace of %s: %w", address, err)
+	}
+	if !exists {
+		return pendingReplace{}, nil, false, nil
+	}
+	if err = json.Unmarshal(pendingB, &pending); err != nil {
+		return pendingReplace{}, nil, false, fmt.Errorf("failed to parse json for the pending replace of %s: %s", address, err)
+	}
+
+	keep = make(map[string]bool)
+	for i := 0; i < pending.Chunks; i++ {
+		chunkKeyB, err := r.replaceHashesKey(address, i)
+		if err != nil {
+			return pendingReplace{}, nil, false, err
+		}
+		chunkB, chunkExists, err := get(r.db, chunkKeyB)
+		if err != nil || !chunkExists {
+			return pendingReplace{}, nil, false, fmt.Errorf("pending replace of %s: chunk %d: exists %t, %v", address, i, chunkExists, err)
+		}
+
+		var hashes []string
+		if err = json.Unmarshal(chunkB, &hashes); err != nil {
+			return pendingReplace{}, nil, false, fmt.Errorf("failed to parse json for the pending replace of %s: %s", address, err)
+		}
+		for _, hash := range hashes {
+			keep[hash] = true
+		}
+	}
+
+	return pending, keep, true, nil
+}
+
+// finishReplace applies the pending replace of address, if an earlier one was interrupted.
+func (r TransactionsRepo) finishReplace(address string) error {
+	pending, keep, exists, err := r.loadReplace(address)
+	if err != nil || !exists {
+		return err
+	}
+
+	return r.applyReplace(address, pending, keep)
+}
+
+// applyReplace deletes the transactions above the height of a pending replace that it doesn't
+// keep, then its record. Applying it again is harmless.
+func (r TransactionsRepo) applyReplace(address string, pending pendingReplace, keep map[string]bool) error {
+	txs, err := r.accountTransactions(address)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		if tx.Height <= pending.Height || keep[strings.ToLower(tx.Hash)] {
+			continue
+		}
+
+		keyB, err := r.txKey(address, tx.Hash)
+		if err != nil {
+			return err
+		}
+		if err = r.db.Delete(keyB); err != nil {
+			return fmt.Errorf("[%s, %s]: %s", address, tx.Hash, err)
+		}
+	}
+
+	// the record goes first, as the hashes it refers to must outlive it
+	keyB, err := r.replaceKey(address)
+	if err != nil {
+		return err
+	}
+	if err = r.db.Delete(keyB); err != nil {
+		return fmt.Errorf("pending replace of %s: %s", address, err)
+	}
+	for i := 0; i < pending.Chunks; i++ {
+		chunkKeyB, err := r.replaceHashesKey(address, i)
+		if err != nil {
+			return err
+		}
+		if err = r.db.Delete(chunkKeyB); err != nil {
+			return fmt.Errorf("pending replace of %s: %s", address, err)
+		}
+	}
+
+	return nil
+}
+
+// Checkpoint returns the height up to which transactions for address have been indexed.
+func (r TransactionsRepo) Checkpoint(address string) (height uint, exists bool, err error) {
+	keyB, err := r.checkpointKey(address)
+	if err != nil {
+		return 0, false, fmt.Errorf("TransactionsRepo.Checkpoint: %s", err)
+	}
+
+	heightB, exists, err := get(r.db, keyB)
+	if err != nil {
+		return 0, false, fmt.Errorf("TransactionsRepo.Checkpoint [%s]: %w", address, err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+	if len(heightB) != 8 {
+		return 0, false, fmt.Errorf("TransactionsRepo.Checkpoint [%s]: invalid checkpoint value", address)
+	}
+
+	return uint(binary.BigEndian.Uint64(heightB)), true, nil
+}
+
+func (r TransactionsRepo) SetCheckpoint(address string, height uint) error {
+	keyB, err := r.checkpointKey(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.SetCheckpoint: %s", err)
+	}
+
+	heightB := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightB, uint64(height))
+	if err = put(r.db, keyB, heightB); err != nil {
+		return fmt.Errorf("TransactionsRepo.SetCheckpoint [%s, %d]: %s", address, height, err)
+	}
+
+	return nil
+}
+
+func (r TransactionsRepo) accountPrefix(address string) ([]byte, error) {
+	addrB, err := hex.DecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %s", address, err)
+	}
+
+	return append(append([]byte{}, txKeyPrefix...), addrB...), nil
+}
+
+func (r TransactionsRepo) txKey(address, hash string) ([]byte, error) {
+	prefix, err := r.accountPrefix(address)
+	if err != nil {
+		return nil, err
+	}
+
+	hashB, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx hash %s: %s", hash, err)
+	}
+
+	return append(prefix, hashB...), nil
+}
+
+func (r TransactionsRepo) replaceKey(address string) ([]byte, error) {
+	addrB, err := hex.DecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %s", address, err)
+	}
+
+	return append(append([]byte{}, replaceKeyPrefix...), addrB...), nil
+}
+
+func (r TransactionsRepo) replaceHashesKey(address string, chunk int) ([]byte, error) {
+	addrB, err := hex.DecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %s", address, err)
+	}
+
+	keyB := make([]byte, len(replaceHashesPrefix)+len(addrB)+4)
+	copy(keyB, replaceHashesPrefix)
+	copy(keyB[len(replaceHashesPrefix):], addrB)
+	binary.BigEndian.PutUint32(keyB[len(replaceHashesPrefix)+len(addrB):], uint32(chunk))
+	return keyB, nil
+}
+
+func (r TransactionsRepo) checkpointKey(address string) ([]byte, error) {
+	addrB, err := hex.DecodeString(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %s", address, err)
+	}
+
+	return append(append([]byte{}, checkpointKeyPrefix...), addrB...), nil
+}
+
+// Addresses returns the addresses with a checkpoint, in lower case and sorted.
+func (r TransactionsRepo) Addresses() ([]string, error) {
+	keys, err := scanKeys(r.db, checkpointKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.Addresses: %s", err)
+	}
+
+	addresses := make([]string, len(keys))
+	for i, k := range keys {
+		addresses[i] = hex.EncodeToString(k[len(checkpointKeyPrefix):])
+	}
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
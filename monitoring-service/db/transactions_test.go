@@ -0,0 +1,65 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"monitoring-service/pocket"
+)
+
+func txHeights(t *testing.T, r TransactionsRepo, address string) []uint {
+	t.Helper()
+
+	txs, err := r.AccountTransactions(address)
+	if err != nil {
+		t.Fatalf("AccountTransactions: %v", err)
+	}
+	heights := make([]uint, len(txs))
+	for i, tx := range txs {
+		heights[i] = tx.Height
+	}
+	return heights
+}
+
+func TestInterruptedReplaceIsFinished(t *testing.T) {
+	const address = "ab12cd34"
+
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	r := s.transactions
+
+	// a replace above 5 that stored its new tx at 6 and was recorded, but stopped before deleting
+	// the reorged tx at 7
+	txs := []pocket.Transaction{{Hash: "aa01", Height: 5}, {Hash: "aa02", Height: 7}, {Hash: "AA03", Height: 6}}
+	if err = r.SetTransactions(address, txs); err != nil {
+		t.Fatalf("SetTransactions: %v", err)
+	}
+	if _, err = r.recordReplace(address, 5, map[string]bool{"aa03": true}); err != nil {
+		t.Fatalf("recordReplace: %v", err)
+	}
+	keyB, _ := r.replaceKey(address)
+
+	// reads leave the reorged tx out, without writing
+	if heights := txHeights(t, r, address); !reflect.DeepEqual(heights, []uint{6, 5}) {
+		t.Errorf("AccountTransactions during an interrupted replace: %v, want [6 5]", heights)
+	}
+	if !s.db.Has(keyB) {
+		t.Fatal("AccountTransactions finished the pending replace")
+	}
+
+	// the next write finishes it
+	if err = r.SetTransactions(address, []pocket.Transaction{{Hash: "aa04", Height: 8}}); err != nil {
+		t.Fatalf("SetTransactions: %v", err)
+	}
+	if heights := txHeights(t, r, address); !reflect.DeepEqual(heights, []uint{8, 6, 5}) {
+		t.Errorf("AccountTransactions after the replace was finished: %v, want [8 6 5]", heights)
+	}
+	reorgedKeyB, _ := r.txKey(address, "aa02")
+	chunkKeyB, _ := r.replaceHashesKey(address, 0)
+	if s.db.Has(keyB) || s.db.Has(chunkKeyB) || s.db.Has(reorgedKeyB) {
+		t.Error("the pending replace, or the tx it deletes, was kept after it was finished")
+	}
+}
@@ -0,0 +1,270 @@
+// Package exporter publishes the balances, status and rewards of a set of nodes as Prometheus
+// metrics, so that earnings can be charted next to the nodes' own metrics.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/timer"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultInterval = 5 * time.Minute
+
+	namespace    = "pokt_node"
+	upoktPerPokt = 1000000
+)
+
+// NodeService supplies the per-node data that the metrics are built from.
+type NodeService interface {
+	Node(ctx context.Context, address string) (pocket.Node, error)
+	AccountClaimsAndProofs(ctx context.Context, address string) (claims, proofs map[string]pocket.Transaction, err error)
+	ClaimsStatusOf(ctx context.Context, claims, proofs map[string]pocket.Transaction) (pocket.ClaimsStatus, error)
+}
+
+var (
+	addressLabels = []string{"address"}
+
+	upDesc = prometheus.NewDesc(namespace+"_up",
+		"Whether the last collection for the node succeeded.", addressLabels, nil)
+	collectedDesc = prometheus.NewDesc(namespace+"_last_collected_timestamp_seconds",
+		"Unix time of the last successful collection for the node.", addressLabels, nil)
+	balanceDesc = prometheus.NewDesc(namespace+"_balance_pokt",
+		"Liquid balance of the node.", addressLabels, nil)
+	stakedBalanceDesc = prometheus.NewDesc(namespace+"_staked_balance_pokt",
+		"Staked balance of the node.", addressLabels, nil)
+	jailedDesc = prometheus.NewDesc(namespace+"_jailed",
+		"Whether the node is jailed.", addressLabels, nil)
+	heightLagDesc = prometheus.NewDesc(namespace+"_height_lag_blocks",
+		"Blocks the node's latest height is behind the network.", addressLabels, nil)
+	relaysClaimedDesc = prometheus.NewDesc(namespace+"_relays_claimed_total",
+		"Relays claimed by the node, by chain.", []string{"address", "chain"}, nil)
+	// a gauge, as claims the history no longer returns, such as those of a reorg, drop out of it
+	earnedDesc = prometheus.NewDesc(namespace+"_earned_pokt",
+		"POKT earned by the node's claims with a successful proof.", addressLabels, nil)
+	pendingClaimsDesc = prometheus.NewDesc(namespace+"_pending_claims",
+		"Claims of the node waiting for a proof.", addressLabels, nil)
+	pendingPoktDesc = prometheus.NewDesc(namespace+"_pending_pokt",
+		"POKT of the node's claims waiting for a proof.", addressLabels, nil)
+	expiredClaimsDesc = prometheus.NewDesc(namespace+"_expired_claims_total",
+		"Claims of the node that expired without a proof.", addressLabels, nil)
+	lostPoktDesc = prometheus.NewDesc(namespace+"_lost_pokt_total",
+		"POKT of the node's claims that expired or whose proof failed.", addressLabels, nil)
+)
+
+// nodeMetrics is what was collected for a node. The fields other than up hold the values of the
+// last successful collection.
+type nodeMetrics struct {
+	up          bool
+	collectedAt time.Time
+
+	balance       float64
+	stakedBalance float64
+	jailed        bool
+	// heightLag is only known when the node answered for its height
+	heightLag    float64
+	hasHeightLag bool
+
+	relaysClaimed map[string]float64
+	earned        float64
+	pendingClaims int
+	pendingPokt   float64
+	expiredClaims int
+	lostPokt      float64
+}
+
+// Exporter collects the metrics of a set of nodes once per interval, and serves the last values
+// collected as a prometheus.Collector.
+type Exporter struct {
+	nodes     NodeService
+	addresses []string
+	interval  time.Duration
+	logger    log.Logger
+
+	mu      sync.RWMutex
+	metrics map[string]nodeMetrics
+}
+
+func New(nodes NodeService, addresses []string, interval time.Duration, logger log.Logger) *Exporter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	tracked := make([]string, 0, len(addresses))
+	seen := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		tracked = append(tracked, a)
+	}
+	sort.Strings(tracked)
+
+	return &Exporter{
+		nodes:     nodes,
+		addresses: tracked,
+		interval:  interval,
+		logger:    logger,
+		metrics:   make(map[string]nodeMetrics, len(tracked)),
+	}
+}
+
+// Run collects the metrics of every node straight away and then once per interval, until ctx is done.
+func (e *Exporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.collectAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (e *Exporter) collectAll(ctx context.Context) {
+	t := timer.Start()
+
+	for _, address := range e.addresses {
+		if ctx.Err() != nil {
+			return
+		}
+
+		m, err := e.collect(ctx, address)
+
+		e.mu.Lock()
+		if err != nil {
+			_ = e.logger.Log("level", "ERROR", "msg", err.Error())
+			m = e.metrics[address]
+			m.up = false
+		}
+		e.metrics[address] = m
+		e.mu.Unlock()
+	}
+
+	_ = e.logger.Log("level", "INFO", "msg", fmt.Sprintf("Collected metrics for %d nodes (took %s)", len(e.addresses), t.Elapsed().String()))
+}
+
+// collect gathers the metrics of the node at address.
+func (e *Exporter) collect(ctx context.Context, address string) (nodeMetrics, error) {
+	fail := func(err error) (nodeMetrics, error) {
+		return nodeMetrics{}, fmt.Errorf("Exporter.collect(%s): %w", address, err)
+	}
+
+	node, err := e.nodes.Node(ctx, address)
+	if err != nil {
+		return fail(err)
+	}
+
+	// the history is paged once, for the rewards and the claims status alike
+	claims, proofs, err := e.nodes.AccountClaimsAndProofs(ctx, address)
+	if err != nil {
+		return fail(err)
+	}
+
+	status, err := e.nodes.ClaimsStatusOf(ctx, claims, proofs)
+	if err != nil {
+		return fail(err)
+	}
+
+	m := nodeMetrics{
+		up:            true,
+		collectedAt:   time.Now(),
+		balance:       float64(node.Balance) / upoktPerPokt,
+		stakedBalance: float64(node.StakedBalance) / upoktPerPokt,
+		jailed:        node.IsJailed,
+		relaysClaimed: make(map[string]float64),
+		pendingClaims: len(status.Pending),
+		pendingPokt:   status.PendingPoktAmount(),
+		expiredClaims: len(status.Expired),
+		lostPokt:      status.LostPoktAmount(),
+	}
+
+	// a node that didn't answer has no latest height, and without the network's there's no lag
+	if node.IsReachable && node.NetworkHeight > 0 {
+		m.hasHeightLag = true
+		m.heightLag = float64(node.LagBlocks)
+	}
+
+	for sessionKey, claim := range claims {
+		m.relaysClaimed[claim.ChainID] += float64(claim.NumRelays)
+		if proof, exists := proofs[sessionKey]; exists && proof.ResultCode == 0 {
+			m.earned += claim.PoktAmount()
+		}
+	}
+
+	return m, nil
+}
+
+// Describe implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc, collectedDesc, balanceDesc, stakedBalanceDesc, jailedDesc, heightLagDesc, relaysClaimedDesc,
+		earnedDesc, pendingClaimsDesc, pendingPoktDesc, expiredClaimsDesc, lostPoktDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector, with the values of the last collection. Nodes that
+// were never collected successfully only report that they are down.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, address := range e.addresses {
+		m, collected := e.metrics[address]
+		if !collected {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, boolValue(m.up), address)
+		if m.collectedAt.IsZero() {
+			continue
+		}
+
+		gauge := func(desc *prometheus.Desc, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, address)
+		}
+		counter := func(desc *prometheus.Desc, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, address)
+		}
+
+		gauge(collectedDesc, float64(m.collectedAt.Unix()))
+		gauge(balanceDesc, m.balance)
+		gauge(stakedBalanceDesc, m.stakedBalance)
+		gauge(jailedDesc, boolValue(m.jailed))
+		if m.hasHeightLag {
+			gauge(heightLagDesc, m.heightLag)
+		}
+		for chain, relays := range m.relaysClaimed {
+			ch <- prometheus.MustNewConstMetric(relaysClaimedDesc, prometheus.CounterValue, relays, address, chain)
+		}
+		gauge(earnedDesc, m.earned)
+		gauge(pendingClaimsDesc, float64(m.pendingClaims))
+		gauge(pendingPoktDesc, m.pendingPokt)
+		counter(expiredClaimsDesc, float64(m.expiredClaims))
+		counter(lostPoktDesc, m.lostPokt)
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
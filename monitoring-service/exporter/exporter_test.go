@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+
+	"monitoring-service/pocket"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const address = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// fakeNodes serves a node with two claims, one of them proven, and counts how often the claims are paged.
+type fakeNodes struct {
+	claimsCalls int
+}
+
+func (f *fakeNodes) Node(ctx context.Context, address string) (pocket.Node, error) {
+	return pocket.Node{Address: address, IsReachable: true, LatestBlockHeight: 97, NetworkHeight: 100, LagBlocks: 3}, nil
+}
+
+func (f *fakeNodes) AccountClaimsAndProofs(ctx context.Context, address string) (claims, proofs map[string]pocket.Transaction, err error) {
+	f.claimsCalls++
+	claims = map[string]pocket.Transaction{
+		"a": {ChainID: "0021", NumRelays: 100, PoktPerRelay: 0.01},
+		"b": {ChainID: "0021", NumRelays: 50, PoktPerRelay: 0.01},
+	}
+	proofs = map[string]pocket.Transaction{"a": {ResultCode: 0}}
+	return claims, proofs, nil
+}
+
+func (f *fakeNodes) ClaimsStatusOf(ctx context.Context, claims, proofs map[string]pocket.Transaction) (pocket.ClaimsStatus, error) {
+	return pocket.ClaimsStatus{Pending: []pocket.UnprovenClaim{{Claim: claims["b"]}}}, nil
+}
+
+func TestCollect(t *testing.T) {
+	nodes := &fakeNodes{}
+	e := New(nodes, []string{address}, 0, log.NewNopLogger())
+
+	e.collectAll(context.Background())
+	if nodes.claimsCalls != 1 {
+		t.Errorf("claims were fetched %d times in one collection, want 1", nodes.claimsCalls)
+	}
+
+	ch := make(chan prometheus.Metric, 32)
+	e.Collect(ch)
+	close(ch)
+
+	got := make(map[*prometheus.Desc]*dto.Metric)
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		got[m.Desc()] = &pb
+	}
+
+	// earned can go down, so it mustn't be a counter
+	earned, ok := got[earnedDesc]
+	if !ok || earned.Gauge == nil || earned.Gauge.GetValue() != 1 {
+		t.Errorf("earned: %v, want a gauge of 1 POKT", earned)
+	}
+	if relays, ok := got[relaysClaimedDesc]; !ok || relays.Counter.GetValue() != 150 {
+		t.Errorf("relays claimed: %v, want 150", relays)
+	}
+	if pending, ok := got[pendingPoktDesc]; !ok || pending.Gauge.GetValue() != 0.5 {
+		t.Errorf("pending POKT: %v, want 0.5", pending)
+	}
+	if lag, ok := got[heightLagDesc]; !ok || lag.Gauge.GetValue() != 3 {
+		t.Errorf("height lag: %v, want 3", lag)
+	}
+}
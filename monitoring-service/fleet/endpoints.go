@@ -0,0 +1,372 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"monitoring-service/pocket"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	List          endpoint.Endpoint
+	Get           endpoint.Endpoint
+	Save          endpoint.Endpoint
+	Delete        endpoint.Endpoint
+	Status        endpoint.Endpoint
+	Rewards       endpoint.Endpoint
+	RelaysByChain endpoint.Endpoint
+	Leaderboard   endpoint.Endpoint
+}
+
+type fleetRequest struct {
+	Name string
+}
+
+type saveFleetRequest struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+}
+
+type fleetRewardsRequest struct {
+	Name     string
+	Location *time.Location
+}
+
+type fleetResponse struct {
+	Name      string    `json:"name"`
+	Addresses []string  `json:"addresses"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type nodeErrorResponse struct {
+	Address string `json:"address"`
+	Error   string `json:"error"`
+}
+
+type relaysByChain struct {
+	Chain     string `json:"chain"`
+	Name      string `json:"name"`
+	NumRelays uint   `json:"num_relays"`
+}
+
+func ListEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fleets, err := svc.List()
+		if err != nil {
+			return nil, fmt.Errorf("ListEndpoint: %w", err)
+		}
+
+		resp := make([]fleetResponse, len(fleets))
+		for i, f := range fleets {
+			resp[i] = toFleetResponse(f)
+		}
+
+		return resp, nil
+	}
+}
+
+func GetEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("GetEndpoint: %w", err)
+		}
+
+		req, ok := request.(fleetRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		f, err := svc.Get(req.Name)
+		if err != nil {
+			return fail(err)
+		}
+
+		return toFleetResponse(f), nil
+	}
+}
+
+func SaveEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("SaveEndpoint: %w", err)
+		}
+
+		req, ok := request.(saveFleetRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		f, err := svc.Save(req.Name, req.Addresses)
+		if err != nil {
+			return fail(err)
+		}
+
+		return toFleetResponse(f), nil
+	}
+}
+
+func DeleteEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("DeleteEndpoint: %w", err)
+		}
+
+		req, ok := request.(fleetRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		if err = svc.Delete(req.Name); err != nil {
+			return fail(err)
+		}
+
+		return fleetResponse{Name: req.Name, Addresses: []string{}}, nil
+	}
+}
+
+type statusResponse struct {
+	Name          string               `json:"name"`
+	NetworkHeight uint                 `json:"network_height"`
+	NumNodes      int                  `json:"num_nodes"`
+	NumJailed     int                  `json:"num_jailed"`
+	NumOutOfSync  int                  `json:"num_out_of_sync"`
+	Nodes         []nodeStatusResponse `json:"nodes"`
+	Errors        []nodeErrorResponse  `json:"errors"`
+}
+
+type nodeStatusResponse struct {
+	Address           string    `json:"address"`
+	StakedBalance     uint      `json:"staked_balance"`
+	Balance           uint      `json:"balance"`
+	IsJailed          bool      `json:"is_jailed"`
+	IsReachable       bool      `json:"is_reachable"`
+	IsSynced          bool      `json:"is_synced"`
+	LagBlocks         uint      `json:"lag_blocks"`
+	LatestBlockHeight uint      `json:"latest_block_height"`
+	LatestBlockTime   time.Time `json:"latest_block_time"`
+}
+
+func StatusEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("StatusEndpoint: %w", err)
+		}
+
+		req, ok := request.(fleetRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		status, err := svc.Status(ctx, req.Name)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := statusResponse{
+			Name:          status.Name,
+			NetworkHeight: status.NetworkHeight,
+			NumNodes:      status.NumNodes,
+			NumJailed:     status.NumJailed,
+			NumOutOfSync:  status.NumOutOfSync,
+			Nodes:         make([]nodeStatusResponse, len(status.Nodes)),
+			Errors:        toNodeErrorsResponse(status.Errors),
+		}
+		for i, n := range status.Nodes {
+			resp.Nodes[i] = nodeStatusResponse{
+				Address:           n.Address,
+				StakedBalance:     n.StakedBalance,
+				Balance:           n.Balance,
+				IsJailed:          n.IsJailed,
+				IsReachable:       n.IsReachable,
+				IsSynced:          n.IsSynced,
+				LagBlocks:         n.LagBlocks,
+				LatestBlockHeight: n.LatestBlockHeight,
+				LatestBlockTime:   n.LatestBlockTime,
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+type monthlyRewardsResponse struct {
+	Year          uint            `json:"year"`
+	Month         uint            `json:"month"`
+	NumRelays     uint            `json:"num_relays"`
+	PoktAmount    float64         `json:"pokt_amount"`
+	RelaysByChain []relaysByChain `json:"relays_by_chain"`
+}
+
+type rewardsResponse struct {
+	Name   string                   `json:"name"`
+	Months []monthlyRewardsResponse `json:"months"`
+	Errors []nodeErrorResponse      `json:"errors"`
+}
+
+func RewardsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("RewardsEndpoint: %w", err)
+		}
+
+		req, ok := request.(fleetRewardsRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		rewards, err := svc.Rewards(ctx, req.Name, req.Location)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := rewardsResponse{
+			Name:   rewards.Name,
+			Months: make([]monthlyRewardsResponse, len(rewards.Months)),
+			Errors: toNodeErrorsResponse(rewards.Errors),
+		}
+		for i, mo := range rewards.Months {
+			resp.Months[i] = monthlyRewardsResponse{
+				Year:          mo.Year,
+				Month:         mo.Month,
+				NumRelays:     mo.TotalProofs,
+				PoktAmount:    mo.PoktAmount,
+				RelaysByChain: toRelaysByChainResponse(mo.RelaysByChain),
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+type relaysByChainResponse struct {
+	Name          string              `json:"name"`
+	RelaysByChain []relaysByChain     `json:"relays_by_chain"`
+	Errors        []nodeErrorResponse `json:"errors"`
+}
+
+func RelaysByChainEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("RelaysByChainEndpoint: %w", err)
+		}
+
+		req, ok := request.(fleetRewardsRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		rewards, err := svc.Rewards(ctx, req.Name, req.Location)
+		if err != nil {
+			return fail(err)
+		}
+
+		return relaysByChainResponse{
+			Name:          rewards.Name,
+			RelaysByChain: toRelaysByChainResponse(rewards.RelaysByChain),
+			Errors:        toNodeErrorsResponse(rewards.Errors),
+		}, nil
+	}
+}
+
+type leaderboardResponse struct {
+	Name   string                `json:"name"`
+	Nodes  []nodeRewardsResponse `json:"nodes"`
+	Errors []nodeErrorResponse   `json:"errors"`
+}
+
+type nodeRewardsResponse struct {
+	Rank          int             `json:"rank"`
+	Address       string          `json:"address"`
+	NumRelays     uint            `json:"num_relays"`
+	PoktAmount    float64         `json:"pokt_amount"`
+	RelaysByChain []relaysByChain `json:"relays_by_chain"`
+}
+
+func LeaderboardEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("LeaderboardEndpoint: %w", err)
+		}
+
+		req, ok := request.(fleetRewardsRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		rewards, err := svc.Rewards(ctx, req.Name, req.Location)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := leaderboardResponse{
+			Name:   rewards.Name,
+			Nodes:  make([]nodeRewardsResponse, len(rewards.Leaderboard)),
+			Errors: toNodeErrorsResponse(rewards.Errors),
+		}
+		for i, n := range rewards.Leaderboard {
+			resp.Nodes[i] = nodeRewardsResponse{
+				Rank:          n.Rank,
+				Address:       n.Address,
+				NumRelays:     n.TotalProofs,
+				PoktAmount:    n.PoktAmount,
+				RelaysByChain: toRelaysByChainResponse(n.RelaysByChain),
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+func toFleetResponse(f pocket.Fleet) fleetResponse {
+	return fleetResponse{
+		Name:      f.Name,
+		Addresses: f.Addresses,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+	}
+}
+
+func toNodeErrorsResponse(errs []NodeError) []nodeErrorResponse {
+	resp := make([]nodeErrorResponse, len(errs))
+	for i, e := range errs {
+		resp[i] = nodeErrorResponse{
+			Address: e.Address,
+			Error:   e.Error,
+		}
+	}
+	return resp
+}
+
+func toRelaysByChainResponse(byChain map[string]uint) []relaysByChain {
+	resp := make([]relaysByChain, 0, len(byChain))
+	for ch, num := range byChain {
+		name := ch
+		if chain, err := pocket.ChainFromID(ch); err == nil {
+			name = chain.Name
+		}
+
+		resp = append(resp, relaysByChain{
+			Chain:     ch,
+			Name:      name,
+			NumRelays: num,
+		})
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].NumRelays > resp[j].NumRelays
+	})
+
+	return resp
+}
@@ -0,0 +1,380 @@
+package fleet
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitoring-service/api"
+	"monitoring-service/pocket"
+)
+
+const (
+	DefaultConcurrency = 4
+
+	maxNameLength = 48
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NodeService supplies the per-node data that fleet reports are built from.
+type NodeService interface {
+	Height(ctx context.Context) (uint, error)
+	Node(ctx context.Context, address string) (pocket.Node, error)
+	RewardsByMonth(ctx context.Context, address string, loc *time.Location) (map[string]pocket.MonthlyReward, error)
+}
+
+type Repo interface {
+	Get(name string) (f pocket.Fleet, exists bool, err error)
+	Set(f pocket.Fleet) error
+	Delete(name string) error
+	List() ([]pocket.Fleet, error)
+}
+
+// Service manages fleets and aggregates the status and rewards of the nodes in them.
+type Service struct {
+	nodes       NodeService
+	repo        Repo
+	concurrency int
+}
+
+// NewService returns a fleet Service. concurrency bounds the number of nodes looked up in
+// parallel. Whether a node is synced is up to nodes.
+func NewService(nodes NodeService, repo Repo, concurrency int) Service {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	return Service{
+		nodes:       nodes,
+		repo:        repo,
+		concurrency: concurrency,
+	}
+}
+
+func (s *Service) List() ([]pocket.Fleet, error) {
+	fleets, err := s.repo.List()
+	if err != nil {
+		return nil, fmt.Errorf("List: %w", err)
+	}
+
+	return fleets, nil
+}
+
+func (s *Service) Get(name string) (pocket.Fleet, error) {
+	f, exists, err := s.repo.Get(name)
+	if err != nil {
+		return pocket.Fleet{}, fmt.Errorf("Get: %w", err)
+	}
+	if !exists {
+		return pocket.Fleet{}, api.NotFound(fmt.Sprintf("Get: fleet '%s' not found", name), api.Details{"fleet": name})
+	}
+
+	return f, nil
+}
+
+// Save creates the fleet, or replaces the addresses of an existing fleet with the same name.
+func (s *Service) Save(name string, addresses []string) (pocket.Fleet, error) {
+	if err := validateName(name); err != nil {
+		return pocket.Fleet{}, fmt.Errorf("Save: %w", err)
+	}
+
+	normalized, err := normalizeAddresses(addresses)
+	if err != nil {
+		return pocket.Fleet{}, fmt.Errorf("Save: %w", err)
+	}
+
+	existing, exists, err := s.repo.Get(name)
+	if err != nil {
+		return pocket.Fleet{}, fmt.Errorf("Save: %w", err)
+	}
+
+	now := time.Now().UTC()
+	f := pocket.Fleet{
+		Name:      name,
+		Addresses: normalized,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if exists {
+		f.CreatedAt = existing.CreatedAt
+	}
+
+	if err = s.repo.Set(f); err != nil {
+		return pocket.Fleet{}, fmt.Errorf("Save: %w", err)
+	}
+
+	return f, nil
+}
+
+func (s *Service) Delete(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return fmt.Errorf("Delete: %w", err)
+	}
+
+	if err := s.repo.Delete(name); err != nil {
+		return fmt.Errorf("Delete: %w", err)
+	}
+
+	return nil
+}
+
+// NodeError records a node whose data could not be fetched.
+type NodeError struct {
+	Address string
+	Error   string
+}
+
+// Status counts the nodes in a fleet that are jailed or behind the network.
+type Status struct {
+	Name          string
+	NetworkHeight uint
+	NumNodes      int
+	NumJailed     int
+	NumOutOfSync  int
+	Nodes         []pocket.Node
+	Errors        []NodeError
+}
+
+// Status fetches every node in the fleet, and counts those that are jailed or not synced.
+func (s *Service) Status(ctx context.Context, name string) (Status, error) {
+	f, err := s.Get(name)
+	if err != nil {
+		return Status{}, fmt.Errorf("Status: %w", err)
+	}
+
+	networkHeight, err := s.nodes.Height(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("Status: %w", err)
+	}
+
+	nodes := make([]pocket.Node, len(f.Addresses))
+	errs := s.forEachNode(ctx, f.Addresses, func(ctx context.Context, i int, address string) error {
+		var err error
+		nodes[i], err = s.nodes.Node(ctx, address)
+		return err
+	})
+	if ctx.Err() != nil {
+		return Status{}, fmt.Errorf("Status: %w", ctx.Err())
+	}
+
+	status := Status{
+		Name:          f.Name,
+		NetworkHeight: networkHeight,
+		NumNodes:      len(f.Addresses),
+		Errors:        errs,
+	}
+	failed := failedAddresses(errs)
+	for i, n := range nodes {
+		if failed[f.Addresses[i]] {
+			continue
+		}
+
+		status.Nodes = append(status.Nodes, n)
+		if n.IsJailed {
+			status.NumJailed++
+		}
+		if !n.IsSynced {
+			status.NumOutOfSync++
+		}
+	}
+
+	return status, nil
+}
+
+// MonthlyReward is the combined reward of every node in a fleet for one month.
+type MonthlyReward struct {
+	Year          uint
+	Month         uint
+	TotalProofs   uint
+	PoktAmount    float64
+	RelaysByChain map[string]uint
+}
+
+// NodeReward is a node's total reward, as ranked on a fleet's leaderboard.
+type NodeReward struct {
+	Rank          int
+	Address       string
+	TotalProofs   uint
+	PoktAmount    float64
+	RelaysByChain map[string]uint
+}
+
+// Rewards combines the monthly rewards of every node in a fleet.
+type Rewards struct {
+	Name          string
+	Months        []MonthlyReward
+	RelaysByChain map[string]uint
+	Leaderboard   []NodeReward
+	Errors        []NodeError
+}
+
+// Rewards fetches the monthly rewards of every node in the fleet, and combines them by month, by
+// chain, and into a leaderboard of nodes ordered by POKT earned. Months are those of loc.
+func (s *Service) Rewards(ctx context.Context, name string, loc *time.Location) (Rewards, error) {
+	f, err := s.Get(name)
+	if err != nil {
+		return Rewards{}, fmt.Errorf("Rewards: %w", err)
+	}
+
+	nodeMonths := make([]map[string]pocket.MonthlyReward, len(f.Addresses))
+	errs := s.forEachNode(ctx, f.Addresses, func(ctx context.Context, i int, address string) error {
+		var err error
+		nodeMonths[i], err = s.nodes.RewardsByMonth(ctx, address, loc)
+		return err
+	})
+	if ctx.Err() != nil {
+		return Rewards{}, fmt.Errorf("Rewards: %w", ctx.Err())
+	}
+
+	rewards := Rewards{
+		Name:          f.Name,
+		RelaysByChain: make(map[string]uint),
+		Errors:        errs,
+	}
+	failed := failedAddresses(errs)
+	months := make(map[string]*MonthlyReward)
+	for i, address := range f.Addresses {
+		if failed[address] {
+			continue
+		}
+
+		node := NodeReward{
+			Address:       address,
+			RelaysByChain: make(map[string]uint),
+		}
+		for key, mo := range nodeMonths[i] {
+			month, exists := months[key]
+			if !exists {
+				month = &MonthlyReward{
+					Year:          mo.Year,
+					Month:         mo.Month,
+					RelaysByChain: make(map[string]uint),
+				}
+				months[key] = month
+			}
+
+			pokt := mo.PoktAmount()
+			month.TotalProofs += mo.TotalProofs
+			month.PoktAmount += pokt
+			node.TotalProofs += mo.TotalProofs
+			node.PoktAmount += pokt
+			for _, tx := range mo.Transactions {
+				month.RelaysByChain[tx.ChainID] += tx.NumRelays
+				node.RelaysByChain[tx.ChainID] += tx.NumRelays
+				rewards.RelaysByChain[tx.ChainID] += tx.NumRelays
+			}
+		}
+		rewards.Leaderboard = append(rewards.Leaderboard, node)
+	}
+
+	for _, month := range months {
+		rewards.Months = append(rewards.Months, *month)
+	}
+	sort.Slice(rewards.Months, func(i, j int) bool {
+		if rewards.Months[i].Year == rewards.Months[j].Year {
+			return rewards.Months[i].Month > rewards.Months[j].Month
+		}
+		return rewards.Months[i].Year > rewards.Months[j].Year
+	})
+
+	sort.SliceStable(rewards.Leaderboard, func(i, j int) bool {
+		return rewards.Leaderboard[i].PoktAmount > rewards.Leaderboard[j].PoktAmount
+	})
+	for i := range rewards.Leaderboard {
+		rewards.Leaderboard[i].Rank = i + 1
+	}
+
+	return rewards, nil
+}
+
+// forEachNode calls fn for every address, at most s.concurrency at a time. A node that fails
+// doesn't stop the others; its error is returned instead.
+func (s *Service) forEachNode(ctx context.Context, addresses []string, fn func(ctx context.Context, i int, address string) error) []NodeError {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []NodeError
+	)
+	sem := make(chan struct{}, s.concurrency)
+
+	for i, address := range addresses {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, i, address); err != nil {
+				mu.Lock()
+				errs = append(errs, NodeError{Address: address, Error: err.Error()})
+				mu.Unlock()
+			}
+		}(i, address)
+	}
+	wg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Address < errs[j].Address
+	})
+
+	return errs
+}
+
+func failedAddresses(errs []NodeError) map[string]bool {
+	failed := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		failed[e.Address] = true
+	}
+	return failed
+}
+
+func validateName(name string) error {
+	if name == "" {
+		return api.InvalidArgument("validateName: Missing required param 'name'", api.Details{"param": "name"})
+	}
+
+	if len(name) > maxNameLength || !validName.MatchString(name) {
+		return api.InvalidArgument(
+			fmt.Sprintf("validateName: fleet names are up to %d letters, digits, '-' or '_'", maxNameLength),
+			api.Details{"param": "name", "value": name},
+		)
+	}
+
+	return nil
+}
+
+// normalizeAddresses lower-cases addresses and drops duplicates, keeping their order.
+func normalizeAddresses(addresses []string) ([]string, error) {
+	if len(addresses) == 0 {
+		return nil, api.InvalidArgument("normalizeAddresses: Missing required param 'addresses'", api.Details{"param": "addresses"})
+	}
+
+	seen := make(map[string]bool, len(addresses))
+	normalized := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if b, err := hex.DecodeString(a); err != nil || len(b) != 20 {
+			return nil, api.InvalidArgument(fmt.Sprintf("normalizeAddresses: invalid address '%s'", a), api.Details{"param": "addresses", "value": a})
+		}
+
+		if !seen[a] {
+			seen[a] = true
+			normalized = append(normalized, a)
+		}
+	}
+
+	return normalized, nil
+}
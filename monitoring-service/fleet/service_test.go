@@ -0,0 +1,382 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"monitoring-service/api"
+	"monitoring-service/inmem"
+	"monitoring-service/pocket"
+)
+
+const networkHeight = 1000
+
+// fakeNodes serves the nodes and rewards of addresses, failing for those in fail. It counts the
+// lookups made for each address, and how many were being made at once.
+type fakeNodes struct {
+	nodes   map[string]pocket.Node
+	rewards map[string]map[string]pocket.MonthlyReward
+	fail    map[string]bool
+	delay   time.Duration
+
+	mu          sync.Mutex
+	lookups     map[string]int
+	inFlight    int
+	maxInFlight int
+}
+
+func newFakeNodes() *fakeNodes {
+	return &fakeNodes{
+		nodes:   make(map[string]pocket.Node),
+		rewards: make(map[string]map[string]pocket.MonthlyReward),
+		fail:    make(map[string]bool),
+		lookups: make(map[string]int),
+	}
+}
+
+func (f *fakeNodes) lookup(address string) error {
+	f.mu.Lock()
+	f.lookups[address]++
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	if f.fail[address] {
+		return fmt.Errorf("node %s is unreachable", address)
+	}
+	return nil
+}
+
+func (f *fakeNodes) Height(ctx context.Context) (uint, error) {
+	return networkHeight, nil
+}
+
+func (f *fakeNodes) Node(ctx context.Context, address string) (pocket.Node, error) {
+	if err := f.lookup(address); err != nil {
+		return pocket.Node{}, err
+	}
+	return f.nodes[address], nil
+}
+
+func (f *fakeNodes) RewardsByMonth(ctx context.Context, address string, loc *time.Location) (map[string]pocket.MonthlyReward, error) {
+	if err := f.lookup(address); err != nil {
+		return nil, err
+	}
+	return f.rewards[address], nil
+}
+
+func address(i int) string {
+	return fmt.Sprintf("%040x", i+1)
+}
+
+// proofs returns a month in which a node submitted n proofs of 100 relays on chain, each paying 1 POKT.
+func proofs(year, month uint, chain string, n int) pocket.MonthlyReward {
+	mo := pocket.MonthlyReward{Year: year, Month: month, TotalProofs: uint(n)}
+	for i := 0; i < n; i++ {
+		mo.Transactions = append(mo.Transactions, pocket.Transaction{ChainID: chain, NumRelays: 100, PoktPerRelay: 0.01, IsConfirmed: true})
+	}
+	return mo
+}
+
+// newFleet returns a service with a fleet "fleet" of n nodes. Node i has proved i+1 sessions on
+// chain 0021 in March 2022, and every other node is jailed and every third one out of sync.
+func newFleet(t *testing.T, nodes *fakeNodes, n, concurrency int) Service {
+	t.Helper()
+
+	addresses := make([]string, n)
+	for i := range addresses {
+		a := address(i)
+		addresses[i] = a
+		nodes.nodes[a] = pocket.Node{Address: a, IsJailed: i%2 == 1, IsSynced: i%3 != 2}
+		nodes.rewards[a] = map[string]pocket.MonthlyReward{"2022-03": proofs(2022, 3, "0021", i+1)}
+	}
+
+	svc := NewService(nodes, inmem.NewFleetsRepo(), concurrency)
+	if _, err := svc.Save("fleet", addresses); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	return svc
+}
+
+func TestFanOut(t *testing.T) {
+	// 8 nodes looked up 4 at a time
+	const numNodes, concurrency = 8, 4
+
+	for name, call := range map[string]func(svc Service) error{
+		"status": func(svc Service) error {
+			_, err := svc.Status(context.Background(), "fleet")
+			return err
+		},
+		"rewards": func(svc Service) error {
+			_, err := svc.Rewards(context.Background(), "fleet", time.UTC)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			nodes := newFakeNodes()
+			nodes.delay = 10 * time.Millisecond
+			svc := newFleet(t, nodes, numNodes, concurrency)
+
+			if err := call(svc); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			nodes.mu.Lock()
+			defer nodes.mu.Unlock()
+			if len(nodes.lookups) != numNodes {
+				t.Errorf("looked up %d nodes, want %d", len(nodes.lookups), numNodes)
+			}
+			for a, n := range nodes.lookups {
+				if n != 1 {
+					t.Errorf("node %s looked up %d times, want once", a, n)
+				}
+			}
+			if nodes.maxInFlight != concurrency {
+				t.Errorf("%d nodes looked up at once, want %d", nodes.maxInFlight, concurrency)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		fail          []int
+		wantNodes     []int
+		wantJailed    int
+		wantOutOfSync int
+	}{
+		// of the 4 nodes, 1 and 3 are jailed and 2 is out of sync
+		{"all nodes", nil, []int{0, 1, 2, 3}, 2, 1},
+		{"one node fails", []int{1}, []int{0, 2, 3}, 1, 1},
+		{"some nodes fail", []int{0, 2}, []int{1, 3}, 2, 0},
+		{"every node fails", []int{0, 1, 2, 3}, nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newFakeNodes()
+			svc := newFleet(t, nodes, 4, 2)
+			for _, i := range tt.fail {
+				nodes.fail[address(i)] = true
+			}
+
+			status, err := svc.Status(context.Background(), "fleet")
+			if err != nil {
+				t.Fatalf("Status: %v", err)
+			}
+
+			if status.Name != "fleet" || status.NetworkHeight != networkHeight || status.NumNodes != 4 {
+				t.Errorf("Status: %s at %d with %d nodes, want fleet at %d with 4", status.Name, status.NetworkHeight, status.NumNodes, networkHeight)
+			}
+
+			var got []string
+			for _, n := range status.Nodes {
+				got = append(got, n.Address)
+			}
+			if want := addresses(tt.wantNodes); !reflect.DeepEqual(got, want) {
+				t.Errorf("nodes: %v, want %v", got, want)
+			}
+			if status.NumJailed != tt.wantJailed || status.NumOutOfSync != tt.wantOutOfSync {
+				t.Errorf("%d jailed and %d out of sync, want %d and %d", status.NumJailed, status.NumOutOfSync, tt.wantJailed, tt.wantOutOfSync)
+			}
+			checkErrors(t, status.Errors, tt.fail)
+		})
+	}
+}
+
+func TestRewards(t *testing.T) {
+	tests := []struct {
+		name        string
+		fail        []int
+		wantRanking []int
+	}{
+		// node i earned i+1 POKT in March, and node 0 another 5 in April
+		{"all nodes", nil, []int{0, 3, 2, 1}},
+		{"one node fails", []int{2}, []int{0, 3, 1}},
+		{"some nodes fail", []int{0, 3}, []int{2, 1}},
+		{"every node fails", []int{0, 1, 2, 3}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := newFakeNodes()
+			svc := newFleet(t, nodes, 4, 2)
+			nodes.rewards[address(0)]["2022-04"] = proofs(2022, 4, "0001", 5)
+			for _, i := range tt.fail {
+				nodes.fail[address(i)] = true
+			}
+
+			rewards, err := svc.Rewards(context.Background(), "fleet", time.UTC)
+			if err != nil {
+				t.Fatalf("Rewards: %v", err)
+			}
+
+			var ranking []string
+			wantProofs := map[string]uint{}
+			wantRelays := map[string]uint{}
+			for i, r := range rewards.Leaderboard {
+				ranking = append(ranking, r.Address)
+				if r.Rank != i+1 {
+					t.Errorf("node %s ranked %d at position %d", r.Address, r.Rank, i+1)
+				}
+			}
+			if want := addresses(tt.wantRanking); !reflect.DeepEqual(ranking, want) {
+				t.Errorf("leaderboard: %v, want %v", ranking, want)
+			}
+
+			// the months and chains only count the nodes that were fetched
+			for _, i := range tt.wantRanking {
+				wantProofs["2022-03"] += uint(i + 1)
+				wantRelays["0021"] += uint(i+1) * 100
+				if i == 0 {
+					wantProofs["2022-04"] += 5
+					wantRelays["0001"] += 500
+				}
+			}
+			gotProofs := map[string]uint{}
+			var order []string
+			for _, mo := range rewards.Months {
+				key := fmt.Sprintf("%d-%02d", mo.Year, mo.Month)
+				order = append(order, key)
+				gotProofs[key] = mo.TotalProofs
+				if math.Abs(mo.PoktAmount-float64(mo.TotalProofs)) > 1e-9 {
+					t.Errorf("month %s: %g POKT, want %d", key, mo.PoktAmount, mo.TotalProofs)
+				}
+			}
+			if !reflect.DeepEqual(gotProofs, wantProofs) {
+				t.Errorf("proofs by month: %v, want %v", gotProofs, wantProofs)
+			}
+			if !sort.SliceIsSorted(order, func(i, j int) bool { return order[i] > order[j] }) {
+				t.Errorf("months %v are not latest first", order)
+			}
+			if !reflect.DeepEqual(rewards.RelaysByChain, wantRelays) {
+				t.Errorf("relays by chain: %v, want %v", rewards.RelaysByChain, wantRelays)
+			}
+			checkErrors(t, rewards.Errors, tt.fail)
+		})
+	}
+}
+
+func TestSave(t *testing.T) {
+	repo := inmem.NewFleetsRepo()
+	svc := NewService(newFakeNodes(), repo, 1)
+
+	// addresses are stored lower case, without duplicates, in the order given
+	created, err := svc.Save("my-fleet", []string{" " + strings.ToUpper(address(1)), address(0), address(1)})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if want := addresses([]int{1, 0}); !reflect.DeepEqual(created.Addresses, want) {
+		t.Errorf("saved addresses %v, want %v", created.Addresses, want)
+	}
+	stored, exists, err := repo.Get("my-fleet")
+	if err != nil || !exists || !reflect.DeepEqual(stored, created) {
+		t.Fatalf("stored fleet: %+v, %t, %v, want %+v", stored, exists, err, created)
+	}
+
+	// saving it again replaces the addresses but keeps when it was created
+	time.Sleep(time.Millisecond)
+	updated, err := svc.Save("my-fleet", []string{address(2)})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) || !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Errorf("updated fleet created %s and updated %s, want created %s and updated after %s",
+			updated.CreatedAt, updated.UpdatedAt, created.CreatedAt, created.UpdatedAt)
+	}
+	got, err := svc.Get("my-fleet")
+	if err != nil || !reflect.DeepEqual(got, updated) {
+		t.Errorf("Get: %+v, %v, want %+v", got, err, updated)
+	}
+
+	if _, err = svc.Save("other", []string{address(3)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	fleets, err := svc.List()
+	if err != nil || len(fleets) != 2 {
+		t.Errorf("List: %+v, %v, want 2 fleets", fleets, err)
+	}
+
+	// invalid fleets are rejected without storing anything
+	for _, invalid := range []struct {
+		name      string
+		addresses []string
+	}{
+		{"", []string{address(0)}},
+		{"no spaces", []string{address(0)}},
+		{strings.Repeat("a", maxNameLength+1), []string{address(0)}},
+		{"new", nil},
+		{"new", []string{"not-hex"}},
+		{"new", []string{address(0)[2:]}},
+	} {
+		if _, err = svc.Save(invalid.name, invalid.addresses); !hasCode(err, api.CodeInvalidArgument) {
+			t.Errorf("Save(%q, %v): %v, want an invalid argument", invalid.name, invalid.addresses, err)
+		}
+	}
+	if fleets, _ = svc.List(); len(fleets) != 2 {
+		t.Errorf("List after invalid saves: %d fleets, want 2", len(fleets))
+	}
+
+	if err = svc.Delete("my-fleet"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err = svc.Get("my-fleet"); !hasCode(err, api.CodeNotFound) {
+		t.Errorf("Get of a deleted fleet: %v, want not found", err)
+	}
+	if err = svc.Delete("my-fleet"); !hasCode(err, api.CodeNotFound) {
+		t.Errorf("Delete of a deleted fleet: %v, want not found", err)
+	}
+	if _, err = svc.Status(context.Background(), "my-fleet"); !hasCode(err, api.CodeNotFound) {
+		t.Errorf("Status of a deleted fleet: %v, want not found", err)
+	}
+}
+
+func addresses(indexes []int) []string {
+	var list []string
+	for _, i := range indexes {
+		list = append(list, address(i))
+	}
+	return list
+}
+
+// checkErrors checks that errs names exactly the nodes in failed, ordered by address.
+func checkErrors(t *testing.T, errs []NodeError, failed []int) {
+	t.Helper()
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Address)
+		if !strings.Contains(e.Error, "unreachable") {
+			t.Errorf("error of node %s: %q", e.Address, e.Error)
+		}
+	}
+
+	want := addresses(failed)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("failed nodes: %v, want %v", got, want)
+	}
+}
+
+func hasCode(err error, code api.ErrorCode) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
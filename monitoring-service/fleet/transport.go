@@ -0,0 +1,145 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"monitoring-service/api"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	fleetsEndpointPath        = "/fleets"
+	fleetEndpointPath         = "/fleets/{name}"
+	statusEndpointPath        = "/fleets/{name}/status"
+	rewardsEndpointPath       = "/fleets/{name}/rewards"
+	relaysByChainEndpointPath = "/fleets/{name}/relays-by-chain"
+	leaderboardEndpointPath   = "/fleets/{name}/leaderboard"
+)
+
+type transport struct {
+	Service Service
+	Routes  []api.Route
+}
+
+func NewTransport(svc Service) transport {
+	return transport{
+		Service: svc,
+		Routes: []api.Route{
+			{
+				Method:   http.MethodGet,
+				Path:     fleetsEndpointPath,
+				Endpoint: ListEndpoint(svc),
+				Decoder:  api.DecodeEmptyRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodPost,
+				Path:     fleetsEndpointPath,
+				Endpoint: SaveEndpoint(svc),
+				Decoder:  decodeSaveFleetRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     fleetEndpointPath,
+				Endpoint: GetEndpoint(svc),
+				Decoder:  decodeFleetRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodPut,
+				Path:     fleetEndpointPath,
+				Endpoint: SaveEndpoint(svc),
+				Decoder:  decodeSaveFleetRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodDelete,
+				Path:     fleetEndpointPath,
+				Endpoint: DeleteEndpoint(svc),
+				Decoder:  decodeFleetRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     statusEndpointPath,
+				Endpoint: StatusEndpoint(svc),
+				Decoder:  decodeFleetRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     rewardsEndpointPath,
+				Endpoint: RewardsEndpoint(svc),
+				Decoder:  decodeFleetRewardsRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     relaysByChainEndpointPath,
+				Endpoint: RelaysByChainEndpoint(svc),
+				Decoder:  decodeFleetRewardsRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     leaderboardEndpointPath,
+				Endpoint: LeaderboardEndpoint(svc),
+				Decoder:  decodeFleetRewardsRequest,
+				Encoder:  api.EncodeResponse,
+			},
+		},
+	}
+}
+
+func decodeFleetRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	name, ok := mux.Vars(req)["name"]
+	if !ok {
+		return nil, api.InvalidArgument("decodeFleetRequest: required param 'name' not found", api.Details{"param": "name"})
+	}
+
+	return fleetRequest{Name: name}, nil
+}
+
+// decodeSaveFleetRequest reads the fleet from the request body. On PUT the name comes from the
+// path, and a name in the body, if any, must match it.
+func decodeSaveFleetRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	var saveRequest saveFleetRequest
+	if err := json.NewDecoder(req.Body).Decode(&saveRequest); err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeSaveFleetRequest: invalid request body: %s", err), nil)
+	}
+
+	if name, ok := mux.Vars(req)["name"]; ok {
+		if saveRequest.Name != "" && saveRequest.Name != name {
+			return nil, api.InvalidArgument(
+				fmt.Sprintf("decodeSaveFleetRequest: name '%s' in body doesn't match '%s'", saveRequest.Name, name),
+				api.Details{"param": "name", "value": saveRequest.Name},
+			)
+		}
+		saveRequest.Name = name
+	}
+
+	return saveRequest, nil
+}
+
+func decodeFleetRewardsRequest(ctx context.Context, req *http.Request) (request interface{}, err error) {
+	name, ok := mux.Vars(req)["name"]
+	if !ok {
+		return nil, api.InvalidArgument("decodeFleetRewardsRequest: required param 'name' not found", api.Details{"param": "name"})
+	}
+
+	loc := time.UTC
+	if tz := req.URL.Query().Get("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeFleetRewardsRequest: failed to parse tz: %s", err), api.Details{"param": "tz", "value": tz})
+		}
+	}
+
+	return fleetRewardsRequest{Name: name, Location: loc}, nil
+}
@@ -0,0 +1,44 @@
+package follower
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	Status endpoint.Endpoint
+}
+
+type statusResponse struct {
+	Following        bool       `json:"following"`
+	Tip              uint       `json:"tip"`
+	LastCachedHeight uint       `json:"last_cached_height"`
+	StalenessBlocks  uint       `json:"staleness_blocks"`
+	LastPoll         *time.Time `json:"last_poll,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+}
+
+func StatusEndpoint(f *Follower) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		status, err := f.Status(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("StatusEndpoint: %w", err)
+		}
+
+		resp := statusResponse{
+			Following:        status.Following,
+			Tip:              status.Tip,
+			LastCachedHeight: status.LastCached,
+			StalenessBlocks:  status.Staleness,
+			LastError:        status.LastError,
+		}
+		if !status.LastPoll.IsZero() {
+			resp.LastPoll = &status.LastPoll
+		}
+
+		return resp, nil
+	}
+}
@@ -0,0 +1,225 @@
+package follower
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"monitoring-service/pocket"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	DefaultInterval = time.Minute
+
+	// DefaultMaxCatchUp is how far behind the tip the follower will start. Older gaps are left
+	// to the block time fetcher.
+	DefaultMaxCatchUp = 960
+)
+
+// Source supplies, and caches, block times and params.
+type Source interface {
+	Height(ctx context.Context) (uint, error)
+	BlockTime(ctx context.Context, height uint) (time.Time, error)
+	AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error)
+}
+
+// Marker stores the height up to which every block time has been cached.
+type Marker interface {
+	HighWaterMark() (height uint, exists bool, err error)
+	SetHighWaterMark(height uint) error
+}
+
+// Status reports how far the cache is behind the network.
+type Status struct {
+	Following  bool
+	Tip        uint
+	LastCached uint
+	Staleness  uint
+	LastPoll   time.Time
+	LastError  string
+}
+
+// Follower keeps the block times and params cache warm by fetching every new block as the
+// network produces it.
+type Follower struct {
+	source     Source
+	marker     Marker
+	interval   time.Duration
+	maxCatchUp uint
+	logger     log.Logger
+
+	mu         sync.RWMutex
+	following  bool
+	last       uint
+	markerHeld bool
+	lastPoll   time.Time
+	lastErr    error
+
+	lag metrics.Gauge
+}
+
+func New(source Source, marker Marker, interval time.Duration, maxCatchUp uint, logger log.Logger) *Follower {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Follower{
+		source:     source,
+		marker:     marker,
+		interval:   interval,
+		maxCatchUp: maxCatchUp,
+		logger:     logger,
+	}
+}
+
+// Instrument reports how many blocks the cache is behind the tip in lag, after every poll.
+func (f *Follower) Instrument(lag metrics.Gauge) {
+	f.lag = lag
+}
+
+// Run polls for new blocks once per interval until ctx is done.
+func (f *Follower) Run(ctx context.Context) error {
+	f.mu.Lock()
+	f.following = true
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.following = false
+		f.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.poll(ctx); err != nil && ctx.Err() == nil {
+			_ = f.logger.Log("level", "ERROR", "msg", err.Error())
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// poll caches every block between the last cached one and the tip.
+func (f *Follower) poll(ctx context.Context) (err error) {
+	defer func() {
+		f.mu.Lock()
+		f.lastPoll = time.Now()
+		f.lastErr = err
+		f.mu.Unlock()
+	}()
+
+	tip, err := f.source.Height(ctx)
+	if err != nil {
+		return fmt.Errorf("Follower.poll: %w", err)
+	}
+
+	f.mu.RLock()
+	last, markerHeld := f.last, f.markerHeld
+	f.mu.RUnlock()
+
+	if f.lag != nil {
+		defer func() {
+			f.mu.RLock()
+			last := f.last
+			f.mu.RUnlock()
+			if tip > last {
+				f.lag.Set(float64(tip - last))
+			} else {
+				f.lag.Set(0)
+			}
+		}()
+	}
+
+	if last == 0 {
+		if last, markerHeld, err = f.start(tip); err != nil {
+			return fmt.Errorf("Follower.poll: %w", err)
+		}
+	}
+
+	for h := last + 1; h <= tip; h++ {
+		if _, err = f.source.BlockTime(ctx, h); err != nil {
+			return fmt.Errorf("Follower.poll(%d): %w", h, err)
+		}
+		if _, err = f.source.AllParams(ctx, int64(h), false); err != nil {
+			return fmt.Errorf("Follower.poll(%d): %w", h, err)
+		}
+
+		// the mark only covers heights with no gaps below them
+		if markerHeld {
+			if err = f.marker.SetHighWaterMark(h); err != nil {
+				return fmt.Errorf("Follower.poll(%d): %w", h, err)
+			}
+		}
+
+		f.mu.Lock()
+		f.last, f.markerHeld = h, markerHeld
+		f.mu.Unlock()
+	}
+
+	return nil
+}
+
+// start returns the height to follow from: the high-water mark if it is within maxCatchUp of
+// the tip, and otherwise the block before the tip.
+func (f *Follower) start(tip uint) (last uint, markerHeld bool, err error) {
+	mark, exists, err := f.marker.HighWaterMark()
+	if err != nil {
+		return 0, false, err
+	}
+
+	if exists && mark+f.maxCatchUp >= tip {
+		return mark, true, nil
+	}
+
+	if exists {
+		_ = f.logger.Log("level", "WARN", "msg", fmt.Sprintf("Cache is %d blocks behind, following from the tip; run the block time fetcher to fill the gap", tip-mark))
+	}
+	if tip == 0 {
+		return 0, false, nil
+	}
+	return tip - 1, false, nil
+}
+
+// Status reports the network's tip and the last height cached. When the follower hasn't run yet,
+// the high-water mark is used as the last cached height.
+func (f *Follower) Status(ctx context.Context) (Status, error) {
+	tip, err := f.source.Height(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("Follower.Status: %w", err)
+	}
+
+	f.mu.RLock()
+	status := Status{
+		Following:  f.following,
+		Tip:        tip,
+		LastCached: f.last,
+		LastPoll:   f.lastPoll,
+	}
+	if f.lastErr != nil {
+		status.LastError = f.lastErr.Error()
+	}
+	f.mu.RUnlock()
+
+	if status.LastCached == 0 {
+		mark, _, err := f.marker.HighWaterMark()
+		if err != nil {
+			return Status{}, fmt.Errorf("Follower.Status: %w", err)
+		}
+		status.LastCached = mark
+	}
+
+	if tip > status.LastCached {
+		status.Staleness = tip - status.LastCached
+	}
+
+	return status, nil
+}
@@ -0,0 +1,298 @@
+package follower
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"monitoring-service/api"
+	"monitoring-service/pocket"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+)
+
+// fakeSource is a chain whose tip can be moved. It records the heights whose block time and params
+// were fetched, and fails to fetch the block time at failAt.
+type fakeSource struct {
+	mu         sync.Mutex
+	tip        uint
+	failAt     uint
+	blockTimes []uint
+	params     []int64
+}
+
+func (s *fakeSource) setTip(tip uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tip = tip
+}
+
+func (s *fakeSource) setFailAt(height uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failAt = height
+}
+
+// fetched returns the heights fetched since the last call.
+func (s *fakeSource) fetched(t *testing.T) []uint {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heights := s.blockTimes
+	if len(s.params) != len(heights) {
+		t.Errorf("fetched the block times of %v but the params of %v", heights, s.params)
+	}
+	for i, h := range s.params {
+		if i < len(heights) && uint(h) != heights[i] {
+			t.Errorf("fetched the block times of %v but the params of %v", heights, s.params)
+			break
+		}
+	}
+
+	s.blockTimes, s.params = nil, nil
+	return heights
+}
+
+func (s *fakeSource) Height(ctx context.Context) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tip, nil
+}
+
+func (s *fakeSource) BlockTime(ctx context.Context, height uint) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if height == s.failAt {
+		return time.Time{}, errors.New("block not found")
+	}
+	s.blockTimes = append(s.blockTimes, height)
+	return time.Unix(int64(height)*900, 0), nil
+}
+
+func (s *fakeSource) AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = append(s.params, height)
+	return pocket.AllParams{}, nil
+}
+
+type fakeMarker struct {
+	mu     sync.Mutex
+	mark   uint
+	exists bool
+}
+
+func (m *fakeMarker) HighWaterMark() (uint, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mark, m.exists, nil
+}
+
+func (m *fakeMarker) SetHighWaterMark(height uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mark, m.exists = height, true
+	return nil
+}
+
+func (m *fakeMarker) get() uint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mark
+}
+
+func heights(from, to uint) []uint {
+	var list []uint
+	for h := from; h <= to; h++ {
+		list = append(list, h)
+	}
+	return list
+}
+
+func poll(t *testing.T, f *Follower) {
+	t.Helper()
+
+	if err := f.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+}
+
+func TestPollFollowsNewBlocks(t *testing.T) {
+	source, marker := &fakeSource{tip: 100}, &fakeMarker{mark: 100, exists: true}
+	f := New(source, marker, time.Minute, DefaultMaxCatchUp, log.NewNopLogger())
+
+	// the cache is up to date
+	poll(t, f)
+	if got := source.fetched(t); len(got) != 0 {
+		t.Errorf("fetched %v with the cache at the tip", got)
+	}
+
+	for _, tip := range []uint{101, 101, 104} {
+		last := marker.get()
+		source.setTip(tip)
+		poll(t, f)
+
+		if got, want := source.fetched(t), heights(last+1, tip); !reflect.DeepEqual(got, want) {
+			t.Errorf("tip %d: fetched %v, want %v", tip, got, want)
+		}
+		if mark := marker.get(); mark != tip {
+			t.Errorf("tip %d: high-water mark %d", tip, mark)
+		}
+	}
+}
+
+func TestPollCatchesUp(t *testing.T) {
+	source, marker := &fakeSource{tip: 100, failAt: 95}, &fakeMarker{mark: 90, exists: true}
+	f := New(source, marker, time.Minute, 10, log.NewNopLogger())
+	lag := generic.NewGauge("lag")
+	f.Instrument(lag)
+
+	// blocks missed while the service was down are fetched up to the first that fails
+	if err := f.poll(context.Background()); err == nil {
+		t.Fatal("poll with a failing block: nil error")
+	}
+	if got, want := source.fetched(t), heights(91, 94); !reflect.DeepEqual(got, want) {
+		t.Errorf("fetched %v, want %v", got, want)
+	}
+	if mark := marker.get(); mark != 94 {
+		t.Errorf("high-water mark %d, want 94", mark)
+	}
+	if v := lag.Value(); v != 6 {
+		t.Errorf("lag %g, want 6", v)
+	}
+
+	// the next poll carries on from where it stopped
+	source.setFailAt(0)
+	source.setTip(102)
+	poll(t, f)
+	if got, want := source.fetched(t), heights(95, 102); !reflect.DeepEqual(got, want) {
+		t.Errorf("fetched %v, want %v", got, want)
+	}
+	if mark := marker.get(); mark != 102 {
+		t.Errorf("high-water mark %d, want 102", mark)
+	}
+	if v := lag.Value(); v != 0 {
+		t.Errorf("lag %g, want 0", v)
+	}
+}
+
+func TestPollLeavesOldGapsToTheFetcher(t *testing.T) {
+	for _, marker := range []*fakeMarker{{mark: 10, exists: true}, {}} {
+		source := &fakeSource{tip: 2000}
+		f := New(source, marker, time.Minute, DefaultMaxCatchUp, log.NewNopLogger())
+		start := marker.get()
+
+		// too far behind to catch up, so it follows from the tip
+		poll(t, f)
+		source.setTip(2002)
+		poll(t, f)
+		if got, want := source.fetched(t), heights(2000, 2002); !reflect.DeepEqual(got, want) {
+			t.Errorf("mark %d: fetched %v, want %v", start, got, want)
+		}
+
+		// the gap below is still there, so the mark must not move
+		if mark := marker.get(); mark != start {
+			t.Errorf("high-water mark moved from %d to %d across a gap", start, mark)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	source, marker := &fakeSource{tip: 100}, &fakeMarker{mark: 100, exists: true}
+	f := New(source, marker, 5*time.Millisecond, DefaultMaxCatchUp, log.NewNopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- f.Run(ctx) }()
+
+	// new blocks are picked up on the next tick
+	source.setTip(103)
+	deadline := time.Now().Add(5 * time.Second)
+	for marker.get() != 103 {
+		if time.Now().After(deadline) {
+			t.Fatalf("high-water mark %d, want 103", marker.get())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if status, err := f.Status(context.Background()); err != nil || !status.Following {
+		t.Errorf("Status while running: %+v, %v, want following", status, err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run: %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return once cancelled")
+	}
+
+	if got, want := source.fetched(t), heights(101, 103); !reflect.DeepEqual(got, want) {
+		t.Errorf("fetched %v, want %v", got, want)
+	}
+	if status, err := f.Status(context.Background()); err != nil || status.Following {
+		t.Errorf("Status after Run returned: %+v, %v, want not following", status, err)
+	}
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	source, marker := &fakeSource{tip: 120, failAt: 115}, &fakeMarker{mark: 110, exists: true}
+	f := New(source, marker, time.Minute, DefaultMaxCatchUp, log.NewNopLogger())
+
+	router := api.NewRouter(log.NewNopLogger())
+	router.AddRoutes(NewTransport(f).Routes)
+	get := func() map[string]interface{} {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		router.Mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cache/status", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /cache/status: %d %s", w.Code, w.Body)
+		}
+
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decoding %s: %v", w.Body, err)
+		}
+		return body.Data
+	}
+
+	// before the first poll, the high-water mark is the last cached height
+	want := map[string]interface{}{"following": false, "tip": 120.0, "last_cached_height": 110.0, "staleness_blocks": 10.0}
+	if got := get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("status before polling: %v, want %v", got, want)
+	}
+
+	// a poll that failed part way reports its error
+	if err := f.poll(context.Background()); err == nil {
+		t.Fatal("poll with a failing block: nil error")
+	}
+	got := get()
+	if got["last_cached_height"] != 114.0 || got["staleness_blocks"] != 6.0 {
+		t.Errorf("status after a failed poll: %v, want 114 cached and 6 behind", got)
+	}
+	if got["last_error"] == nil || got["last_poll"] == nil {
+		t.Errorf("status after a failed poll: %v, want its error and time", got)
+	}
+
+	// and a poll that succeeds clears it
+	source.setFailAt(0)
+	poll(t, f)
+	got = get()
+	if got["last_cached_height"] != 120.0 || got["staleness_blocks"] != 0.0 || got["last_error"] != nil {
+		t.Errorf("status after catching up: %v, want 120 cached, 0 behind and no error", got)
+	}
+}
@@ -0,0 +1,31 @@
+package follower
+
+import (
+	"net/http"
+
+	"monitoring-service/api"
+)
+
+const (
+	statusEndpointPath = "/cache/status"
+)
+
+type transport struct {
+	Follower *Follower
+	Routes   []api.Route
+}
+
+func NewTransport(f *Follower) transport {
+	return transport{
+		Follower: f,
+		Routes: []api.Route{
+			{
+				Method:   http.MethodGet,
+				Path:     statusEndpointPath,
+				Endpoint: StatusEndpoint(f),
+				Decoder:  api.DecodeEmptyRequest,
+				Encoder:  api.EncodeResponse,
+			},
+		},
+	}
+}
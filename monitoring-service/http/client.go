@@ -36,7 +36,7 @@ func (c ClientWithLogger) Do(req *http.Request) (*http.Response, error) {
 	}
 
 	_ = c.logger.Log("type", "INFO", "url", req.URL.String(), "took", t.Elapsed().String())
-	return resp, nil
+	return resp, err
 }
 
 func (c ClientWithLogger) Log(args ...interface{}) error {
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"context"
+	"monitoring-service/timer"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	unknownRPCPath  = "other"
+	statusTransport = "error"
+)
+
+type rpcPathKey struct{}
+
+// WithRPCPath returns a context that labels the requests made with it by path, rather than by
+// their URL, which also holds the endpoint's base path.
+func WithRPCPath(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, rpcPathKey{}, path)
+}
+
+// NewClientWithMetrics observes the duration of every request made through c in duration, by the
+// RPC path set with WithRPCPath and the response's status code.
+func NewClientWithMetrics(c Client, duration metrics.Histogram) Client {
+	return &ClientWithMetrics{
+		client:   c,
+		duration: duration,
+	}
+}
+
+type ClientWithMetrics struct {
+	client   Client
+	duration metrics.Histogram
+}
+
+func (c ClientWithMetrics) Do(req *http.Request) (*http.Response, error) {
+	path, ok := req.Context().Value(rpcPathKey{}).(string)
+	if !ok {
+		path = unknownRPCPath
+	}
+
+	t := timer.Start()
+	resp, err := c.client.Do(req)
+
+	status := statusTransport
+	if err == nil && resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	c.duration.With("path", path, "status", status).Observe(t.Elapsed().Seconds())
+
+	return resp, err
+}
@@ -0,0 +1,51 @@
+package indexer
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	Status endpoint.Endpoint
+}
+
+type addressStatusResponse struct {
+	Address        string    `json:"address"`
+	Indexed        bool      `json:"indexed"`
+	Checkpoint     uint      `json:"checkpoint"`
+	Running        bool      `json:"running"`
+	LastRun        time.Time `json:"last_run"`
+	LastDurationMs int64     `json:"last_duration_ms"`
+	LastNumTxs     int       `json:"last_num_txs"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+type statusResponse struct {
+	Addresses []addressStatusResponse `json:"addresses"`
+}
+
+func StatusEndpoint(idx *Indexer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		status := idx.Status()
+
+		resp := statusResponse{
+			Addresses: make([]addressStatusResponse, len(status)),
+		}
+		for i, st := range status {
+			resp.Addresses[i] = addressStatusResponse{
+				Address:        st.Address,
+				Indexed:        st.Indexed,
+				Checkpoint:     st.Checkpoint,
+				Running:        st.Running,
+				LastRun:        st.LastRun,
+				LastDurationMs: st.LastDuration.Milliseconds(),
+				LastNumTxs:     st.LastNumTxs,
+				LastError:      st.LastError,
+			}
+		}
+
+		return resp, nil
+	}
+}
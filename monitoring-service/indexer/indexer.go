@@ -0,0 +1,310 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/timer"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	DefaultInterval   = time.Minute
+	DefaultReorgDepth = 4
+
+	txsPerPage      = 100
+	sortNewestFirst = "desc"
+)
+
+// TransactionSource supplies enriched account transactions from the network.
+type TransactionSource interface {
+	Height(ctx context.Context) (uint, error)
+	AccountTransactions(ctx context.Context, address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error)
+}
+
+// TransactionStore persists indexed transactions and a checkpoint per address.
+type TransactionStore interface {
+	AccountTransactions(address string) ([]pocket.Transaction, error)
+	SetTransactions(address string, txs []pocket.Transaction) error
+	ReplaceTransactionsAbove(address string, height uint, txs []pocket.Transaction) error
+	Checkpoint(address string) (height uint, exists bool, err error)
+	SetCheckpoint(address string, height uint) error
+}
+
+// AddressStatus reports the indexing progress of a tracked address.
+type AddressStatus struct {
+	Address      string
+	Checkpoint   uint
+	Indexed      bool
+	Running      bool
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastNumTxs   int
+	LastError    string
+}
+
+// Indexer keeps the claims and proofs of a set of tracked addresses in a local store, so
+// that reward lookups don't need to page through the full account history on the network.
+type Indexer struct {
+	source     TransactionSource
+	store      TransactionStore
+	addresses  []string
+	interval   time.Duration
+	reorgDepth uint
+	logger     log.Logger
+
+	mu     sync.RWMutex
+	status map[string]*AddressStatus
+
+	lag         metrics.Gauge
+	lastSuccess metrics.Gauge
+}
+
+// New returns an Indexer for addresses. Every run re-fetches the last reorgDepth blocks before
+// an address's checkpoint so that txs from blocks that were replaced are dropped.
+func New(source TransactionSource, store TransactionStore, addresses []string, interval time.Duration, reorgDepth uint, logger log.Logger) *Indexer {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	status := make(map[string]*AddressStatus, len(addresses))
+	tracked := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" || status[a] != nil {
+			continue
+		}
+		tracked = append(tracked, a)
+		status[a] = &AddressStatus{Address: a}
+	}
+
+	return &Indexer{
+		source:     source,
+		store:      store,
+		addresses:  tracked,
+		interval:   interval,
+		reorgDepth: reorgDepth,
+		logger:     logger,
+		status:     status,
+	}
+}
+
+// Instrument reports, per address, how many blocks its checkpoint is behind the tip in lag, and
+// the unix time of its last successful run in lastSuccess.
+func (i *Indexer) Instrument(lag, lastSuccess metrics.Gauge) {
+	i.lag = lag
+	i.lastSuccess = lastSuccess
+}
+
+// ParseAddresses splits a comma separated list of node addresses.
+func ParseAddresses(list string) []string {
+	var addresses []string
+	for _, a := range strings.Split(list, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addresses = append(addresses, a)
+		}
+	}
+	return addresses
+}
+
+// Run indexes every tracked address straight away and then once per interval, until ctx is done.
+func (i *Indexer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		i.indexAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (i *Indexer) indexAll(ctx context.Context) {
+	for _, address := range i.addresses {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if _, err := i.IndexAddress(ctx, address); err != nil {
+			_ = i.logger.Log("level", "ERROR", "msg", err.Error())
+		}
+	}
+}
+
+// IndexAddress stores the transactions for address that are newer than its checkpoint, and
+// returns how many were stored.
+func (i *Indexer) IndexAddress(ctx context.Context, address string) (int, error) {
+	fail := func(err error) (int, error) {
+		i.finish(address, 0, 0, err)
+		return 0, fmt.Errorf("Indexer.IndexAddress(%s): %w", address, err)
+	}
+
+	address = strings.ToLower(address)
+	t := timer.Start()
+	i.start(address)
+
+	tip, err := i.source.Height(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	checkpoint, indexed, err := i.store.Checkpoint(address)
+	if err != nil {
+		return fail(err)
+	}
+
+	if i.lag != nil {
+		// an address that was never indexed is behind by the whole chain
+		lag := tip
+		if indexed {
+			lag = 0
+			if checkpoint < tip {
+				lag = tip - checkpoint
+			}
+		}
+		i.lag.With("address", address).Set(float64(lag))
+	}
+
+	var from uint
+	if indexed && checkpoint > i.reorgDepth {
+		from = checkpoint - i.reorgDepth
+	}
+
+	var txs []pocket.Transaction
+	for page, done := uint(1), false; !done; page++ {
+		pageTxs, err := i.source.AccountTransactions(ctx, address, page, txsPerPage, sortNewestFirst)
+		if err != nil {
+			return fail(err)
+		}
+
+		for _, tx := range pageTxs {
+			if indexed && tx.Height <= from {
+				done = true
+				break
+			}
+			txs = append(txs, tx)
+		}
+
+		if len(pageTxs) < txsPerPage {
+			done = true
+		}
+	}
+
+	// anything stored above from that the network no longer returns was reorged away. The
+	// replace is a single change, so a failure can't leave the reorged range empty.
+	if indexed {
+		err = i.store.ReplaceTransactionsAbove(address, from, txs)
+	} else {
+		err = i.store.SetTransactions(address, txs)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, tx := range txs {
+		if tx.Height > tip {
+			tip = tx.Height
+		}
+	}
+	if err = i.store.SetCheckpoint(address, tip); err != nil {
+		return fail(err)
+	}
+
+	i.finish(address, tip, len(txs), nil)
+	if i.lag != nil {
+		i.lag.With("address", address).Set(0)
+		i.lastSuccess.With("address", address).Set(float64(time.Now().Unix()))
+	}
+	_ = i.logger.Log("level", "INFO", "msg", fmt.Sprintf("Indexed %d txs for %s from height %d to %d (took %s)",
+		len(txs), address, from, tip, t.Elapsed().String()))
+
+	return len(txs), nil
+}
+
+// IndexedTransactions returns the stored transactions for a tracked address, newest first.
+func (i *Indexer) IndexedTransactions(address string) ([]pocket.Transaction, bool, error) {
+	address = strings.ToLower(address)
+
+	i.mu.RLock()
+	st, tracked := i.status[address]
+	indexed := tracked && st.Indexed
+	i.mu.RUnlock()
+
+	if !tracked {
+		return nil, false, nil
+	}
+
+	if !indexed {
+		// a checkpoint from a previous run is still good
+		_, exists, err := i.store.Checkpoint(address)
+		if err != nil || !exists {
+			return nil, false, err
+		}
+	}
+
+	txs, err := i.store.AccountTransactions(address)
+	if err != nil {
+		return nil, false, fmt.Errorf("Indexer.IndexedTransactions: %w", err)
+	}
+
+	return txs, true, nil
+}
+
+// Status returns the indexing status of every tracked address.
+func (i *Indexer) Status() []AddressStatus {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	status := make([]AddressStatus, 0, len(i.status))
+	for _, st := range i.status {
+		status = append(status, *st)
+	}
+
+	sort.Slice(status, func(a, b int) bool {
+		return status[a].Address < status[b].Address
+	})
+
+	return status
+}
+
+func (i *Indexer) start(address string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	st, ok := i.status[address]
+	if !ok {
+		st = &AddressStatus{Address: address}
+		i.status[address] = st
+	}
+	st.Running = true
+	st.LastRun = time.Now()
+}
+
+func (i *Indexer) finish(address string, checkpoint uint, numTxs int, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	st := i.status[address]
+	st.Running = false
+	st.LastDuration = time.Since(st.LastRun)
+	st.LastNumTxs = numTxs
+	if err != nil {
+		st.LastError = err.Error()
+		return
+	}
+
+	st.LastError = ""
+	st.Indexed = true
+	st.Checkpoint = checkpoint
+}
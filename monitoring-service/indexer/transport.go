@@ -0,0 +1,31 @@
+package indexer
+
+import (
+	"net/http"
+
+	"monitoring-service/api"
+)
+
+const (
+	statusEndpointPath = "/indexer/status"
+)
+
+type transport struct {
+	Indexer *Indexer
+	Routes  []api.Route
+}
+
+func NewTransport(idx *Indexer) transport {
+	return transport{
+		Indexer: idx,
+		Routes: []api.Route{
+			{
+				Method:   http.MethodGet,
+				Path:     statusEndpointPath,
+				Endpoint: StatusEndpoint(idx),
+				Decoder:  api.DecodeEmptyRequest,
+				Encoder:  api.EncodeResponse,
+			},
+		},
+	}
+}
@@ -1,26 +1,86 @@
 package inmem
 
-import "time"
+import (
+	"sort"
+	"sync"
+	"time"
+)
 
-type blockTimes map[uint]time.Time
+type blockTimes struct {
+	mu         sync.RWMutex
+	times      map[uint]time.Time
+	mark       uint
+	markExists bool
+}
 
+// BlockTimesRepo keeps block times in memory. Copies share the same data.
 type BlockTimesRepo struct {
-	store blockTimes
+	store *blockTimes
 }
 
 func NewBlockTimesRepo() BlockTimesRepo {
-	return BlockTimesRepo{store: make(blockTimes)}
+	return BlockTimesRepo{store: &blockTimes{times: make(map[uint]time.Time)}}
 }
 
-func (r *BlockTimesRepo) Get(height uint) (t time.Time, exists bool) {
-	t, ok := r.store[height]
+func (r BlockTimesRepo) Get(height uint) (t time.Time, exists bool, err error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	t, ok := r.store.times[height]
 	if !ok {
-		return time.Time{}, false
+		return time.Time{}, false, nil
 	}
 
-	return t, true
+	return t, true, nil
+}
+
+func (r BlockTimesRepo) Set(height uint, t time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.times[height] = t
+	return nil
+}
+
+// HighWaterMark returns the height up to which every block time has been stored.
+func (r BlockTimesRepo) HighWaterMark() (height uint, exists bool, err error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.mark, r.store.markExists, nil
 }
 
-func (r *BlockTimesRepo) Set(height uint, t time.Time) {
-	r.store[height] = t
+func (r BlockTimesRepo) SetHighWaterMark(height uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.mark, r.store.markExists = height, true
+	return nil
+}
+
+// Range calls fn with every stored block time in [from, to], in height order. A zero to means no
+// upper bound. fn is called without the lock held, so it may use the repo.
+func (r BlockTimesRepo) Range(from, to uint, fn func(height uint, t time.Time) error) error {
+	r.store.mu.RLock()
+	heights := make([]uint, 0, len(r.store.times))
+	for h := range r.store.times {
+		if h >= from && (to == 0 || h <= to) {
+			heights = append(heights, h)
+		}
+	}
+	r.store.mu.RUnlock()
+
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	for _, h := range heights {
+		t, exists, _ := r.Get(h)
+		if !exists {
+			continue
+		}
+		if err := fn(h, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,73 @@
+package inmem
+
+import (
+	"sort"
+	"sync"
+
+	"monitoring-service/pocket"
+)
+
+type fleets struct {
+	mu     sync.RWMutex
+	fleets map[string]pocket.Fleet
+}
+
+// FleetsRepo keeps fleets in memory. Copies share the same data.
+type FleetsRepo struct {
+	store *fleets
+}
+
+func NewFleetsRepo() FleetsRepo {
+	return FleetsRepo{store: &fleets{fleets: make(map[string]pocket.Fleet)}}
+}
+
+func (r FleetsRepo) Get(name string) (f pocket.Fleet, exists bool, err error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	f, ok := r.store.fleets[name]
+	if !ok {
+		return pocket.Fleet{}, false, nil
+	}
+
+	return copyFleet(f), true, nil
+}
+
+func (r FleetsRepo) Set(f pocket.Fleet) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.fleets[f.Name] = copyFleet(f)
+	return nil
+}
+
+func (r FleetsRepo) Delete(name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.fleets, name)
+	return nil
+}
+
+// List returns every fleet, ordered by name.
+func (r FleetsRepo) List() ([]pocket.Fleet, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	list := make([]pocket.Fleet, 0, len(r.store.fleets))
+	for _, f := range r.store.fleets {
+		list = append(list, copyFleet(f))
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list, nil
+}
+
+// copyFleet keeps callers from changing stored addresses through a shared slice.
+func copyFleet(f pocket.Fleet) pocket.Fleet {
+	f.Addresses = append([]string(nil), f.Addresses...)
+	return f
+}
@@ -0,0 +1,59 @@
+package inmem
+
+import (
+	"sync"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+)
+
+type paramsKey struct {
+	name   string
+	height int64
+}
+
+type params struct {
+	mu     sync.RWMutex
+	params map[paramsKey]pocket.Params
+}
+
+// ParamsRepo keeps network params in memory. Copies share the same data.
+type ParamsRepo struct {
+	*store.ParamsIntervals
+	store *params
+}
+
+func NewParamsRepo() ParamsRepo {
+	return ParamsRepo{
+		ParamsIntervals: store.NewParamsIntervals(memoryIntervals{}),
+		store:           &params{params: make(map[paramsKey]pocket.Params)},
+	}
+}
+
+func (r ParamsRepo) Get(name string, height int64) (p pocket.Params, exists bool, err error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.params[paramsKey{name: name, height: height}]
+	if !ok {
+		return pocket.Params{}, false, nil
+	}
+
+	return p, true, nil
+}
+
+func (r ParamsRepo) Set(name string, height int64, p pocket.Params) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.params[paramsKey{name: name, height: height}] = p
+	return nil
+}
+
+// memoryIntervals doesn't persist anything: store.ParamsIntervals already holds every interval
+// in memory, so there is nothing to load.
+type memoryIntervals struct{}
+
+func (memoryIntervals) LoadIntervals() ([]store.ParamsInterval, error) { return nil, nil }
+func (memoryIntervals) PutInterval(store.ParamsInterval) error         { return nil }
+func (memoryIntervals) DeleteInterval(uint) error                      { return nil }
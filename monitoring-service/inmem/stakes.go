@@ -0,0 +1,53 @@
+package inmem
+
+import (
+	"fmt"
+	"sync"
+
+	"monitoring-service/store"
+)
+
+type stakeKey struct {
+	address string
+	height  uint
+}
+
+type stakes struct {
+	mu     sync.RWMutex
+	stakes map[stakeKey]uint
+}
+
+// StakesRepo keeps the stake of a node per height in memory. Copies share the same data.
+type StakesRepo struct {
+	store *stakes
+}
+
+func NewStakesRepo() StakesRepo {
+	return StakesRepo{store: &stakes{stakes: make(map[stakeKey]uint)}}
+}
+
+func (r StakesRepo) Get(address string, height uint) (stake uint, exists bool, err error) {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return 0, false, fmt.Errorf("StakesRepo.Get: %s", err)
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	stake, exists = r.store.stakes[stakeKey{address: addr, height: height}]
+	return stake, exists, nil
+}
+
+func (r StakesRepo) Set(address string, height uint, stake uint) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("StakesRepo.Set: %s", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.stakes[stakeKey{address: addr, height: height}] = stake
+	return nil
+}
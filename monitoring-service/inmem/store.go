@@ -0,0 +1,31 @@
+// Package inmem implements the store repositories in memory, for running without a DB.
+// Nothing is kept after the process exits.
+package inmem
+
+import "monitoring-service/store"
+
+// Store holds every repository in memory.
+type Store struct {
+	blockTimes   BlockTimesRepo
+	params       ParamsRepo
+	transactions TransactionsRepo
+	stakes       StakesRepo
+	fleets       FleetsRepo
+}
+
+func NewStore() *Store {
+	return &Store{
+		blockTimes:   NewBlockTimesRepo(),
+		params:       NewParamsRepo(),
+		transactions: NewTransactionsRepo(),
+		stakes:       NewStakesRepo(),
+		fleets:       NewFleetsRepo(),
+	}
+}
+
+func (s *Store) BlockTimes() store.BlockTimesRepo     { return s.blockTimes }
+func (s *Store) Params() store.ParamsRepo             { return s.params }
+func (s *Store) Transactions() store.TransactionsRepo { return s.transactions }
+func (s *Store) Stakes() store.StakesRepo             { return s.stakes }
+func (s *Store) Fleets() store.FleetsRepo             { return s.fleets }
+func (s *Store) Close() error                         { return nil }
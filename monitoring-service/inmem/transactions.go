@@ -0,0 +1,178 @@
+package inmem
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+)
+
+type transactions struct {
+	mu          sync.RWMutex
+	txs         map[string]map[string]pocket.Transaction // by address, then hash
+	checkpoints map[string]uint
+}
+
+// TransactionsRepo keeps enriched transactions per account in memory. Copies share the same data.
+type TransactionsRepo struct {
+	store *transactions
+}
+
+func NewTransactionsRepo() TransactionsRepo {
+	return TransactionsRepo{store: &transactions{
+		txs:         make(map[string]map[string]pocket.Transaction),
+		checkpoints: make(map[string]uint),
+	}}
+}
+
+// AccountTransactions returns every stored transaction for address, newest first.
+func (r TransactionsRepo) AccountTransactions(address string) ([]pocket.Transaction, error) {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions: %s", err)
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	byHash := r.store.txs[addr]
+	txs := make([]pocket.Transaction, 0, len(byHash))
+	for _, tx := range byHash {
+		txs = append(txs, tx)
+	}
+
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].Height != txs[j].Height {
+			return txs[i].Height > txs[j].Height
+		}
+		return txs[i].Hash < txs[j].Hash
+	})
+
+	return txs, nil
+}
+
+// SetTransactions stores txs for address, replacing any stored transaction with the same hash.
+func (r TransactionsRepo) SetTransactions(address string, txs []pocket.Transaction) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.SetTransactions: %s", err)
+	}
+
+	hashes := make([]string, len(txs))
+	for i, tx := range txs {
+		if hashes[i], err = store.NormalizeHex(tx.Hash); err != nil {
+			return fmt.Errorf("TransactionsRepo.SetTransactions: %s", err)
+		}
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	byHash, ok := r.store.txs[addr]
+	if !ok {
+		byHash = make(map[string]pocket.Transaction)
+		r.store.txs[addr] = byHash
+	}
+	for i, tx := range txs {
+		byHash[hashes[i]] = tx
+	}
+
+	return nil
+}
+
+// DeleteTransactionsAbove removes the stored transactions for address with a height greater than height.
+func (r TransactionsRepo) DeleteTransactionsAbove(address string, height uint) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove: %s", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for hash, tx := range r.store.txs[addr] {
+		if tx.Height > height {
+			delete(r.store.txs[addr], hash)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceTransactionsAbove removes the stored transactions for address with a height greater than
+// height and stores txs, as one change.
+func (r TransactionsRepo) ReplaceTransactionsAbove(address string, height uint, txs []pocket.Transaction) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %s", err)
+	}
+
+	hashes := make([]string, len(txs))
+	for i, tx := range txs {
+		if hashes[i], err = store.NormalizeHex(tx.Hash); err != nil {
+			return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %s", err)
+		}
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	byHash, ok := r.store.txs[addr]
+	if !ok {
+		byHash = make(map[string]pocket.Transaction)
+		r.store.txs[addr] = byHash
+	}
+	for hash, tx := range byHash {
+		if tx.Height > height {
+			delete(byHash, hash)
+		}
+	}
+	for i, tx := range txs {
+		byHash[hashes[i]] = tx
+	}
+
+	return nil
+}
+
+// Checkpoint returns the height up to which transactions for address have been indexed.
+func (r TransactionsRepo) Checkpoint(address string) (height uint, exists bool, err error) {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return 0, false, fmt.Errorf("TransactionsRepo.Checkpoint: %s", err)
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	height, exists = r.store.checkpoints[addr]
+	return height, exists, nil
+}
+
+func (r TransactionsRepo) SetCheckpoint(address string, height uint) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.SetCheckpoint: %s", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.checkpoints[addr] = height
+	return nil
+}
+
+// Addresses returns the addresses with a checkpoint, in lower case and sorted.
+func (r TransactionsRepo) Addresses() ([]string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	addresses := make([]string, 0, len(r.store.checkpoints))
+	for addr := range r.store.checkpoints {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultError = "error"
+)
+
+// InstrumentBlockTimes counts the lookups of r in lookups, with the label repo="block_times".
+func InstrumentBlockTimes(r store.BlockTimesRepo, lookups metrics.Counter) store.BlockTimesRepo {
+	return blockTimesRepo{BlockTimesRepo: r, lookups: lookups.With("repo", "block_times")}
+}
+
+type blockTimesRepo struct {
+	store.BlockTimesRepo
+	lookups metrics.Counter
+}
+
+func (r blockTimesRepo) Get(height uint) (time.Time, bool, error) {
+	t, exists, err := r.BlockTimesRepo.Get(height)
+	r.lookups.With("result", lookupResult(exists, err)).Add(1)
+	return t, exists, err
+}
+
+// InstrumentParams counts the lookups of r in lookups, with the label repo="params".
+func InstrumentParams(r store.ParamsRepo, lookups metrics.Counter) store.ParamsRepo {
+	return paramsRepo{ParamsRepo: r, lookups: lookups.With("repo", "params")}
+}
+
+type paramsRepo struct {
+	store.ParamsRepo
+	lookups metrics.Counter
+}
+
+func (r paramsRepo) Get(name string, height int64) (pocket.Params, bool, error) {
+	p, exists, err := r.ParamsRepo.Get(name, height)
+	r.lookups.With("result", lookupResult(exists, err)).Add(1)
+	return p, exists, err
+}
+
+func (r paramsRepo) GetAll(height int64) (pocket.AllParams, bool, error) {
+	p, exists, err := r.ParamsRepo.GetAll(height)
+	r.lookups.With("result", lookupResult(exists, err)).Add(1)
+	return p, exists, err
+}
+
+func lookupResult(exists bool, err error) string {
+	switch {
+	case err != nil:
+		return resultError
+	case exists:
+		return resultHit
+	default:
+		return resultMiss
+	}
+}
@@ -0,0 +1,91 @@
+// Package metrics defines the Prometheus metrics of the monitoring service itself, served on
+// /metrics, and the middlewares that record them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pokt_monitoring"
+
+// Path is where the metrics are served.
+const Path = "/metrics"
+
+// Metrics are the instruments of the service. New registers them with the default Prometheus
+// registry, so it must only be called once per process.
+type Metrics struct {
+	// RPCDuration is the latency of upstream calls, by RPC path and HTTP status ("error" when no
+	// response was received).
+	RPCDuration metrics.Histogram
+	// CacheLookups counts lookups in the block times and params caches, by repo and result
+	// (hit, miss or error).
+	CacheLookups metrics.Counter
+	// HTTPRequests and HTTPDuration cover the service's own routes, by method, route and status.
+	HTTPRequests metrics.Counter
+	HTTPDuration metrics.Histogram
+	// IndexerLag is the number of blocks an indexed address's checkpoint is behind the tip, and
+	// IndexerLastSuccess the unix time of its last successful run.
+	IndexerLag         metrics.Gauge
+	IndexerLastSuccess metrics.Gauge
+	// FollowerLag is the number of blocks the cache is behind the tip, as of the follower's last poll.
+	FollowerLag metrics.Gauge
+}
+
+func New() *Metrics {
+	return &Metrics{
+		RPCDuration: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "upstream",
+			Name:      "rpc_duration_seconds",
+			Help:      "Latency of upstream RPC calls.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"path", "status"}),
+		CacheLookups: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "lookups_total",
+			Help:      "Lookups in the block times and params caches.",
+		}, []string{"repo", "result"}),
+		HTTPRequests: kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Requests served, by route.",
+		}, []string{"method", "route", "status"}),
+		HTTPDuration: kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to serve requests, by route.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		IndexerLag: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "indexer",
+			Name:      "lag_blocks",
+			Help:      "Blocks the checkpoint of an indexed address is behind the tip.",
+		}, []string{"address"}),
+		IndexerLastSuccess: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "indexer",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful indexer run for an address.",
+		}, []string{"address"}),
+		FollowerLag: kitprometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "follower",
+			Name:      "lag_blocks",
+			Help:      "Blocks the cache is behind the tip.",
+		}, []string{}),
+	}
+}
+
+// Handler serves the metrics of the default registry, which include the Go runtime's.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,78 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"monitoring-service/pocket"
+)
+
+// ClaimsStatus returns the claims for address that have no successful proof. Claims that are
+// past their expire height are expired, and claims whose proof failed can't be paid out either,
+// so both count towards the POKT lost. The rest are pending, with an estimate of when they expire.
+// Claims that failed themselves are left out, as there was never anything to prove.
+func (s *Service) ClaimsStatus(ctx context.Context, address string) (pocket.ClaimsStatus, error) {
+	claims, proofs, err := s.AccountClaimsAndProofs(ctx, address)
+	if err != nil {
+		return pocket.ClaimsStatus{}, fmt.Errorf("ClaimsStatus: %w", err)
+	}
+
+	status, err := s.ClaimsStatusOf(ctx, claims, proofs)
+	if err != nil {
+		return pocket.ClaimsStatus{}, fmt.Errorf("ClaimsStatus: %w", err)
+	}
+
+	return status, nil
+}
+
+// ClaimsStatusOf is ClaimsStatus for claims and proofs already returned by AccountClaimsAndProofs,
+// for callers that need them too and shouldn't page the history twice.
+func (s *Service) ClaimsStatusOf(ctx context.Context, claims, proofs map[string]pocket.Transaction) (pocket.ClaimsStatus, error) {
+	tip, err := s.chainTip(ctx)
+	if err != nil {
+		return pocket.ClaimsStatus{}, fmt.Errorf("ClaimsStatusOf: %w", err)
+	}
+	height := tip.height
+
+	status := pocket.ClaimsStatus{
+		Height:    height,
+		BlockTime: tip.interval,
+	}
+	for sessionKey, claim := range claims {
+		if claim.ResultCode != 0 {
+			continue
+		}
+
+		c := pocket.UnprovenClaim{Claim: claim}
+		if proof, exists := proofs[sessionKey]; exists {
+			if proof.ResultCode == 0 {
+				continue
+			}
+			c.Proof = &proof
+			status.FailedProofs = append(status.FailedProofs, c)
+			continue
+		}
+
+		if claim.ExpireHeight <= height {
+			status.Expired = append(status.Expired, c)
+			continue
+		}
+
+		expiry, err := s.estimateBlockTime(tip, claim.ExpireHeight)
+		if err != nil {
+			return pocket.ClaimsStatus{}, fmt.Errorf("ClaimsStatusOf: %w", err)
+		}
+		c.BlocksUntilExpiry = claim.ExpireHeight - height
+		c.EstimatedExpiresAt = expiry.Time
+		status.Pending = append(status.Pending, c)
+	}
+
+	for _, list := range [][]pocket.UnprovenClaim{status.Pending, status.Expired, status.FailedProofs} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Claim.Height > list[j].Claim.Height
+		})
+	}
+
+	return status, nil
+}
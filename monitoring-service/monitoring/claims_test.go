@@ -0,0 +1,85 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+
+	"monitoring-service/pocket"
+)
+
+func TestClaimsStatusOf(t *testing.T) {
+	const session = "session"
+
+	// the chain's tip is at 1014
+	tests := []struct {
+		name  string
+		claim pocket.Transaction
+		proof *pocket.Transaction
+		want  string
+	}{
+		{"proven", pocket.Transaction{Height: 1000, ExpireHeight: 1120}, &pocket.Transaction{Height: 1005}, ""},
+		{"failed proof", pocket.Transaction{Height: 1000, ExpireHeight: 1120}, &pocket.Transaction{Height: 1005, ResultCode: 1}, "failed proof"},
+		{"expired", pocket.Transaction{Height: 800, ExpireHeight: 920}, nil, "expired"},
+		{"expires at the tip", pocket.Transaction{Height: 894, ExpireHeight: 1014}, nil, "expired"},
+		{"pending", pocket.Transaction{Height: 1000, ExpireHeight: 1120}, nil, "pending"},
+		{"failed claim", pocket.Transaction{Height: 1000, ExpireHeight: 1120, ResultCode: 1}, nil, ""},
+		{"failed and expired claim", pocket.Transaction{Height: 800, ExpireHeight: 920, ResultCode: 1}, nil, ""},
+	}
+
+	svc := NewService(newFakeChain(), 1, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.claim.NumRelays, tt.claim.PoktPerRelay = 100, 0.01
+			claims := map[string]pocket.Transaction{session: tt.claim}
+			proofs := map[string]pocket.Transaction{}
+			if tt.proof != nil {
+				proofs[session] = *tt.proof
+			}
+
+			status, err := svc.ClaimsStatusOf(context.Background(), claims, proofs)
+			if err != nil {
+				t.Fatalf("ClaimsStatusOf: %v", err)
+			}
+
+			got := ""
+			for list, claims := range map[string][]pocket.UnprovenClaim{"pending": status.Pending, "expired": status.Expired, "failed proof": status.FailedProofs} {
+				if len(claims) > 0 {
+					if got != "" {
+						t.Errorf("claim is both %s and %s", got, list)
+					}
+					got = list
+				}
+			}
+			if got != tt.want {
+				t.Errorf("claim is %q, want %q", got, tt.want)
+			}
+
+			wantLost := 0.0
+			if tt.want == "expired" || tt.want == "failed proof" {
+				wantLost = 1
+			}
+			if lost := status.LostPoktAmount(); lost != wantLost {
+				t.Errorf("LostPoktAmount: %g, want %g", lost, wantLost)
+			}
+		})
+	}
+}
+
+func TestClaimsStatusOfPendingExpiry(t *testing.T) {
+	svc := NewService(newFakeChain(), 1, nil)
+	claims := map[string]pocket.Transaction{"session": {Height: 1000, ExpireHeight: 1020}}
+
+	status, err := svc.ClaimsStatusOf(context.Background(), claims, nil)
+	if err != nil {
+		t.Fatalf("ClaimsStatusOf: %v", err)
+	}
+	if len(status.Pending) != 1 || status.Height != 1014 {
+		t.Fatalf("ClaimsStatusOf: %+v, want one pending claim at height 1014", status)
+	}
+
+	// blocks are a minute apart, so the claim expires 6 minutes after the tip
+	c := status.Pending[0]
+	if want := secs(1014*60 + 6*60); c.BlocksUntilExpiry != 6 || !c.EstimatedExpiresAt.Equal(want) {
+		t.Errorf("pending claim expires in %d blocks at %s, want 6 at %s", c.BlocksUntilExpiry, c.EstimatedExpiresAt, want)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"sort"
 	"time"
 
-	"gopkg.in/errgo.v2/fmt/errors"
+	"monitoring-service/api"
 
 	"github.com/go-kit/kit/endpoint"
 )
@@ -20,6 +20,11 @@ type Endpoints struct {
 	AccountTransactions endpoint.Endpoint
 	BlockTimes          endpoint.Endpoint
 	MonthlyRewards      endpoint.Endpoint
+	PeriodRewards       endpoint.Endpoint
+	PendingClaims       endpoint.Endpoint
+	Forecast            endpoint.Endpoint
+	EstimateBlockTimes  endpoint.Endpoint
+	TimeToHeight        endpoint.Endpoint
 }
 
 type heightResponse struct {
@@ -28,9 +33,9 @@ type heightResponse struct {
 
 func HeightEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		height, err := svc.Height()
+		height, err := svc.Height(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("HeightEndpoint: %s", err)
+			return nil, fmt.Errorf("HeightEndpoint: %w", err)
 		}
 
 		response = heightResponse{Height: height}
@@ -39,7 +44,8 @@ func HeightEndpoint(svc Service) endpoint.Endpoint {
 }
 
 type monthlyRewardsRequest struct {
-	Address string `json:"address"'`
+	Address  string `json:"address"`
+	Location *time.Location
 }
 
 type monthlyRewardsResponse struct {
@@ -52,6 +58,7 @@ type monthlyRewardsResponse struct {
 	TotalSecBetweenRewards float64                    `json:"total_sec_between_rewards"`
 	Transactions           []transactionResponse      `json:"transactions"`
 	DaysOfWeek             map[int]daysOfWeekResponse `json:"days_of_week"`
+	HoursOfDay             [24]uint                   `json:"hours_of_day"`
 }
 
 type daysOfWeekResponse struct {
@@ -68,7 +75,7 @@ type relaysByChain struct {
 func MonthlyRewardsEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("MonthlyRewardsEndpoint: %s", err)
+			return nil, fmt.Errorf("MonthlyRewardsEndpoint: %w", err)
 		}
 
 		req, ok := request.(monthlyRewardsRequest)
@@ -77,7 +84,7 @@ func MonthlyRewardsEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		months, err := svc.RewardsByMonth(req.Address)
+		months, err := svc.RewardsByMonth(ctx, req.Address, req.Location)
 		if err != nil {
 			return fail(err)
 		}
@@ -94,6 +101,7 @@ func MonthlyRewardsEndpoint(svc Service) endpoint.Endpoint {
 				AvgSecBetweenRewards:   month.AvgSecsBetweenRewards,
 				TotalSecBetweenRewards: month.TotalSecsBetweenRewards,
 				Transactions:           make([]transactionResponse, len(month.Transactions)),
+				HoursOfDay:             month.HoursOfDay,
 			}
 
 			byChain := make(map[string]uint, 0)
@@ -123,21 +131,7 @@ func MonthlyRewardsEndpoint(svc Service) endpoint.Endpoint {
 				}
 			}
 
-			for ch, num := range byChain {
-				byChainResp := relaysByChain{
-					Chain:     ch,
-					NumRelays: num,
-				}
-
-				chain, err := pocket.ChainFromID(ch)
-				if err != nil {
-					byChainResp.Name = ch
-				} else {
-					byChainResp.Name = chain.Name
-				}
-
-				resp[i].RelaysByChain = append(resp[i].RelaysByChain, byChainResp)
-			}
+			resp[i].RelaysByChain = relaysByChainResponse(byChain)
 
 			resp[i].DaysOfWeek = make(map[int]daysOfWeekResponse, len(month.DaysOfWeek))
 			for j, d := range month.DaysOfWeek {
@@ -160,6 +154,100 @@ func MonthlyRewardsEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+type periodRewardsRequest struct {
+	Address     string
+	Granularity pocket.Granularity
+	From        time.Time
+	To          time.Time
+	Location    *time.Location
+}
+
+type periodRewardsResponse struct {
+	Period                 pocket.Granularity `json:"period"`
+	Key                    string             `json:"key"`
+	Start                  time.Time          `json:"start"`
+	End                    time.Time          `json:"end"`
+	NumRelays              uint               `json:"num_relays"`
+	PoktAmount             float64            `json:"pokt_amount"`
+	NumTransactions        int                `json:"num_transactions"`
+	RelaysByChain          []relaysByChain    `json:"relays_by_chain"`
+	AvgSecBetweenRewards   float64            `json:"avg_sec_between_rewards"`
+	TotalSecBetweenRewards float64            `json:"total_sec_between_rewards"`
+}
+
+func PeriodRewardsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("PeriodRewardsEndpoint: %w", err)
+		}
+
+		req, ok := request.(periodRewardsRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		periods, err := svc.RewardsByPeriod(ctx, req.Address, req.Granularity, req.From, req.To, req.Location)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := make([]periodRewardsResponse, len(periods))
+		for i, period := range periods {
+			resp[i] = periodRewardsResponse{
+				Period:                 period.Granularity,
+				Key:                    period.Key,
+				Start:                  period.Start,
+				End:                    period.End,
+				NumRelays:              period.TotalProofs,
+				PoktAmount:             period.PoktAmount(),
+				NumTransactions:        len(period.Transactions),
+				RelaysByChain:          relaysByChainResponse(period.RelaysByChain),
+				AvgSecBetweenRewards:   period.AvgSecsBetweenRewards,
+				TotalSecBetweenRewards: period.TotalSecsBetweenRewards,
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+// RewardsEndpoint serves period rewards when a period was requested, and monthly rewards otherwise.
+func RewardsEndpoint(svc Service) endpoint.Endpoint {
+	monthly, byPeriod := MonthlyRewardsEndpoint(svc), PeriodRewardsEndpoint(svc)
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		if _, ok := request.(periodRewardsRequest); ok {
+			return byPeriod(ctx, request)
+		}
+		return monthly(ctx, request)
+	}
+}
+
+func relaysByChainResponse(byChain map[string]uint) []relaysByChain {
+	resp := make([]relaysByChain, 0, len(byChain))
+	for ch, num := range byChain {
+		byChainResp := relaysByChain{
+			Chain:     ch,
+			NumRelays: num,
+		}
+
+		chain, err := pocket.ChainFromID(ch)
+		if err != nil {
+			byChainResp.Name = ch
+		} else {
+			byChainResp.Name = chain.Name
+		}
+
+		resp = append(resp, byChainResp)
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Chain < resp[j].Chain
+	})
+
+	return resp
+}
+
 type blockTimesRequest struct {
 	Heights []uint `json:"heights"`
 }
@@ -169,7 +257,7 @@ type blockTimesResponse map[uint]time.Time
 func BlockTimesEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("BlockTimesEndpoint: %s", err)
+			return nil, fmt.Errorf("BlockTimesEndpoint: %w", err)
 		}
 
 		req, ok := request.(blockTimesRequest)
@@ -178,7 +266,7 @@ func BlockTimesEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		blocks, err := svc.BlockTimes(req.Heights)
+		blocks, err := svc.BlockTimes(ctx, req.Heights)
 		if err != nil {
 			return fail(err)
 		}
@@ -199,7 +287,7 @@ type paramsResponse struct {
 func ParamsEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("ParamsEndpoint: %s", err)
+			return nil, fmt.Errorf("ParamsEndpoint: %w", err)
 		}
 
 		req, ok := request.(paramsRequest)
@@ -208,7 +296,7 @@ func ParamsEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		params, err := svc.ParamsAtHeight(req.Height, req.ForceRefresh)
+		params, err := svc.ParamsAtHeight(ctx, req.Height, req.ForceRefresh)
 		if err != nil {
 			return fail(err)
 		}
@@ -239,7 +327,7 @@ type transactionResponse struct {
 func TransactionEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("TransactionEndpoint: %s", err)
+			return nil, fmt.Errorf("TransactionEndpoint: %w", err)
 		}
 
 		req, ok := request.(transactionRequest)
@@ -248,7 +336,7 @@ func TransactionEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		txn, err := svc.Transaction(req.Hash)
+		txn, err := svc.Transaction(ctx, req.Hash)
 		if err != nil {
 			return fail(err)
 		}
@@ -277,7 +365,7 @@ type accountTransactionsResponse []transactionResponse
 func AccountTransactionsEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("AccountTransactionsEndpoint: %s", err)
+			return nil, fmt.Errorf("AccountTransactionsEndpoint: %w", err)
 		}
 
 		req, ok := request.(accountTransactionsRequest)
@@ -286,7 +374,7 @@ func AccountTransactionsEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		txs, err := svc.AccountTransactions(req.Address, req.Page, req.PerPage, req.Sort)
+		txs, err := svc.AccountTransactions(ctx, req.Address, req.Page, req.PerPage, req.Sort)
 		if err != nil {
 			return fail(err)
 		}
@@ -319,15 +407,15 @@ type relayRequest struct {
 
 func (req relayRequest) validate() error {
 	if req.ServicerURL == "" {
-		return errors.Newf("relayRequest.validate: Missing required param 'servicer_url")
+		return api.InvalidArgument("relayRequest.validate: Missing required param 'servicer_url'", api.Details{"param": "servicer_url"})
 	}
 
 	if req.ChainID == "" {
-		return errors.Newf("relayRequest.validate: Missing required param 'chain_id")
+		return api.InvalidArgument("relayRequest.validate: Missing required param 'chain_id'", api.Details{"param": "chain_id"})
 	}
 
 	if req.Payload == nil {
-		return errors.Newf("relayRequest.validate: Missing required param 'payload")
+		return api.InvalidArgument("relayRequest.validate: Missing required param 'payload'", api.Details{"param": "payload"})
 	}
 
 	return nil
@@ -336,7 +424,7 @@ func (req relayRequest) validate() error {
 func SimulateRelayEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("SimulateRelayEndpoint: %s", err)
+			return nil, fmt.Errorf("SimulateRelayEndpoint: %w", err)
 		}
 
 		req, ok := request.(relayRequest)
@@ -349,7 +437,7 @@ func SimulateRelayEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		res, err := svc.SimulateRelay(req.ServicerURL, req.ChainID, req.Payload)
+		res, err := svc.SimulateRelay(ctx, req.ServicerURL, req.ChainID, req.Payload)
 		if err != nil {
 			return fail(err)
 		}
@@ -373,6 +461,11 @@ type nodeResponse struct {
 	IsSynced          bool            `json:"is_synced"`
 	LatestBlockHeight uint            `json:"latest_block_height"`
 	LatestBlockTime   time.Time       `json:"latest_block_time"`
+	IsReachable       bool            `json:"is_reachable"`
+	RPCError          string          `json:"rpc_error,omitempty"`
+	NetworkHeight     uint            `json:"network_height"`
+	LagBlocks         uint            `json:"lag_blocks"`
+	LagSeconds        float64         `json:"lag_seconds"`
 }
 
 type chainResponse struct {
@@ -383,7 +476,7 @@ type chainResponse struct {
 func NodeEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		fail := func(err error) (interface{}, error) {
-			return nil, fmt.Errorf("NodeEndpoint: %s", err)
+			return nil, fmt.Errorf("NodeEndpoint: %w", err)
 		}
 
 		req, ok := request.(nodeRequest)
@@ -392,7 +485,7 @@ func NodeEndpoint(svc Service) endpoint.Endpoint {
 			return fail(err)
 		}
 
-		node, err := svc.Node(req.Address)
+		node, err := svc.Node(ctx, req.Address)
 		if err != nil {
 			return fail(err)
 		}
@@ -416,6 +509,405 @@ func NodeEndpoint(svc Service) endpoint.Endpoint {
 			IsSynced:          node.IsSynced,
 			LatestBlockHeight: node.LatestBlockHeight,
 			LatestBlockTime:   node.LatestBlockTime,
+			IsReachable:       node.IsReachable,
+			RPCError:          node.RPCError,
+			NetworkHeight:     node.NetworkHeight,
+			LagBlocks:         node.LagBlocks,
+			LagSeconds:        node.LagSeconds,
+		}, nil
+	}
+}
+
+type claimsStatusResponse struct {
+	Height            uint                    `json:"height"`
+	AvgBlockTimeSec   float64                 `json:"avg_block_time_sec"`
+	NumPending        int                     `json:"num_pending"`
+	NumExpired        int                     `json:"num_expired"`
+	NumFailedProofs   int                     `json:"num_failed_proofs"`
+	PendingPoktAmount float64                 `json:"pending_pokt_amount"`
+	LostPoktAmount    float64                 `json:"lost_pokt_amount"`
+	Pending           []unprovenClaimResponse `json:"pending"`
+	Expired           []unprovenClaimResponse `json:"expired"`
+	FailedProofs      []unprovenClaimResponse `json:"failed_proofs"`
+}
+
+type unprovenClaimResponse struct {
+	Claim              transactionResponse  `json:"claim"`
+	Proof              *transactionResponse `json:"proof,omitempty"`
+	PoktAmount         float64              `json:"pokt_amount"`
+	BlocksUntilExpiry  uint                 `json:"blocks_until_expiry"`
+	EstimatedExpiresAt *time.Time           `json:"estimated_expires_at,omitempty"`
+}
+
+func PendingClaimsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("PendingClaimsEndpoint: %w", err)
+		}
+
+		req, ok := request.(nodeRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		status, err := svc.ClaimsStatus(ctx, req.Address)
+		if err != nil {
+			return fail(err)
+		}
+
+		return claimsStatusResponse{
+			Height:            status.Height,
+			AvgBlockTimeSec:   status.BlockTime.Seconds(),
+			NumPending:        len(status.Pending),
+			NumExpired:        len(status.Expired),
+			NumFailedProofs:   len(status.FailedProofs),
+			PendingPoktAmount: status.PendingPoktAmount(),
+			LostPoktAmount:    status.LostPoktAmount(),
+			Pending:           unprovenClaimsResponse(status.Pending),
+			Expired:           unprovenClaimsResponse(status.Expired),
+			FailedProofs:      unprovenClaimsResponse(status.FailedProofs),
+		}, nil
+	}
+}
+
+func unprovenClaimsResponse(claims []pocket.UnprovenClaim) []unprovenClaimResponse {
+	resp := make([]unprovenClaimResponse, len(claims))
+	for i, c := range claims {
+		resp[i] = unprovenClaimResponse{
+			Claim:             claimTransactionResponse(c.Claim),
+			PoktAmount:        c.Claim.PoktAmount(),
+			BlocksUntilExpiry: c.BlocksUntilExpiry,
+		}
+		if c.Proof != nil {
+			proof := claimTransactionResponse(*c.Proof)
+			resp[i].Proof = &proof
+		}
+		if !c.EstimatedExpiresAt.IsZero() {
+			expiresAt := c.EstimatedExpiresAt
+			resp[i].EstimatedExpiresAt = &expiresAt
+		}
+	}
+	return resp
+}
+
+func claimTransactionResponse(tx pocket.Transaction) transactionResponse {
+	chain, _ := tx.Chain()
+	return transactionResponse{
+		Hash:    tx.Hash,
+		Height:  tx.Height,
+		Time:    tx.Time,
+		Type:    tx.Type,
+		ChainID: tx.ChainID,
+		Chain: chainResponse{
+			Name: chain.Name,
+			ID:   chain.ID,
+		},
+		SessionHeight: tx.SessionHeight,
+		ExpireHeight:  tx.ExpireHeight,
+		AppPubkey:     tx.AppPubkey,
+		NumRelays:     tx.NumRelays,
+		PoktPerRelay:  tx.PoktPerRelay,
+	}
+}
+
+type forecastRequest struct {
+	Address      string
+	LookbackDays int
+	Overrides    pocket.ParamOverrides
+}
+
+type forecastResponse struct {
+	From               time.Time            `json:"from"`
+	To                 time.Time            `json:"to"`
+	NumDays            int                  `json:"num_days"`
+	Params             forecastParams       `json:"params"`
+	DailyRelays        float64              `json:"daily_relays"`
+	DailyRelaysStdDev  float64              `json:"daily_relays_std_dev"`
+	DailyRelaysByChain []relayRateByChain   `json:"daily_relays_by_chain"`
+	Projections        []projectionResponse `json:"projections"`
+}
+
+type forecastParams struct {
+	RelaysToTokensMultiplier float64 `json:"relays_to_tokens_multiplier"`
+	DaoAllocation            uint8   `json:"dao_allocation"`
+	ProposerPercentage       uint8   `json:"proposer_percentage"`
+	StakeWeight              float64 `json:"stake_weight"`
+	PoktPerRelay             float64 `json:"pokt_per_relay"`
+	IsOverridden             bool    `json:"is_overridden"`
+}
+
+type relayRateByChain struct {
+	Chain     string  `json:"chain"`
+	Name      string  `json:"name"`
+	NumRelays float64 `json:"num_relays"`
+}
+
+type projectionResponse struct {
+	Days          int                `json:"days"`
+	NumRelays     float64            `json:"num_relays"`
+	NumRelaysLow  float64            `json:"num_relays_low"`
+	NumRelaysHigh float64            `json:"num_relays_high"`
+	PoktAmount    float64            `json:"pokt_amount"`
+	PoktLow       float64            `json:"pokt_low"`
+	PoktHigh      float64            `json:"pokt_high"`
+	RelaysByChain []relayRateByChain `json:"relays_by_chain"`
+}
+
+func ForecastEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("ForecastEndpoint: %w", err)
+		}
+
+		req, ok := request.(forecastRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		forecast, err := svc.Forecast(ctx, req.Address, req.LookbackDays, req.Overrides)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := forecastResponse{
+			From:    forecast.From,
+			To:      forecast.To,
+			NumDays: forecast.NumDays,
+			Params: forecastParams{
+				RelaysToTokensMultiplier: forecast.Params.RelaysToTokensMultiplier,
+				DaoAllocation:            forecast.Params.DaoAllocation,
+				ProposerPercentage:       forecast.Params.ProposerPercentage,
+				StakeWeight:              forecast.Params.StakeWeight(forecast.StakedBalance),
+				PoktPerRelay:             forecast.Params.PoktPerRelayForStake(forecast.StakedBalance),
+				IsOverridden:             !req.Overrides.IsEmpty(),
+			},
+			DailyRelays:        forecast.DailyRelays,
+			DailyRelaysStdDev:  forecast.DailyRelaysStdDev,
+			DailyRelaysByChain: relayRatesByChainResponse(forecast.DailyRelaysByChain),
+			Projections:        make([]projectionResponse, len(forecast.Projections)),
+		}
+		for i, p := range forecast.Projections {
+			resp.Projections[i] = projectionResponse{
+				Days:          p.Days,
+				NumRelays:     p.Relays,
+				NumRelaysLow:  p.RelaysLow,
+				NumRelaysHigh: p.RelaysHigh,
+				PoktAmount:    p.PoktAmount,
+				PoktLow:       p.PoktLow,
+				PoktHigh:      p.PoktHigh,
+				RelaysByChain: relayRatesByChainResponse(p.RelaysByChain),
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+func relayRatesByChainResponse(byChain map[string]float64) []relayRateByChain {
+	resp := make([]relayRateByChain, 0, len(byChain))
+	for ch, num := range byChain {
+		name := ch
+		if chain, err := pocket.ChainFromID(ch); err == nil {
+			name = chain.Name
+		}
+
+		resp = append(resp, relayRateByChain{
+			Chain:     ch,
+			Name:      name,
+			NumRelays: num,
+		})
+	}
+
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Chain < resp[j].Chain
+	})
+
+	return resp
+}
+
+type paramHistoryRequest struct {
+	Key  string
+	From uint
+	To   uint
+}
+
+type paramHistoryResponse struct {
+	Key     string                `json:"key"`
+	From    uint                  `json:"from"`
+	To      uint                  `json:"to"`
+	Changes []paramChangeResponse `json:"changes"`
+}
+
+// paramChangeResponse values are null where the param didn't exist.
+type paramChangeResponse struct {
+	Height        uint    `json:"height"`
+	Value         *string `json:"value"`
+	PreviousValue *string `json:"previous_value"`
+}
+
+func ParamHistoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("ParamHistoryEndpoint: %w", err)
+		}
+
+		req, ok := request.(paramHistoryRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		changes, err := svc.ParamHistory(ctx, req.Key, req.From, req.To)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := paramHistoryResponse{
+			Key:     req.Key,
+			From:    req.From,
+			To:      req.To,
+			Changes: make([]paramChangeResponse, len(changes)),
+		}
+		for i, c := range changes {
+			resp.Changes[i] = paramChangeResponse{
+				Height:        c.Height,
+				Value:         optionalParamValue(c.Value, c.Exists),
+				PreviousValue: optionalParamValue(c.PreviousValue, c.PreviousExists),
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+type paramsDiffRequest struct {
+	A uint
+	B uint
+}
+
+type paramsDiffResponse struct {
+	A     uint                `json:"a"`
+	B     uint                `json:"b"`
+	Diffs []paramDiffResponse `json:"diffs"`
+}
+
+// paramDiffResponse values are null where the param didn't exist.
+type paramDiffResponse struct {
+	Group string  `json:"group"`
+	Key   string  `json:"key"`
+	A     *string `json:"a"`
+	B     *string `json:"b"`
+}
+
+func ParamsDiffEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("ParamsDiffEndpoint: %w", err)
+		}
+
+		req, ok := request.(paramsDiffRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		diffs, err := svc.ParamsDiff(ctx, req.A, req.B)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := paramsDiffResponse{
+			A:     req.A,
+			B:     req.B,
+			Diffs: make([]paramDiffResponse, len(diffs)),
+		}
+		for i, d := range diffs {
+			resp.Diffs[i] = paramDiffResponse{
+				Group: d.Group,
+				Key:   d.Key,
+				A:     optionalParamValue(d.A, d.AExists),
+				B:     optionalParamValue(d.B, d.BExists),
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+func optionalParamValue(value string, exists bool) *string {
+	if !exists {
+		return nil
+	}
+	return &value
+}
+
+type estimateBlockTimesRequest struct {
+	Heights []uint
+}
+
+type blockEstimateResponse struct {
+	Height      uint      `json:"height"`
+	Time        time.Time `json:"time"`
+	IsEstimated bool      `json:"is_estimated"`
+}
+
+func EstimateBlockTimesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("EstimateBlockTimesEndpoint: %w", err)
+		}
+
+		req, ok := request.(estimateBlockTimesRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		estimates, err := svc.EstimateBlockTimes(ctx, req.Heights)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := make([]blockEstimateResponse, len(estimates))
+		for i, e := range estimates {
+			resp[i] = blockEstimateResponse{
+				Height:      e.Height,
+				Time:        e.Time,
+				IsEstimated: e.IsEstimated,
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+type timeToHeightRequest struct {
+	Time time.Time
+}
+
+func TimeToHeightEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("TimeToHeightEndpoint: %w", err)
+		}
+
+		req, ok := request.(timeToHeightRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		e, err := svc.TimeToHeight(ctx, req.Time)
+		if err != nil {
+			return fail(err)
+		}
+
+		return blockEstimateResponse{
+			Height:      e.Height,
+			Time:        e.Time,
+			IsEstimated: e.IsEstimated,
 		}, nil
 	}
 }
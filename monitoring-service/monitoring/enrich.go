@@ -0,0 +1,124 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"monitoring-service/pocket"
+)
+
+type heightDetail struct {
+	params pocket.Params
+	time   time.Time
+	// stakedBalance is the node's stake at the height, only looked up where rewards are weighted by it
+	stakedBalance uint
+}
+
+// enrichTransactions sets the block time, reward rate and expiry height on each transaction of the
+// node at address. Where the params at the tx's height weight rewards by stake, the reward rate is
+// weighted by the node's stake at that height, as its stake today may differ.
+// Lookups are made once per distinct height, at most s.concurrency at a time, and the
+// returned slice keeps the order of txs.
+func (s *Service) enrichTransactions(ctx context.Context, address string, txs []pocket.Transaction) ([]pocket.Transaction, error) {
+	heights := make([]uint, 0, len(txs))
+	seen := make(map[uint]bool, len(txs))
+	for _, tx := range txs {
+		if !seen[tx.Height] {
+			seen[tx.Height] = true
+			heights = append(heights, tx.Height)
+		}
+	}
+
+	details, err := s.heightDetails(ctx, address, heights)
+	if err != nil {
+		return nil, fmt.Errorf("enrichTransactions: %w", err)
+	}
+
+	transactions := make([]pocket.Transaction, len(txs))
+	for i, tx := range txs {
+		d := details[tx.Height]
+		tx.Time = d.time
+		tx.PoktPerRelay = d.params.PoktPerRelayForStake(d.stakedBalance)
+		tx.ExpireHeight = d.params.ClaimExpirationBlocks + tx.Height
+		transactions[i] = tx
+	}
+
+	return transactions, nil
+}
+
+// heightDetails fetches params, block times and the stake of the node at address for heights using
+// a bounded pool of workers. Each of them is cached by the provider, so a height is only fetched from
+// the network once. The first error cancels the remaining lookups.
+func (s *Service) heightDetails(ctx context.Context, address string, heights []uint) (map[uint]heightDetail, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	details := make(map[uint]heightDetail, len(heights))
+	sem := make(chan struct{}, s.concurrency)
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	for _, h := range heights {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(height uint) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			params, err := s.ParamsAtHeight(ctx, int64(height), false)
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			t, err := s.provider.BlockTime(ctx, height)
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			var stakedBalance uint
+			if params.IsStakeWeighted {
+				if stakedBalance, err = s.provider.StakeAtHeight(ctx, address, height); err != nil {
+					fail(err)
+					return
+				}
+			}
+
+			mu.Lock()
+			details[height] = heightDetail{params: params, time: t, stakedBalance: stakedBalance}
+			mu.Unlock()
+		}(h)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("heightDetails: %w", firstErr)
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("heightDetails: %w", ctx.Err())
+	}
+
+	return details, nil
+}
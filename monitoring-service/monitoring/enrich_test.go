@@ -0,0 +1,238 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"monitoring-service/pocket"
+)
+
+const (
+	weightedFrom = 150
+	upokt        = 1000000
+)
+
+// fakeProvider serves params that weight rewards by stake from weightedFrom, and a node whose
+// stake at a height is stakes[height]. Its other methods are left unimplemented.
+type fakeProvider struct {
+	PocketProvider
+
+	mu          sync.Mutex
+	stakes      map[uint]uint
+	stakeLookup []uint
+}
+
+func (f *fakeProvider) AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error) {
+	np := pocket.ParamGroup{
+		{Key: "pos/RelaysToTokensMultiplier", Value: "10000"},
+		{Key: "pos/DAOAllocation", Value: "10"},
+		{Key: "pos/ProposerPercentage", Value: "5"},
+	}
+	if height >= weightedFrom {
+		np = append(np,
+			pocket.Param{Key: "pos/ServicerStakeWeightMultiplier", Value: "2"},
+			pocket.Param{Key: "pos/ServicerStakeFloorMultiplier", Value: fmt.Sprint(15000 * upokt)},
+			pocket.Param{Key: "pos/ServicerStakeFloorMultiplierExponent", Value: "1"},
+			pocket.Param{Key: "pos/ServicerStakeWeightCeiling", Value: fmt.Sprint(60000 * upokt)},
+		)
+	}
+
+	return pocket.AllParams{
+		NodeParams:   np,
+		PocketParams: pocket.ParamGroup{{Key: "pocketcore/ClaimExpiration", Value: "120"}},
+	}, nil
+}
+
+func (f *fakeProvider) BlockTime(ctx context.Context, height uint) (time.Time, error) {
+	return time.Unix(int64(height)*900, 0), nil
+}
+
+func (f *fakeProvider) StakeAtHeight(ctx context.Context, address string, height uint) (uint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stakeLookup = append(f.stakeLookup, height)
+	return f.stakes[height], nil
+}
+
+func TestEnrichTransactionsUsesStakeAtHeight(t *testing.T) {
+	// the node staked 15k, doubled it at 300, and has 60k staked today
+	provider := &fakeProvider{stakes: map[uint]uint{0: 60000 * upokt, 200: 15000 * upokt, 300: 30000 * upokt}}
+	svc := NewService(provider, 2, nil)
+
+	txs, err := svc.enrichTransactions(context.Background(), "node", []pocket.Transaction{{Height: 100}, {Height: 200}, {Height: 300}})
+	if err != nil {
+		t.Fatalf("enrichTransactions: %v", err)
+	}
+
+	// 10000 uPOKT per relay less 15% for the DAO and proposer, then weighted by bin^1 / 2
+	unweighted := 0.0085
+	for i, want := range []float64{unweighted, unweighted / 2, unweighted} {
+		if got := txs[i].PoktPerRelay; math.Abs(got-want) > 1e-12 {
+			t.Errorf("PoktPerRelay at %d: %g, want %g", txs[i].Height, got, want)
+		}
+		if txs[i].ExpireHeight != txs[i].Height+120 {
+			t.Errorf("ExpireHeight at %d: %d, want %d", txs[i].Height, txs[i].ExpireHeight, txs[i].Height+120)
+		}
+	}
+
+	// the stake is only looked up where it weights rewards, and never the current one
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if len(provider.stakeLookup) != 2 {
+		t.Errorf("stake looked up at %v, want 200 and 300", provider.stakeLookup)
+	}
+	for _, h := range provider.stakeLookup {
+		if h == 0 || h == 100 {
+			t.Errorf("stake looked up at %d", h)
+		}
+	}
+}
+
+// countingProvider is a fakeProvider that counts the lookups made for each height, and how many
+// block times were being fetched at once. Each block time takes delay to fetch.
+type countingProvider struct {
+	fakeProvider
+	delay time.Duration
+
+	mu          sync.Mutex
+	params      map[int64]int
+	blockTimes  map[uint]int
+	inFlight    int
+	maxInFlight int
+}
+
+func newCountingProvider(delay time.Duration) *countingProvider {
+	return &countingProvider{delay: delay, params: map[int64]int{}, blockTimes: map[uint]int{}}
+}
+
+func (c *countingProvider) AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error) {
+	c.mu.Lock()
+	c.params[height]++
+	c.mu.Unlock()
+
+	return c.fakeProvider.AllParams(ctx, height, forceRefresh)
+}
+
+func (c *countingProvider) BlockTime(ctx context.Context, height uint) (time.Time, error) {
+	c.mu.Lock()
+	c.blockTimes[height]++
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+
+	return c.fakeProvider.BlockTime(ctx, height)
+}
+
+func TestEnrichTransactionsKeepsOrder(t *testing.T) {
+	provider := newCountingProvider(time.Millisecond)
+	svc := NewService(provider, 4, nil)
+
+	// heights out of order, so that lookups finish in a different order than they are listed
+	var txs []pocket.Transaction
+	for i := 0; i < 30; i++ {
+		h := uint(100 + (i*7)%30)
+		txs = append(txs, pocket.Transaction{Hash: fmt.Sprintf("tx%d", i), Height: h})
+	}
+
+	got, err := svc.enrichTransactions(context.Background(), "node", txs)
+	if err != nil {
+		t.Fatalf("enrichTransactions: %v", err)
+	}
+
+	if len(got) != len(txs) {
+		t.Fatalf("enrichTransactions returned %d txs, want %d", len(got), len(txs))
+	}
+	for i, tx := range got {
+		if tx.Hash != txs[i].Hash || tx.Height != txs[i].Height {
+			t.Errorf("tx %d: %s at %d, want %s at %d", i, tx.Hash, tx.Height, txs[i].Hash, txs[i].Height)
+		}
+		if want := time.Unix(int64(tx.Height)*900, 0); !tx.Time.Equal(want) {
+			t.Errorf("tx %s: time %s, want the time of its height %s", tx.Hash, tx.Time, want)
+		}
+		if tx.ExpireHeight != tx.Height+120 {
+			t.Errorf("tx %s: ExpireHeight %d, want %d", tx.Hash, tx.ExpireHeight, tx.Height+120)
+		}
+	}
+}
+
+func TestEnrichTransactionsDuplicates(t *testing.T) {
+	provider := newCountingProvider(0)
+	svc := NewService(provider, 4, nil)
+
+	// a page can list the same tx twice, e.g. when it shifts while being paged, and several txs
+	// can share a height
+	txs := []pocket.Transaction{
+		{Hash: "a", Height: 100},
+		{Hash: "b", Height: 100},
+		{Hash: "c", Height: 101},
+		{Hash: "a", Height: 100},
+		{Hash: "d", Height: 102},
+		{Hash: "c", Height: 101},
+	}
+
+	got, err := svc.enrichTransactions(context.Background(), "node", txs)
+	if err != nil {
+		t.Fatalf("enrichTransactions: %v", err)
+	}
+
+	// every entry is kept and enriched, duplicates included
+	if len(got) != len(txs) {
+		t.Fatalf("enrichTransactions returned %d txs, want %d", len(got), len(txs))
+	}
+	for i, tx := range got {
+		if tx.Hash != txs[i].Hash || tx.Time.IsZero() || tx.PoktPerRelay == 0 {
+			t.Errorf("tx %d: %+v, want %s enriched", i, tx, txs[i].Hash)
+		}
+	}
+
+	// but each height is only looked up once
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	for _, h := range []uint{100, 101, 102} {
+		if n := provider.params[int64(h)]; n != 1 {
+			t.Errorf("params at %d looked up %d times, want once", h, n)
+		}
+		if n := provider.blockTimes[h]; n != 1 {
+			t.Errorf("block time at %d looked up %d times, want once", h, n)
+		}
+	}
+	if len(provider.params) != 3 || len(provider.blockTimes) != 3 {
+		t.Errorf("looked up params at %d heights and block times at %d, want 3 each", len(provider.params), len(provider.blockTimes))
+	}
+}
+
+func TestEnrichTransactionsConcurrencyLimit(t *testing.T) {
+	for _, limit := range []int{1, 3, 8} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			provider := newCountingProvider(10 * time.Millisecond)
+			svc := NewService(provider, limit, nil)
+
+			var txs []pocket.Transaction
+			for h := uint(100); h < 124; h++ {
+				txs = append(txs, pocket.Transaction{Height: h})
+			}
+
+			if _, err := svc.enrichTransactions(context.Background(), "node", txs); err != nil {
+				t.Fatalf("enrichTransactions: %v", err)
+			}
+
+			provider.mu.Lock()
+			defer provider.mu.Unlock()
+			if provider.maxInFlight != limit {
+				t.Errorf("%d lookups at once, want the limit of %d", provider.maxInFlight, limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"monitoring-service/pocket"
+)
+
+const (
+	// blockTimeSampleBlocks is the number of recent blocks averaged to extrapolate block times.
+	blockTimeSampleBlocks = 96
+
+	// maxAnchorDistance is how far from a height cached block times are looked for.
+	maxAnchorDistance = 1 << 16
+)
+
+// chainTip is the latest block and the average interval between the blocks leading up to it.
+type chainTip struct {
+	height   uint
+	time     time.Time
+	interval time.Duration
+}
+
+func (s *Service) chainTip(ctx context.Context) (chainTip, error) {
+	height, err := s.provider.Height(ctx)
+	if err != nil {
+		return chainTip{}, fmt.Errorf("chainTip: %w", err)
+	}
+
+	tipTime, err := s.provider.BlockTime(ctx, height)
+	if err != nil {
+		return chainTip{}, fmt.Errorf("chainTip: %w", err)
+	}
+
+	tip := chainTip{height: height, time: tipTime, interval: pocket.TargetBlockTime}
+	if height <= blockTimeSampleBlocks {
+		return tip, nil
+	}
+
+	sampleTime, err := s.provider.BlockTime(ctx, height-blockTimeSampleBlocks)
+	if err != nil {
+		return chainTip{}, fmt.Errorf("chainTip: %w", err)
+	}
+
+	if avg := tipTime.Sub(sampleTime) / blockTimeSampleBlocks; avg > 0 {
+		tip.interval = avg
+	}
+
+	return tip, nil
+}
+
+// EstimateBlockTimes returns the time of each height without calling the network for heights
+// that aren't cached. Those are interpolated between the nearest cached blocks around them, and
+// heights past the tip are extrapolated from the recent average block interval.
+func (s *Service) EstimateBlockTimes(ctx context.Context, heights []uint) ([]pocket.BlockEstimate, error) {
+	tip, err := s.chainTip(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("EstimateBlockTimes: %w", err)
+	}
+
+	estimates := make([]pocket.BlockEstimate, len(heights))
+	for i, h := range heights {
+		if estimates[i], err = s.estimateBlockTime(tip, h); err != nil {
+			return nil, fmt.Errorf("EstimateBlockTimes: %w", err)
+		}
+	}
+
+	return estimates, nil
+}
+
+func (s *Service) estimateBlockTime(tip chainTip, height uint) (pocket.BlockEstimate, error) {
+	if height == tip.height {
+		return pocket.BlockEstimate{Height: height, Time: tip.time}, nil
+	}
+	if height > tip.height {
+		return pocket.BlockEstimate{
+			Height:      height,
+			Time:        tip.time.Add(time.Duration(height-tip.height) * tip.interval),
+			IsEstimated: true,
+		}, nil
+	}
+
+	t, exists, err := s.provider.CachedBlockTime(height)
+	if err != nil {
+		return pocket.BlockEstimate{}, fmt.Errorf("estimateBlockTime: %w", err)
+	}
+	if exists {
+		return pocket.BlockEstimate{Height: height, Time: t}, nil
+	}
+
+	// the tip is the anchor above when no cached block is closer
+	aboveHeight, aboveTime := tip.height, tip.time
+	if h, t, found := s.cachedAnchor(height, tip.height, true); found {
+		aboveHeight, aboveTime = h, t
+	}
+
+	estimate := pocket.BlockEstimate{Height: height, IsEstimated: true}
+	belowHeight, belowTime, found := s.cachedAnchor(height, tip.height, false)
+	if !found {
+		estimate.Time = aboveTime.Add(-time.Duration(aboveHeight-height) * tip.interval)
+		return estimate, nil
+	}
+
+	fraction := float64(height-belowHeight) / float64(aboveHeight-belowHeight)
+	estimate.Time = belowTime.Add(time.Duration(fraction * float64(aboveTime.Sub(belowTime))))
+	return estimate, nil
+}
+
+// errFound stops a walk over cached block times once the one wanted was found.
+var errFound = errors.New("found")
+
+// cachedAnchor returns the nearest cached block time above or below height, at most
+// maxAnchorDistance away, and below the tip. It searches windows of doubling size moving away
+// from height, so a densely cached range is only read a few blocks at a time. Using the nearest
+// blocks keeps the estimates increasing with height, which TimeToHeight relies on.
+func (s *Service) cachedAnchor(height, tipHeight uint, above bool) (uint, time.Time, bool) {
+	var (
+		anchorHeight uint
+		anchorTime   time.Time
+		found        bool
+	)
+	for near, far := uint(1), uint(1); near <= maxAnchorDistance; near, far = far+1, 2*far {
+		var from, to uint
+		if above {
+			if height+near >= tipHeight {
+				return 0, time.Time{}, false
+			}
+			from, to = height+near, height+far
+			if to >= tipHeight {
+				to = tipHeight - 1
+			}
+		} else {
+			if near >= height {
+				return 0, time.Time{}, false
+			}
+			from, to = 1, height-near
+			if far < height {
+				from = height - far
+			}
+		}
+
+		// like CachedBlockTime, a failed read is treated as a miss
+		err := s.provider.CachedBlockTimes(from, to, func(h uint, t time.Time) error {
+			anchorHeight, anchorTime, found = h, t, true
+			if above {
+				// the first one above is the nearest, the last one below is
+				return errFound
+			}
+			return nil
+		})
+		if found && (err == nil || errors.Is(err, errFound)) {
+			return anchorHeight, anchorTime, true
+		}
+		found = false
+	}
+
+	return 0, time.Time{}, false
+}
+
+// TimeToHeight returns the last height at or before t, searching the estimated block times.
+// Times past the tip are extrapolated. The result is exact when the block and the one after it
+// were both cached.
+func (s *Service) TimeToHeight(ctx context.Context, t time.Time) (pocket.BlockEstimate, error) {
+	tip, err := s.chainTip(ctx)
+	if err != nil {
+		return pocket.BlockEstimate{}, fmt.Errorf("TimeToHeight: %w", err)
+	}
+
+	if !t.Before(tip.time) {
+		blocks := uint(t.Sub(tip.time) / tip.interval)
+		return pocket.BlockEstimate{
+			Height:      tip.height + blocks,
+			Time:        tip.time.Add(time.Duration(blocks) * tip.interval),
+			IsEstimated: blocks > 0,
+		}, nil
+	}
+
+	// find the first height after t, in [1, tip]
+	lo, hi := uint(1), tip.height
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		e, err := s.estimateBlockTime(tip, mid)
+		if err != nil {
+			return pocket.BlockEstimate{}, fmt.Errorf("TimeToHeight: %w", err)
+		}
+
+		if e.Time.After(t) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	if lo <= 1 {
+		// t is before the first block
+		first, err := s.estimateBlockTime(tip, 1)
+		if err != nil {
+			return pocket.BlockEstimate{}, fmt.Errorf("TimeToHeight: %w", err)
+		}
+		return first, nil
+	}
+
+	at, err := s.estimateBlockTime(tip, lo-1)
+	if err != nil {
+		return pocket.BlockEstimate{}, fmt.Errorf("TimeToHeight: %w", err)
+	}
+	next, err := s.estimateBlockTime(tip, lo)
+	if err != nil {
+		return pocket.BlockEstimate{}, fmt.Errorf("TimeToHeight: %w", err)
+	}
+	at.IsEstimated = at.IsEstimated || next.IsEstimated
+
+	return at, nil
+}
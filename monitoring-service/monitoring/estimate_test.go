@@ -0,0 +1,123 @@
+package monitoring
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeChain is a chain of tip blocks a minute apart, of which only cached are cached. Its other
+// methods are left unimplemented.
+type fakeChain struct {
+	PocketProvider
+
+	tip    uint
+	cached map[uint]time.Time
+}
+
+func secs(s int64) time.Time {
+	return time.Unix(s, 0).UTC()
+}
+
+func (c *fakeChain) Height(ctx context.Context) (uint, error) {
+	return c.tip, nil
+}
+
+func (c *fakeChain) BlockTime(ctx context.Context, height uint) (time.Time, error) {
+	if t, exists := c.cached[height]; exists {
+		return t, nil
+	}
+	return secs(int64(height) * 60), nil
+}
+
+func (c *fakeChain) CachedBlockTime(height uint) (time.Time, bool, error) {
+	t, exists := c.cached[height]
+	return t, exists, nil
+}
+
+func (c *fakeChain) CachedBlockTimes(from, to uint, fn func(height uint, t time.Time) error) error {
+	heights := make([]uint, 0, len(c.cached))
+	for h := range c.cached {
+		if h >= from && h <= to {
+			heights = append(heights, h)
+		}
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	for _, h := range heights {
+		if err := fn(h, c.cached[h]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newFakeChain returns a chain where blocks 10 to 14 took 200s each, of which only 10 and 14 are cached.
+func newFakeChain() *fakeChain {
+	return &fakeChain{tip: 1014, cached: map[uint]time.Time{10: secs(600), 14: secs(1400)}}
+}
+
+func TestEstimateBlockTimesInterpolatesBetweenNearestCachedBlocks(t *testing.T) {
+	svc := NewService(newFakeChain(), 1, nil)
+
+	estimates, err := svc.EstimateBlockTimes(context.Background(), []uint{9, 10, 11, 12, 13, 14, 15, 1014, 1015})
+	if err != nil {
+		t.Fatalf("EstimateBlockTimes: %v", err)
+	}
+
+	want := []struct {
+		time        time.Time
+		isEstimated bool
+	}{
+		// below the first cached block, extrapolated from it at the tip's interval
+		{secs(540), true},
+		{secs(600), false},
+		// 11 and 13 are 3 blocks from one of their neighbours, which probing at powers of two skips
+		{secs(800), true},
+		{secs(1000), true},
+		{secs(1200), true},
+		{secs(1400), false},
+		// between the last cached block and the tip
+		{secs(1400).Add(59440 * time.Millisecond), true},
+		{secs(60840), false},
+		{secs(60900), true},
+	}
+	var prev time.Time
+	for i, e := range estimates {
+		if !e.Time.Equal(want[i].time) || e.IsEstimated != want[i].isEstimated {
+			t.Errorf("estimate of %d: %d (estimated %t), want %d (%t)", e.Height, e.Time.Unix(), e.IsEstimated, want[i].time.Unix(), want[i].isEstimated)
+		}
+		if e.Time.Before(prev) {
+			t.Errorf("estimate of %d is before the one of the height below it", e.Height)
+		}
+		prev = e.Time
+	}
+}
+
+func TestTimeToHeight(t *testing.T) {
+	svc := NewService(newFakeChain(), 1, nil)
+
+	tests := []struct {
+		at          time.Time
+		want        uint
+		isEstimated bool
+	}{
+		{secs(1250), 13, true},
+		{secs(1000), 12, true},
+		{secs(1400), 14, true},
+		{secs(600), 10, true},
+		{secs(60), 1, true},
+		{secs(60840), 1014, false},
+		{secs(60970), 1016, true},
+	}
+	for _, tt := range tests {
+		e, err := svc.TimeToHeight(context.Background(), tt.at)
+		if err != nil {
+			t.Fatalf("TimeToHeight(%d): %v", tt.at.Unix(), err)
+		}
+		if e.Height != tt.want || e.IsEstimated != tt.isEstimated {
+			t.Errorf("TimeToHeight(%d): %d (estimated %t), want %d (%t)", tt.at.Unix(), e.Height, e.IsEstimated, tt.want, tt.isEstimated)
+		}
+	}
+}
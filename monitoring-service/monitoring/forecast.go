@@ -0,0 +1,153 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"monitoring-service/api"
+	"monitoring-service/pocket"
+)
+
+const (
+	DefaultForecastLookbackDays = 30
+
+	// forecastBandZ is the z-score of the confidence band around projections, a 90% interval.
+	forecastBandZ = 1.645
+)
+
+var ForecastHorizons = []int{7, 30, 90}
+
+// Forecast projects the relays and rewards of address over ForecastHorizons days, from its daily
+// relays over the last lookbackDays full days (or since its first claim, if that is more recent)
+// and the current reward params with overrides applied. Claims that were proven, or can still
+// be, count towards the relay rate. The bands assume days are independent, so their width grows
+// with the square root of the horizon.
+func (s *Service) Forecast(ctx context.Context, address string, lookbackDays int, overrides pocket.ParamOverrides) (pocket.Forecast, error) {
+	if lookbackDays < 1 {
+		lookbackDays = DefaultForecastLookbackDays
+	}
+
+	claims, err := s.confirmedClaims(ctx, address)
+	if err != nil {
+		return pocket.Forecast{}, fmt.Errorf("Forecast: %w", err)
+	}
+
+	height, err := s.provider.Height(ctx)
+	if err != nil {
+		return pocket.Forecast{}, fmt.Errorf("Forecast: %w", err)
+	}
+
+	params, err := s.ParamsAtHeight(ctx, 0, true)
+	if err != nil {
+		return pocket.Forecast{}, fmt.Errorf("Forecast: %w", err)
+	}
+	if params, err = overrides.Apply(params); err != nil {
+		return pocket.Forecast{}, api.InvalidArgument(fmt.Sprintf("Forecast: %s", err), nil)
+	}
+
+	stakedBalance, err := s.stakedBalance(ctx, address)
+	if err != nil {
+		return pocket.Forecast{}, fmt.Errorf("Forecast: %w", err)
+	}
+
+	to := pocket.GranularityDay.Start(time.Now().UTC())
+	from := to.AddDate(0, 0, -lookbackDays)
+	var first time.Time
+	for _, tx := range claims {
+		if first.IsZero() || tx.Time.Before(first) {
+			first = tx.Time
+		}
+	}
+	if firstDay := pocket.GranularityDay.Start(first.UTC()); firstDay.After(from) {
+		from = firstDay
+	}
+
+	numDays := int(to.Sub(from).Hours() / 24)
+	forecast := pocket.Forecast{
+		From:               from,
+		To:                 to,
+		NumDays:            numDays,
+		Params:             params,
+		StakedBalance:      stakedBalance,
+		DailyRelaysByChain: make(map[string]float64),
+	}
+	if numDays < 1 {
+		forecast.Projections = projections(forecast)
+		return forecast, nil
+	}
+
+	daily := make([]float64, numDays)
+	for _, tx := range claims {
+		t := tx.Time.UTC()
+		if t.Before(from) || !t.Before(to) {
+			continue
+		}
+		if !tx.IsConfirmed && tx.ExpireHeight <= height {
+			continue
+		}
+
+		daily[int(t.Sub(from).Hours()/24)] += float64(tx.NumRelays)
+		forecast.DailyRelaysByChain[tx.ChainID] += float64(tx.NumRelays)
+	}
+
+	for ch := range forecast.DailyRelaysByChain {
+		forecast.DailyRelaysByChain[ch] /= float64(numDays)
+	}
+	forecast.DailyRelays, forecast.DailyRelaysStdDev = meanAndStdDev(daily)
+	forecast.Projections = projections(forecast)
+
+	return forecast, nil
+}
+
+func projections(f pocket.Forecast) []pocket.Projection {
+	poktPerRelay := f.Params.PoktPerRelayForStake(f.StakedBalance)
+
+	projections := make([]pocket.Projection, len(ForecastHorizons))
+	for i, days := range ForecastHorizons {
+		relays := f.DailyRelays * float64(days)
+		band := forecastBandZ * f.DailyRelaysStdDev * math.Sqrt(float64(days))
+
+		p := pocket.Projection{
+			Days:          days,
+			Relays:        relays,
+			RelaysLow:     math.Max(0, relays-band),
+			RelaysHigh:    relays + band,
+			RelaysByChain: make(map[string]float64, len(f.DailyRelaysByChain)),
+		}
+		p.PoktAmount = p.Relays * poktPerRelay
+		p.PoktLow = p.RelaysLow * poktPerRelay
+		p.PoktHigh = p.RelaysHigh * poktPerRelay
+		for ch, daily := range f.DailyRelaysByChain {
+			p.RelaysByChain[ch] = daily * float64(days)
+		}
+
+		projections[i] = p
+	}
+
+	return projections
+}
+
+// meanAndStdDev returns the mean and sample standard deviation of values.
+func meanAndStdDev(values []float64) (mean, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	var sumSq float64
+	for _, v := range values {
+		sumSq += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(sumSq / float64(len(values)-1))
+}
@@ -0,0 +1,254 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"monitoring-service/api"
+	"monitoring-service/pocket"
+)
+
+// forecastProvider is a fakeProvider with a tip at 1000 and a node that has stake staked.
+type forecastProvider struct {
+	fakeProvider
+	stake uint
+}
+
+func (p *forecastProvider) Height(ctx context.Context) (uint, error) {
+	return 1000, nil
+}
+
+func (p *forecastProvider) Node(ctx context.Context, address string) (pocket.Node, error) {
+	return pocket.Node{Address: address, StakedBalance: p.stake}, nil
+}
+
+// fakeIndex has indexed txs for every address.
+type fakeIndex []pocket.Transaction
+
+func (idx fakeIndex) IndexedTransactions(address string) ([]pocket.Transaction, bool, error) {
+	return idx, true, nil
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestForecast(t *testing.T) {
+	today := pocket.GranularityDay.Start(time.Now().UTC())
+	// claim returns a claim of relays on chain, made at noon daysAgo days ago. A proven claim
+	// comes with its proof, and an unproven one expires at expireHeight.
+	session := uint(0)
+	claim := func(daysAgo int, relays uint, chain string, proven bool, expireHeight uint) []pocket.Transaction {
+		session++
+		c := pocket.Transaction{
+			Type:          pocket.TypeClaim,
+			SessionHeight: session,
+			ChainID:       chain,
+			NumRelays:     relays,
+			Time:          today.AddDate(0, 0, -daysAgo).Add(12 * time.Hour),
+			ExpireHeight:  expireHeight,
+		}
+		if !proven {
+			return []pocket.Transaction{c}
+		}
+		return []pocket.Transaction{c, {Type: pocket.TypeProof, SessionHeight: session, ChainID: chain}}
+	}
+	txs := func(claims ...[]pocket.Transaction) fakeIndex {
+		var idx fakeIndex
+		for _, c := range claims {
+			idx = append(idx, c...)
+		}
+		return idx
+	}
+
+	// 10000 uPOKT per relay less 15% for the DAO and proposer
+	const poktPerRelay = 0.0085
+
+	tests := []struct {
+		name         string
+		txs          fakeIndex
+		lookbackDays int
+		wantDays     int
+		wantDaily    float64
+		wantStdDev   float64
+		wantByChain  map[string]float64
+	}{
+		{"no claims", nil, 7, 7, 0, 0, map[string]float64{}},
+		{
+			"single day",
+			txs(claim(1, 100, "0001", true, 0)),
+			30, 1, 100, 0, map[string]float64{"0001": 100},
+		},
+		{
+			// an expired unproven claim doesn't count, a pending one does, and today isn't over yet
+			"since the first claim",
+			txs(
+				claim(4, 100, "0001", true, 0),
+				claim(3, 200, "0002", false, 1100),
+				claim(2, 50, "0001", false, 900),
+				claim(1, 300, "0001", true, 0),
+				claim(0, 1000, "0001", true, 0),
+			),
+			30, 4, 150, math.Sqrt(50000.0 / 3), map[string]float64{"0001": 100, "0002": 50},
+		},
+		{
+			"lookback",
+			txs(
+				claim(5, 1000, "0001", true, 0),
+				claim(2, 100, "0001", true, 0),
+				claim(1, 200, "0001", true, 0),
+			),
+			2, 2, 150, math.Sqrt(5000), map[string]float64{"0001": 150},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(&forecastProvider{stake: 15000 * upokt}, 1, tt.txs)
+
+			f, err := svc.Forecast(context.Background(), "node", tt.lookbackDays, pocket.ParamOverrides{})
+			if err != nil {
+				t.Fatalf("Forecast: %v", err)
+			}
+
+			if f.NumDays != tt.wantDays || !f.To.Equal(today) || !f.From.Equal(today.AddDate(0, 0, -tt.wantDays)) {
+				t.Errorf("Forecast over %d days from %s to %s, want %d days up to %s", f.NumDays, f.From, f.To, tt.wantDays, today)
+			}
+			if !approxEqual(f.DailyRelays, tt.wantDaily) || !approxEqual(f.DailyRelaysStdDev, tt.wantStdDev) {
+				t.Errorf("daily relays %g ± %g, want %g ± %g", f.DailyRelays, f.DailyRelaysStdDev, tt.wantDaily, tt.wantStdDev)
+			}
+			if !reflect.DeepEqual(f.DailyRelaysByChain, tt.wantByChain) {
+				t.Errorf("daily relays by chain %v, want %v", f.DailyRelaysByChain, tt.wantByChain)
+			}
+			if f.StakedBalance != 15000*upokt {
+				t.Errorf("staked balance %d, want %d", f.StakedBalance, 15000*upokt)
+			}
+
+			if len(f.Projections) != len(ForecastHorizons) {
+				t.Fatalf("%d projections, want %d", len(f.Projections), len(ForecastHorizons))
+			}
+			for _, p := range f.Projections {
+				if want := tt.wantDaily * float64(p.Days); !approxEqual(p.Relays, want) || !approxEqual(p.PoktAmount, want*poktPerRelay) {
+					t.Errorf("%d days: %g relays and %g POKT, want %g and %g", p.Days, p.Relays, p.PoktAmount, want, want*poktPerRelay)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastOverrides(t *testing.T) {
+	multiplier, dao, proposer := 20000.0, uint8(60), uint8(41)
+	idx := fakeIndex{{Type: pocket.TypeClaim, NumRelays: 100, Time: time.Now().UTC().AddDate(0, 0, -1), ExpireHeight: 1100}}
+	svc := NewService(&forecastProvider{}, 1, idx)
+
+	// the network's DAO allocation is 10 and its proposer percentage 5
+	f, err := svc.Forecast(context.Background(), "node", 30, pocket.ParamOverrides{RelaysToTokensMultiplier: &multiplier, DaoAllocation: &dao})
+	if err != nil {
+		t.Fatalf("Forecast: %v", err)
+	}
+	if want := 0.02 * 0.35; !approxEqual(f.Params.PoktPerRelay(), want) {
+		t.Errorf("POKT per relay with overrides: %g, want %g", f.Params.PoktPerRelay(), want)
+	}
+
+	// 41 and the network's 10 is fine, but 60 and 41 exceed 100
+	tests := []struct {
+		name      string
+		overrides pocket.ParamOverrides
+		wantErr   bool
+	}{
+		{"with the network's", pocket.ParamOverrides{ProposerPercentage: &proposer}, false},
+		{"with each other", pocket.ParamOverrides{DaoAllocation: &dao, ProposerPercentage: &proposer}, true},
+		{"with the multiplier", pocket.ParamOverrides{DaoAllocation: &proposer, ProposerPercentage: &dao, RelaysToTokensMultiplier: &multiplier}, true},
+	}
+	for _, tt := range tests {
+		_, err = svc.Forecast(context.Background(), "node", 30, tt.overrides)
+		var apiErr *api.Error
+		if isInvalid := errors.As(err, &apiErr) && apiErr.Code == api.CodeInvalidArgument; isInvalid != tt.wantErr || (err != nil && !isInvalid) {
+			t.Errorf("Forecast with overrides %s: %v, want an invalid argument %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMeanAndStdDev(t *testing.T) {
+	tests := []struct {
+		values     []float64
+		wantMean   float64
+		wantStdDev float64
+	}{
+		{nil, 0, 0},
+		{[]float64{42}, 42, 0},
+		{[]float64{3, 3, 3}, 3, 0},
+		{[]float64{0, 10}, 5, math.Sqrt(50)},
+		{[]float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, math.Sqrt(32.0 / 7)},
+	}
+
+	for _, tt := range tests {
+		mean, stdDev := meanAndStdDev(tt.values)
+		if !approxEqual(mean, tt.wantMean) || !approxEqual(stdDev, tt.wantStdDev) {
+			t.Errorf("meanAndStdDev(%v): %g, %g, want %g, %g", tt.values, mean, stdDev, tt.wantMean, tt.wantStdDev)
+		}
+	}
+}
+
+func TestProjections(t *testing.T) {
+	unweighted := pocket.Params{RelaysToTokensMultiplier: 10000, DaoAllocation: 10, ProposerPercentage: 5}
+	weighted := unweighted
+	weighted.IsStakeWeighted = true
+	weighted.ServicerStakeWeightMultiplier = 2
+	weighted.ServicerStakeFloorMultiplier = 15000 * upokt
+	weighted.ServicerStakeFloorMultiplierExponent = 1
+	weighted.ServicerStakeWeightCeiling = 60000 * upokt
+
+	tests := []struct {
+		name         string
+		forecast     pocket.Forecast
+		poktPerRelay float64
+	}{
+		{"no relays", pocket.Forecast{Params: unweighted}, 0.0085},
+		{"no spread", pocket.Forecast{Params: unweighted, DailyRelays: 100, DailyRelaysByChain: map[string]float64{"0001": 60, "0002": 40}}, 0.0085},
+		{"spread", pocket.Forecast{Params: unweighted, DailyRelays: 100, DailyRelaysStdDev: 20}, 0.0085},
+		// a band wider than the projection is cut off at 0
+		{"wide spread", pocket.Forecast{Params: unweighted, DailyRelays: 10, DailyRelaysStdDev: 100}, 0.0085},
+		// 45k staked is bin 3, weighted by 3 / 2
+		{"stake weighted", pocket.Forecast{Params: weighted, StakedBalance: 45000 * upokt, DailyRelays: 100}, 0.0085 * 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projections := projections(tt.forecast)
+			if len(projections) != len(ForecastHorizons) {
+				t.Fatalf("%d projections, want %d", len(projections), len(ForecastHorizons))
+			}
+
+			f := tt.forecast
+			for i, p := range projections {
+				days := float64(ForecastHorizons[i])
+				relays := f.DailyRelays * days
+				band := forecastBandZ * f.DailyRelaysStdDev * math.Sqrt(days)
+				low := math.Max(0, relays-band)
+
+				if p.Days != ForecastHorizons[i] {
+					t.Errorf("projection %d is over %d days, want %d", i, p.Days, ForecastHorizons[i])
+				}
+				if !approxEqual(p.Relays, relays) || !approxEqual(p.RelaysLow, low) || !approxEqual(p.RelaysHigh, relays+band) {
+					t.Errorf("%d days: %g relays in [%g, %g], want %g in [%g, %g]", p.Days, p.Relays, p.RelaysLow, p.RelaysHigh, relays, low, relays+band)
+				}
+				if !approxEqual(p.PoktAmount, relays*tt.poktPerRelay) || !approxEqual(p.PoktLow, low*tt.poktPerRelay) || !approxEqual(p.PoktHigh, (relays+band)*tt.poktPerRelay) {
+					t.Errorf("%d days: %g POKT in [%g, %g], want %g per relay", p.Days, p.PoktAmount, p.PoktLow, p.PoktHigh, tt.poktPerRelay)
+				}
+				if len(p.RelaysByChain) != len(f.DailyRelaysByChain) {
+					t.Errorf("%d days: relays by chain %v, want one per chain of %v", p.Days, p.RelaysByChain, f.DailyRelaysByChain)
+				}
+				for ch, daily := range f.DailyRelaysByChain {
+					if !approxEqual(p.RelaysByChain[ch], daily*days) {
+						t.Errorf("%d days: %g relays on %s, want %g", p.Days, p.RelaysByChain[ch], ch, daily*days)
+					}
+				}
+			}
+		})
+	}
+}
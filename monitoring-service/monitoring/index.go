@@ -0,0 +1,10 @@
+package monitoring
+
+import "monitoring-service/pocket"
+
+// TransactionIndex serves the transactions of accounts that are indexed locally.
+type TransactionIndex interface {
+	// IndexedTransactions returns the stored transactions for address, newest first. indexed
+	// is false if the address is not tracked or has not been indexed yet.
+	IndexedTransactions(address string) (txs []pocket.Transaction, indexed bool, err error)
+}
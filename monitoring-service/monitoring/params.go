@@ -0,0 +1,141 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"monitoring-service/api"
+	"monitoring-service/pocket"
+)
+
+type paramValue struct {
+	value  string
+	exists bool
+}
+
+// ParamHistory returns the heights in (from, to] where the value of key changed, oldest first.
+// A zero to means the current height. Changes are found by bisecting the heights between two
+// differing values, so a value that changed and then changed back between two probed heights
+// can be missed; every change that leaves the value different is found exactly.
+func (s *Service) ParamHistory(ctx context.Context, key string, from, to uint) ([]pocket.ParamChange, error) {
+	if to == 0 {
+		height, err := s.provider.Height(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("ParamHistory: %w", err)
+		}
+		to = height
+	}
+	if from < 1 {
+		from = 1
+	}
+	if from > to {
+		return nil, api.InvalidArgument(fmt.Sprintf("ParamHistory: from %d is after to %d", from, to), api.Details{"param": "from", "value": from})
+	}
+
+	lo, err := s.paramValue(ctx, key, from)
+	if err != nil {
+		return nil, fmt.Errorf("ParamHistory: %w", err)
+	}
+	hi, err := s.paramValue(ctx, key, to)
+	if err != nil {
+		return nil, fmt.Errorf("ParamHistory: %w", err)
+	}
+
+	var changes []pocket.ParamChange
+	if err = s.bisectParam(ctx, key, from, lo, to, hi, &changes); err != nil {
+		return nil, fmt.Errorf("ParamHistory: %w", err)
+	}
+
+	return changes, nil
+}
+
+// bisectParam appends the changes of key between loHeight and hiHeight to changes, in order.
+func (s *Service) bisectParam(ctx context.Context, key string, loHeight uint, lo paramValue, hiHeight uint, hi paramValue, changes *[]pocket.ParamChange) error {
+	if lo == hi {
+		return nil
+	}
+
+	if hiHeight-loHeight == 1 {
+		*changes = append(*changes, pocket.ParamChange{
+			Key:            key,
+			Height:         hiHeight,
+			Value:          hi.value,
+			Exists:         hi.exists,
+			PreviousValue:  lo.value,
+			PreviousExists: lo.exists,
+		})
+		return nil
+	}
+
+	midHeight := loHeight + (hiHeight-loHeight)/2
+	mid, err := s.paramValue(ctx, key, midHeight)
+	if err != nil {
+		return err
+	}
+
+	if err = s.bisectParam(ctx, key, loHeight, lo, midHeight, mid, changes); err != nil {
+		return err
+	}
+	return s.bisectParam(ctx, key, midHeight, mid, hiHeight, hi, changes)
+}
+
+func (s *Service) paramValue(ctx context.Context, key string, height uint) (paramValue, error) {
+	allParams, err := s.provider.AllParams(ctx, int64(height), false)
+	if err != nil {
+		return paramValue{}, fmt.Errorf("paramValue: %w", err)
+	}
+
+	value, exists := allParams.Get(key)
+	return paramValue{value: value, exists: exists}, nil
+}
+
+// ParamsDiff returns every param whose value differs between heights a and b, by group and key.
+func (s *Service) ParamsDiff(ctx context.Context, a, b uint) ([]pocket.ParamDiff, error) {
+	paramsA, err := s.provider.AllParams(ctx, int64(a), false)
+	if err != nil {
+		return nil, fmt.Errorf("ParamsDiff: %w", err)
+	}
+	paramsB, err := s.provider.AllParams(ctx, int64(b), false)
+	if err != nil {
+		return nil, fmt.Errorf("ParamsDiff: %w", err)
+	}
+
+	var diffs []pocket.ParamDiff
+	for _, group := range pocket.ParamGroupNames {
+		groupA, groupB := paramsA.Group(group), paramsB.Group(group)
+
+		keys := make(map[string]bool)
+		for _, p := range groupA {
+			keys[p.Key] = true
+		}
+		for _, p := range groupB {
+			keys[p.Key] = true
+		}
+
+		var groupDiffs []pocket.ParamDiff
+		for key := range keys {
+			valueA, existsA := groupA.Get(key)
+			valueB, existsB := groupB.Get(key)
+			if valueA == valueB && existsA == existsB {
+				continue
+			}
+
+			groupDiffs = append(groupDiffs, pocket.ParamDiff{
+				Group:   group,
+				Key:     key,
+				A:       valueA,
+				AExists: existsA,
+				B:       valueB,
+				BExists: existsB,
+			})
+		}
+		sort.Slice(groupDiffs, func(i, j int) bool {
+			return groupDiffs[i].Key < groupDiffs[j].Key
+		})
+
+		diffs = append(diffs, groupDiffs...)
+	}
+
+	return diffs, nil
+}
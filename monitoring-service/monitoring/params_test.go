@@ -0,0 +1,195 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"monitoring-service/api"
+	"monitoring-service/pocket"
+)
+
+// paramStep sets a param to value from height on, or removes it if exists is false.
+type paramStep struct {
+	height uint
+	value  string
+	exists bool
+}
+
+// paramsChain is a chain with a tip at 1000 whose params change at the heights of steps. Keys that
+// start with "application/" are app params, and all others node params. It counts the params lookups.
+type paramsChain struct {
+	PocketProvider
+
+	steps map[string][]paramStep
+
+	mu      sync.Mutex
+	lookups int
+}
+
+func newParamsChain() *paramsChain {
+	return &paramsChain{steps: map[string][]paramStep{
+		"application/MaxApplications":       {{1, "1", true}, {200, "2", true}},
+		"pocketcore/ClaimExpiration":        {{1, "120", true}},
+		"pos/RelaysToTokensMultiplier":      {{1, "8000", true}, {101, "10000", true}, {500, "9000", true}, {1000, "7000", true}},
+		"pos/ServicerStakeWeightMultiplier": {{300, "2", true}},
+		"pos/Removed":                       {{1, "x", true}, {700, "", false}},
+	}}
+}
+
+func (c *paramsChain) Height(ctx context.Context) (uint, error) {
+	return 1000, nil
+}
+
+func (c *paramsChain) AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error) {
+	c.mu.Lock()
+	c.lookups++
+	c.mu.Unlock()
+
+	var all pocket.AllParams
+	for key, steps := range c.steps {
+		var current *paramStep
+		for i := range steps {
+			if int64(steps[i].height) <= height {
+				current = &steps[i]
+			}
+		}
+		if current == nil || !current.exists {
+			continue
+		}
+
+		p := pocket.Param{Key: key, Value: current.value}
+		if strings.HasPrefix(key, "application/") {
+			all.AppParams = append(all.AppParams, p)
+		} else {
+			all.NodeParams = append(all.NodeParams, p)
+		}
+	}
+
+	return all, nil
+}
+
+func (c *paramsChain) lookupCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookups
+}
+
+func change(key string, height uint, previous, value string) pocket.ParamChange {
+	return pocket.ParamChange{
+		Key:            key,
+		Height:         height,
+		Value:          value,
+		Exists:         value != "",
+		PreviousValue:  previous,
+		PreviousExists: previous != "",
+	}
+}
+
+func TestParamHistory(t *testing.T) {
+	const multiplier = "pos/RelaysToTokensMultiplier"
+
+	tests := []struct {
+		name     string
+		key      string
+		from, to uint
+		want     []pocket.ParamChange
+	}{
+		{
+			"change at from+1 and at to", multiplier, 100, 1000,
+			[]pocket.ParamChange{change(multiplier, 101, "8000", "10000"), change(multiplier, 500, "10000", "9000"), change(multiplier, 1000, "9000", "7000")},
+		},
+		{"change at from is before the range", multiplier, 101, 999, []pocket.ParamChange{change(multiplier, 500, "10000", "9000")}},
+		{"from+1 is to", multiplier, 999, 1000, []pocket.ParamChange{change(multiplier, 1000, "9000", "7000")}},
+		{"from is to", multiplier, 500, 500, nil},
+		{
+			"whole chain up to the tip", multiplier, 0, 0,
+			[]pocket.ParamChange{change(multiplier, 101, "8000", "10000"), change(multiplier, 500, "10000", "9000"), change(multiplier, 1000, "9000", "7000")},
+		},
+		{"added", "pos/ServicerStakeWeightMultiplier", 1, 1000, []pocket.ParamChange{change("pos/ServicerStakeWeightMultiplier", 300, "", "2")}},
+		{"removed", "pos/Removed", 1, 1000, []pocket.ParamChange{change("pos/Removed", 700, "x", "")}},
+		{"unchanged", "pocketcore/ClaimExpiration", 1, 1000, nil},
+		{"unknown", "pos/Unknown", 1, 1000, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newParamsChain()
+			svc := NewService(chain, 1, nil)
+
+			got, err := svc.ParamHistory(context.Background(), tt.key, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("ParamHistory: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParamHistory(%s, %d, %d): %+v, want %+v", tt.key, tt.from, tt.to, got, tt.want)
+			}
+
+			// each change is found by bisecting, in about log2(1000) lookups
+			if maxLookups := 2 + 11*len(tt.want); chain.lookupCount() > maxLookups {
+				t.Errorf("ParamHistory made %d lookups, want at most %d", chain.lookupCount(), maxLookups)
+			}
+		})
+	}
+
+	svc := NewService(newParamsChain(), 1, nil)
+	var apiErr *api.Error
+	if _, err := svc.ParamHistory(context.Background(), multiplier, 600, 500); !errors.As(err, &apiErr) || apiErr.Code != api.CodeInvalidArgument {
+		t.Errorf("ParamHistory with from after to: %v, want an invalid argument", err)
+	}
+}
+
+func TestBisectParam(t *testing.T) {
+	const multiplier = "pos/RelaysToTokensMultiplier"
+	svc := NewService(newParamsChain(), 1, nil)
+	ctx := context.Background()
+
+	value := func(v string) paramValue { return paramValue{value: v, exists: true} }
+
+	// changes are appended after those already found
+	changes := []pocket.ParamChange{change(multiplier, 101, "8000", "10000")}
+	if err := svc.bisectParam(ctx, multiplier, 400, value("10000"), 600, value("9000"), &changes); err != nil {
+		t.Fatalf("bisectParam: %v", err)
+	}
+	want := []pocket.ParamChange{change(multiplier, 101, "8000", "10000"), change(multiplier, 500, "10000", "9000")}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("bisectParam: %+v, want %+v", changes, want)
+	}
+
+	// equal ends aren't bisected, so a change and a change back in between are missed
+	changes = nil
+	if err := svc.bisectParam(ctx, multiplier, 101, value("10000"), 600, value("10000"), &changes); err != nil || changes != nil {
+		t.Errorf("bisectParam between equal values: %+v, %v, want no changes", changes, err)
+	}
+}
+
+func TestParamsDiff(t *testing.T) {
+	svc := NewService(newParamsChain(), 1, nil)
+	ctx := context.Background()
+
+	diffs, err := svc.ParamsDiff(ctx, 100, 1000)
+	if err != nil {
+		t.Fatalf("ParamsDiff: %v", err)
+	}
+	// by group in the network's order, then by key
+	want := []pocket.ParamDiff{
+		{Group: "app_params", Key: "application/MaxApplications", A: "1", AExists: true, B: "2", BExists: true},
+		{Group: "node_params", Key: "pos/RelaysToTokensMultiplier", A: "8000", AExists: true, B: "7000", BExists: true},
+		{Group: "node_params", Key: "pos/Removed", A: "x", AExists: true},
+		{Group: "node_params", Key: "pos/ServicerStakeWeightMultiplier", B: "2", BExists: true},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("ParamsDiff(100, 1000): %+v, want %+v", diffs, want)
+	}
+
+	// heights with the same params have no differences
+	if diffs, err = svc.ParamsDiff(ctx, 200, 250); err != nil || diffs != nil {
+		t.Errorf("ParamsDiff between equal params: %+v, %v, want none", diffs, err)
+	}
+	if diffs, err = svc.ParamsDiff(ctx, 499, 500); err != nil || len(diffs) != 1 || diffs[0].A != "10000" || diffs[0].B != "9000" {
+		t.Errorf("ParamsDiff(499, 500): %+v, %v, want the multiplier going from 10000 to 9000", diffs, err)
+	}
+}
@@ -1,10 +1,10 @@
 package monitoring
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"sort"
 	"strconv"
@@ -15,69 +15,90 @@ import (
 )
 
 type PocketProvider interface {
-	NodeProvider(address string) (pocketnode.Provider, error)
-	SimulateRelay(servicerUrl, chainID string, payload json.RawMessage) (json.RawMessage, error)
-	AccountTransactions(address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error)
-	Transaction(hash string) (pocket.Transaction, error)
-	BlockTime(height uint) (time.Time, error)
-	Node(address string) (pocket.Node, error)
-	Balance(address string) (uint, error)
-	Param(name string, height int64) (string, error)
-	AllParams(height int64, forceRefresh bool) (pocket.AllParams, error)
-	Height() (uint, error)
+	ServicerProvider(serviceURL string, timeout time.Duration) pocketnode.Provider
+	SimulateRelay(ctx context.Context, servicerUrl, chainID string, payload json.RawMessage) (json.RawMessage, error)
+	AccountTransactions(ctx context.Context, address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error)
+	Transaction(ctx context.Context, hash string) (pocket.Transaction, error)
+	BlockTime(ctx context.Context, height uint) (time.Time, error)
+	CachedBlockTime(height uint) (t time.Time, exists bool, err error)
+	CachedBlockTimes(from, to uint, fn func(height uint, t time.Time) error) error
+	Node(ctx context.Context, address string) (pocket.Node, error)
+	StakeAtHeight(ctx context.Context, address string, height uint) (uint, error)
+	Balance(ctx context.Context, address string) (uint, error)
+	Param(ctx context.Context, name string, height int64) (string, error)
+	AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error)
+	Height(ctx context.Context) (uint, error)
 }
 
-func NewService(provider PocketProvider) Service {
+// DefaultConcurrency is the number of concurrent upstream lookups used when enriching transactions.
+const DefaultConcurrency = 8
+
+// NewService returns a Service backed by provider. concurrency bounds the number of parallel
+// upstream lookups made while enriching transactions; values below 1 fall back to DefaultConcurrency.
+// txIndex may be nil; when set, claims and proofs of indexed addresses are read from it.
+func NewService(provider PocketProvider, concurrency int, txIndex TransactionIndex) Service {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
 	return Service{
-		provider: provider,
+		provider:      provider,
+		concurrency:   concurrency,
+		txIndex:       txIndex,
+		syncTolerance: DefaultSyncTolerance,
 	}
 }
 
 type Service struct {
-	provider PocketProvider
+	provider    PocketProvider
+	concurrency int
+	txIndex     TransactionIndex
+
+	syncReferences []HeightProvider
+	syncTolerance  uint
 }
 
-func (s *Service) Height() (uint, error) {
-	height, err := s.provider.Height()
+func (s *Service) Height(ctx context.Context) (uint, error) {
+	height, err := s.provider.Height(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("Height: %s", err)
+		return 0, fmt.Errorf("Height: %w", err)
 	}
 
 	return height, nil
 }
 
-func (s *Service) Transaction(hash string) (pocket.Transaction, error) {
-	txn, err := s.provider.Transaction(hash)
+func (s *Service) Transaction(ctx context.Context, hash string) (pocket.Transaction, error) {
+	txn, err := s.provider.Transaction(ctx, hash)
 	if err != nil {
-		return pocket.Transaction{}, fmt.Errorf("Transaction: %s", err)
+		return pocket.Transaction{}, fmt.Errorf("Transaction: %w", err)
 	}
 
-	txn.Time, err = s.provider.BlockTime(txn.Height)
+	txn.Time, err = s.provider.BlockTime(ctx, txn.Height)
 	if err != nil {
-		return pocket.Transaction{}, fmt.Errorf("Transaction: %s", err)
+		return pocket.Transaction{}, fmt.Errorf("Transaction: %w", err)
 	}
 
 	return txn, nil
 }
 
-func (s *Service) BlockTimes(heights []uint) (map[uint]time.Time, error) {
+func (s *Service) BlockTimes(ctx context.Context, heights []uint) (map[uint]time.Time, error) {
 	times := make(map[uint]time.Time, len(heights))
 	for _, id := range heights {
 		var err error
-		if times[id], err = s.provider.BlockTime(id); err != nil {
-			return nil, fmt.Errorf("BlockTimes: %s", err)
+		if times[id], err = s.provider.BlockTime(ctx, id); err != nil {
+			return nil, fmt.Errorf("BlockTimes: %w", err)
 		}
 	}
 
 	return times, nil
 }
 
-func (s *Service) ParamsAtHeight(height int64, forceRefresh bool) (pocket.Params, error) {
+func (s *Service) ParamsAtHeight(ctx context.Context, height int64, forceRefresh bool) (pocket.Params, error) {
 	params := pocket.Params{}
 
-	allParams, err := s.provider.AllParams(height, forceRefresh)
+	allParams, err := s.provider.AllParams(ctx, height, forceRefresh)
 	if err != nil {
-		return pocket.Params{}, fmt.Errorf("ParamsAtHeight: provider error: %s", err)
+		return pocket.Params{}, fmt.Errorf("ParamsAtHeight: provider error: %w", err)
 	}
 
 	np := allParams.NodeParams
@@ -115,133 +136,230 @@ func (s *Service) ParamsAtHeight(height int64, forceRefresh bool) (pocket.Params
 	}
 	claimExpires, err := strconv.ParseUint(claimExpirationBlocks, 10, 64)
 	if err != nil {
-		return pocket.Params{}, fmt.Errorf("ParamsAtHeight: failed to parse node_params ket 'pocketcore/ClaimExpiration': %s", err)
+		return pocket.Params{}, fmt.Errorf("ParamsAtHeight: failed to parse node_params ket 'pocketcore/ClaimExpiration': %w", err)
 	}
 	params.ClaimExpirationBlocks = uint(claimExpires)
 
+	if err = parseStakeWeightParams(np, &params); err != nil {
+		return pocket.Params{}, fmt.Errorf("ParamsAtHeight: %w", err)
+	}
+
 	return params, nil
 }
 
-func (s *Service) AccountTransactions(address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error) {
-	txs, err := s.provider.AccountTransactions(address, page, perPage, sort)
+// parseStakeWeightParams sets the servicer stake weighting params on params, if they exist in np.
+// They only exist at heights after stake weighting was introduced, and must then all be set.
+func parseStakeWeightParams(np pocket.ParamGroup, params *pocket.Params) error {
+	weightMultiplier, ok := np.Get("pos/ServicerStakeWeightMultiplier")
+	if !ok {
+		return nil
+	}
+
+	var err error
+	if params.ServicerStakeWeightMultiplier, err = strconv.ParseFloat(weightMultiplier, 64); err != nil {
+		return fmt.Errorf("failed to parse node_params key 'pos/ServicerStakeWeightMultiplier': %w", err)
+	}
+
+	floorMultiplier, ok := np.Get("pos/ServicerStakeFloorMultiplier")
+	if !ok {
+		return errors.New("node_params key not found 'pos/ServicerStakeFloorMultiplier'")
+	}
+	floor, err := strconv.ParseUint(floorMultiplier, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("AccountTransactions: %s", err)
+		return fmt.Errorf("failed to parse node_params key 'pos/ServicerStakeFloorMultiplier': %w", err)
 	}
+	params.ServicerStakeFloorMultiplier = uint(floor)
 
-	transactions := make([]pocket.Transaction, len(txs))
-	for i, tx := range txs {
-		params, err := s.ParamsAtHeight(int64(tx.Height), false)
-		if err != nil {
-			return nil, fmt.Errorf("AccountTransactions: %s", err)
-		}
+	exponent, ok := np.Get("pos/ServicerStakeFloorMultiplierExponent")
+	if !ok {
+		return errors.New("node_params key not found 'pos/ServicerStakeFloorMultiplierExponent'")
+	}
+	if params.ServicerStakeFloorMultiplierExponent, err = strconv.ParseFloat(exponent, 64); err != nil {
+		return fmt.Errorf("failed to parse node_params key 'pos/ServicerStakeFloorMultiplierExponent': %w", err)
+	}
 
-		tx.Time, err = s.provider.BlockTime(tx.Height)
-		tx.PoktPerRelay = params.PoktPerRelay()
-		if err != nil {
-			return nil, fmt.Errorf("AccountTransactions: %s", err)
-		}
+	weightCeiling, ok := np.Get("pos/ServicerStakeWeightCeiling")
+	if !ok {
+		return errors.New("node_params key not found 'pos/ServicerStakeWeightCeiling'")
+	}
+	ceiling, err := strconv.ParseUint(weightCeiling, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse node_params key 'pos/ServicerStakeWeightCeiling': %w", err)
+	}
+	params.ServicerStakeWeightCeiling = uint(ceiling)
+
+	params.IsStakeWeighted = true
+	return nil
+}
 
-		tx.ExpireHeight = params.ClaimExpirationBlocks + tx.Height
-		transactions[i] = tx
+func (s *Service) AccountTransactions(ctx context.Context, address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error) {
+	txs, err := s.provider.AccountTransactions(ctx, address, page, perPage, sort)
+	if err != nil {
+		return nil, fmt.Errorf("AccountTransactions: %w", err)
+	}
+
+	transactions, err := s.enrichTransactions(ctx, address, txs)
+	if err != nil {
+		return nil, fmt.Errorf("AccountTransactions: %w", err)
 	}
 
 	return transactions, nil
 }
 
-func (s *Service) AccountClaimsAndProofs(address string) (claims, proofs map[string]pocket.Transaction, err error) {
-	page := 1
-	numPerPage := 100
+// AccountClaimsAndProofs returns the claims and proofs for address keyed by session. Addresses that
+// have been indexed are read from the transaction index, anything else is paged from the network.
+func (s *Service) AccountClaimsAndProofs(ctx context.Context, address string) (claims, proofs map[string]pocket.Transaction, err error) {
+	if s.txIndex != nil {
+		txs, indexed, err := s.txIndex.IndexedTransactions(address)
+		if err != nil {
+			return nil, nil, fmt.Errorf("AccountClaimsAndProofs: %w", err)
+		}
+
+		if indexed {
+			claims, proofs = make(map[string]pocket.Transaction), make(map[string]pocket.Transaction)
+			for _, tx := range txs {
+				addClaimOrProof(claims, proofs, tx)
+			}
+			return claims, proofs, nil
+		}
+	}
+
+	numPerPage := uint(100)
 	sortDirection := "desc"
-	goAgain := true
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type txPage struct {
+		txs []pocket.Transaction
+		err error
+	}
+	fetch := func(page uint) <-chan txPage {
+		ch := make(chan txPage, 1)
+		go func() {
+			txs, err := s.provider.AccountTransactions(ctx, address, page, numPerPage, sortDirection)
+			ch <- txPage{txs: txs, err: err}
+		}()
+		return ch
+	}
 
 	claims, proofs = make(map[string]pocket.Transaction), make(map[string]pocket.Transaction)
 
-	for goAgain {
-		txs, err := s.AccountTransactions(address, uint(page), uint(numPerPage), sortDirection)
-		if err != nil {
-			return nil, nil, fmt.Errorf("AccountClaimsAndProofs: %s", err)
+	// the next page is fetched while the current one is being enriched
+	next := fetch(1)
+	for page := uint(1); next != nil; page++ {
+		res := <-next
+		if res.err != nil {
+			return nil, nil, fmt.Errorf("AccountClaimsAndProofs: %w", res.err)
+		}
+
+		next = nil
+		if uint(len(res.txs)) >= numPerPage {
+			next = fetch(page + 1)
 		}
 
-		if len(txs) < numPerPage {
-			goAgain = false
+		txs, err := s.enrichTransactions(ctx, address, res.txs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("AccountClaimsAndProofs: %w", err)
 		}
 
 		for _, tx := range txs {
-			sessionKey := sessionKey(tx)
-			switch tx.Type {
-			case pocket.TypeClaim:
-				claims[sessionKey] = tx
-				break
-			case pocket.TypeProof:
-				proofs[sessionKey] = tx
-				break
-			}
+			addClaimOrProof(claims, proofs, tx)
 		}
-		page++
 	}
 
 	return claims, proofs, nil
 
 }
 
-func (s *Service) Node(address string) (pocket.Node, error) {
-	node, err := s.provider.Node(address)
+// addClaimOrProof files tx under its session. When a claim or proof was re-submitted for the
+// same session, a successful tx wins over a failed one, and otherwise the latest one wins.
+func addClaimOrProof(claims, proofs map[string]pocket.Transaction, tx pocket.Transaction) {
+	var txs map[string]pocket.Transaction
+	switch tx.Type {
+	case pocket.TypeClaim:
+		txs = claims
+	case pocket.TypeProof:
+		txs = proofs
+	default:
+		return
+	}
+
+	sessionKey := sessionKey(tx)
+	existing, exists := txs[sessionKey]
+	if exists {
+		existingOK, txOK := existing.ResultCode == 0, tx.ResultCode == 0
+		if existingOK && !txOK {
+			return
+		}
+		if existingOK == txOK && existing.Height > tx.Height {
+			return
+		}
+	}
+
+	txs[sessionKey] = tx
+}
+
+// stakedBalance returns the uPOKT staked by the node at address today. It is 0 if address isn't a node.
+func (s *Service) stakedBalance(ctx context.Context, address string) (uint, error) {
+	node, err := s.provider.Node(ctx, address)
+	if errors.Is(err, pocketnode.ErrNotFound) {
+		return 0, nil
+	}
 	if err != nil {
-		return pocket.Node{}, fmt.Errorf("Node: %s", err)
+		return 0, fmt.Errorf("stakedBalance: %w", err)
 	}
 
-	node.Balance, err = s.provider.Balance(address)
+	return node.StakedBalance, nil
+}
+
+// Node returns the node at address with its balance and sync status. A node whose own RPC can't
+// be reached is still returned, as unreachable.
+func (s *Service) Node(ctx context.Context, address string) (pocket.Node, error) {
+	node, err := s.provider.Node(ctx, address)
 	if err != nil {
-		return pocket.Node{}, fmt.Errorf("Node: %s", err)
+		return pocket.Node{}, fmt.Errorf("Node: %w", err)
 	}
 
-	nodeProvider, err := s.provider.NodeProvider(node.Address)
+	node.Balance, err = s.provider.Balance(ctx, address)
 	if err != nil {
-		log.Default().Printf("ERROR: %+v", err)
-	} else {
-		if node.LatestBlockHeight, err = nodeProvider.Height(); err != nil {
-			log.Default().Printf("ERROR: %+v", err)
-		} else {
-			blockTimes, err := s.BlockTimes([]uint{node.LatestBlockHeight})
-			if err != nil {
-				log.Default().Printf("ERROR: %+v", err)
-			} else {
-				node.LatestBlockTime = blockTimes[node.LatestBlockHeight]
-			}
-		}
+		return pocket.Node{}, fmt.Errorf("Node: %w", err)
 	}
 
+	s.syncStatus(ctx, &node)
+
 	return node, nil
 }
 
-func (s *Service) SimulateRelay(servicerUrl, chainID string, payload map[string]interface{}) (json.RawMessage, error) {
+func (s *Service) SimulateRelay(ctx context.Context, servicerUrl, chainID string, payload map[string]interface{}) (json.RawMessage, error) {
 	encodedPayload, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("SimulateRelay: %s", err)
+		return nil, fmt.Errorf("SimulateRelay: %w", err)
 	}
 
-	resp, err := s.provider.SimulateRelay(servicerUrl, chainID, encodedPayload)
+	resp, err := s.provider.SimulateRelay(ctx, servicerUrl, chainID, encodedPayload)
 	if err != nil {
-		return nil, fmt.Errorf("SimulateRelay: %s", err)
+		return nil, fmt.Errorf("SimulateRelay: %w", err)
 	}
 
 	return resp, nil
 }
 
-func (s *Service) RewardsByMonth(address string) (map[string]pocket.MonthlyReward, error) {
-	claims, proofs, err := s.AccountClaimsAndProofs(address)
+// RewardsByMonth buckets the claims for address by calendar month. Months, weekdays and hours
+// are those of loc, which defaults to UTC.
+func (s *Service) RewardsByMonth(ctx context.Context, address string, loc *time.Location) (map[string]pocket.MonthlyReward, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	claims, err := s.confirmedClaims(ctx, address)
 	if err != nil {
-		return nil, fmt.Errorf("RewardsByMonth: %s", err)
+		return nil, fmt.Errorf("RewardsByMonth: %w", err)
 	}
 
 	months := make(map[string]pocket.MonthlyReward)
-	for sessionKey, tx := range claims {
-		tx.IsConfirmed = false
-		proof, proofExists := proofs[sessionKey]
-		if proofExists && proof.ResultCode == 0 {
-			tx.IsConfirmed = true
-		}
-
+	for _, tx := range claims {
+		tx.Time = tx.Time.In(loc)
 		monthKey := fmt.Sprintf("%d-%d", tx.Time.Year(), tx.Time.Month())
 		if _, exists := months[monthKey]; !exists {
 			months[monthKey] = pocket.MonthlyReward{
@@ -272,25 +390,13 @@ func (s *Service) RewardsByMonth(address string) (map[string]pocket.MonthlyRewar
 			return mo.Transactions[i].Time.Before(mo.Transactions[j].Time)
 		})
 
-		var numTxs = float64(0)
-		var totalSecs = float64(0)
-		var prevTx, emptyYx = pocket.Transaction{}, pocket.Transaction{}
 		for _, tx := range months[monthKey].Transactions {
-			if prevTx != emptyYx {
-				totalSecs += tx.Time.Sub(prevTx.Time).Seconds()
-				numTxs++
-			}
-			prevTx = tx
-
 			dayOfWeek := int(tx.Time.Weekday())
 			months[monthKey].DaysOfWeek[dayOfWeek].Proofs += tx.NumRelays
-		}
-		mo.AvgSecsBetweenRewards = totalSecs / numTxs
-		if math.IsNaN(mo.AvgSecsBetweenRewards) {
-			mo.AvgSecsBetweenRewards = 0
+			mo.HoursOfDay[tx.Time.Hour()] += tx.NumRelays
 		}
 
-		mo.TotalSecsBetweenRewards = totalSecs
+		mo.TotalSecsBetweenRewards, mo.AvgSecsBetweenRewards = secsBetweenRewards(mo.Transactions)
 		months[monthKey] = mo
 
 	}
@@ -298,6 +404,110 @@ func (s *Service) RewardsByMonth(address string) (map[string]pocket.MonthlyRewar
 	return months, nil
 }
 
+// RewardsByPeriod buckets the claims for address into periods of the given granularity. Only
+// claims made at or after from and before to are included; a zero from or to leaves that end
+// of the range open. Period boundaries are those of loc, which defaults to UTC. Periods are
+// returned in chronological order.
+func (s *Service) RewardsByPeriod(ctx context.Context, address string, granularity pocket.Granularity, from, to time.Time, loc *time.Location) ([]pocket.PeriodReward, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	claims, err := s.confirmedClaims(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("RewardsByPeriod: %w", err)
+	}
+
+	periods := make(map[string]*pocket.PeriodReward)
+	for _, tx := range claims {
+		tx.Time = tx.Time.In(loc)
+		if !from.IsZero() && tx.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !tx.Time.Before(to) {
+			continue
+		}
+
+		key := granularity.Key(tx.Time)
+		period, exists := periods[key]
+		if !exists {
+			start := granularity.Start(tx.Time)
+			period = &pocket.PeriodReward{
+				Granularity:   granularity,
+				Key:           key,
+				Start:         start,
+				End:           granularity.End(start),
+				RelaysByChain: make(map[string]uint),
+			}
+			periods[key] = period
+		}
+
+		if tx.IsConfirmed {
+			period.TotalProofs += tx.NumRelays
+		}
+		period.RelaysByChain[tx.ChainID] += tx.NumRelays
+		period.Transactions = append(period.Transactions, tx)
+	}
+
+	rewards := make([]pocket.PeriodReward, 0, len(periods))
+	for _, period := range periods {
+		sort.Slice(period.Transactions, func(i, j int) bool {
+			return period.Transactions[i].Time.Before(period.Transactions[j].Time)
+		})
+		period.TotalSecsBetweenRewards, period.AvgSecsBetweenRewards = secsBetweenRewards(period.Transactions)
+		rewards = append(rewards, *period)
+	}
+
+	sort.Slice(rewards, func(i, j int) bool {
+		return rewards[i].Start.Before(rewards[j].Start)
+	})
+
+	return rewards, nil
+}
+
+// confirmedClaims returns every claim for address, with IsConfirmed set when a successful
+// proof was submitted for the same session.
+func (s *Service) confirmedClaims(ctx context.Context, address string) ([]pocket.Transaction, error) {
+	claims, proofs, err := s.AccountClaimsAndProofs(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("confirmedClaims: %w", err)
+	}
+
+	txs := make([]pocket.Transaction, 0, len(claims))
+	for sessionKey, tx := range claims {
+		tx.IsConfirmed = false
+		proof, proofExists := proofs[sessionKey]
+		if proofExists && proof.ResultCode == 0 {
+			tx.IsConfirmed = true
+		}
+
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
+
+// secsBetweenRewards returns the total and average number of seconds between consecutive
+// transactions in txs, which must be sorted by time.
+func secsBetweenRewards(txs []pocket.Transaction) (total, avg float64) {
+	var numTxs = float64(0)
+	var prevTx, emptyTx = pocket.Transaction{}, pocket.Transaction{}
+	for _, tx := range txs {
+		if prevTx != emptyTx {
+			total += tx.Time.Sub(prevTx.Time).Seconds()
+			numTxs++
+		}
+		prevTx = tx
+	}
+
+	avg = total / numTxs
+	if math.IsNaN(avg) {
+		avg = 0
+	}
+
+	return total, avg
+}
+
 func sessionKey(tx pocket.Transaction) string {
 	return fmt.Sprintf("%d%s%s", tx.SessionHeight, tx.AppPubkey, tx.ChainID)
 }
@@ -0,0 +1,135 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"monitoring-service/pocket"
+)
+
+// DefaultSyncTolerance is the number of blocks a node can be behind the network and still count as synced.
+const DefaultSyncTolerance = 2
+
+// nodeHeightTimeout is how long a node's RPC has to answer for its height before it counts as unreachable.
+const nodeHeightTimeout = 5 * time.Second
+
+// HeightProvider reports the latest height known to an RPC.
+type HeightProvider interface {
+	Height(ctx context.Context) (uint, error)
+}
+
+// WithSync returns a copy of s that decides whether nodes are synced against the highest height
+// reported by references, or by its own provider if there are none. A node is synced when its
+// RPC answered and it is at most tolerance blocks behind that height.
+func (s Service) WithSync(references []HeightProvider, tolerance uint) Service {
+	s.syncReferences = references
+	s.syncTolerance = tolerance
+
+	return s
+}
+
+// networkHeight returns the highest height reported by the sync references, which are asked in
+// parallel. It only fails if none of them answered.
+func (s *Service) networkHeight(ctx context.Context) (uint, error) {
+	if len(s.syncReferences) == 0 {
+		return s.Height(ctx)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		highest uint
+		errs    []string
+	)
+	for _, ref := range s.syncReferences {
+		wg.Add(1)
+		go func(ref HeightProvider) {
+			defer wg.Done()
+			height, err := ref.Height(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err.Error())
+				return
+			}
+			if height > highest {
+				highest = height
+			}
+		}(ref)
+	}
+	wg.Wait()
+
+	if len(errs) == len(s.syncReferences) {
+		sort.Strings(errs)
+		return 0, fmt.Errorf("networkHeight: no reference answered: %v", errs)
+	}
+
+	return highest, nil
+}
+
+// syncStatus asks the node's own RPC for its height, and fills in whether it was reachable, its
+// latest block and how far it lags the network.
+func (s *Service) syncStatus(ctx context.Context, node *pocket.Node) {
+	var (
+		wg            sync.WaitGroup
+		networkHeight uint
+		networkErr    error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		networkHeight, networkErr = s.networkHeight(ctx)
+	}()
+
+	nodeHeight, nodeErr := s.nodeHeight(ctx, node.ServiceURL)
+	wg.Wait()
+
+	if nodeErr != nil {
+		node.RPCError = nodeErr.Error()
+	} else {
+		node.IsReachable = true
+		node.LatestBlockHeight = nodeHeight
+	}
+	if networkErr != nil {
+		// without the network's height the node can't be told to be synced
+		log.Default().Printf("ERROR: %+v", networkErr)
+	} else {
+		node.NetworkHeight = networkHeight
+	}
+	if !node.IsReachable {
+		return
+	}
+
+	heights := []uint{node.LatestBlockHeight}
+	if node.NetworkHeight > node.LatestBlockHeight {
+		node.LagBlocks = node.NetworkHeight - node.LatestBlockHeight
+		heights = append(heights, node.NetworkHeight)
+	}
+	node.IsSynced = node.NetworkHeight > 0 && node.LagBlocks <= s.syncTolerance
+
+	blockTimes, err := s.BlockTimes(ctx, heights)
+	if err != nil {
+		log.Default().Printf("ERROR: %+v", err)
+		return
+	}
+	node.LatestBlockTime = blockTimes[node.LatestBlockHeight]
+	if node.LagBlocks > 0 {
+		node.LagSeconds = blockTimes[node.NetworkHeight].Sub(node.LatestBlockTime).Seconds()
+	}
+}
+
+// nodeHeight returns the height reported by the RPC at the node's serviceURL. It is asked once,
+// so that an unreachable node is reported as such within nodeHeightTimeout.
+func (s *Service) nodeHeight(ctx context.Context, serviceURL string) (uint, error) {
+	height, err := s.provider.ServicerProvider(serviceURL, nodeHeightTimeout).Height(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("nodeHeight: %w", err)
+	}
+
+	return height, nil
+}
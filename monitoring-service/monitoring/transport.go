@@ -3,12 +3,14 @@ package monitoring
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"monitoring-service/api"
+	"monitoring-service/pocket"
 
 	"github.com/gorilla/mux"
 )
@@ -16,14 +18,22 @@ import (
 const (
 	heightEndpointPath              = "/height"
 	paramsEndpointPath              = "/params/{height}"
+	paramHistoryEndpointPath        = "/params/history"
+	paramsDiffEndpointPath          = "/params/diff"
 	transactionEndpointPath         = "/transactions/{hash}"
 	nodeEndpointPath                = "/node/{address}"
 	accountTransactionsEndpointPath = "/accounts/{address}/transactions"
 	blockTimesEndpointPath          = "/block-times"
+	estimateBlockTimesEndpointPath  = "/block-times/estimate"
+	timeToHeightEndpointPath        = "/block-times/height"
 	monthlyRewardsEndpointPath      = "/node/{address}/rewards"
+	pendingClaimsEndpointPath       = "/node/{address}/claims/pending"
+	forecastEndpointPath            = "/node/{address}/forecast"
 	simulateRelaysEndpointPath      = "/tests/simulate-relay"
 )
 
+const dateLayout = "2006-01-02"
+
 type transport struct {
 	Service Service
 	Routes  []api.Route
@@ -40,6 +50,21 @@ func NewTransport(svc Service) transport {
 				Decoder:  api.DecodeEmptyRequest,
 				Encoder:  api.EncodeResponse,
 			},
+			// registered before paramsEndpointPath, which would otherwise match them
+			{
+				Method:   http.MethodGet,
+				Path:     paramHistoryEndpointPath,
+				Endpoint: ParamHistoryEndpoint(svc),
+				Decoder:  decodeParamHistoryRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     paramsDiffEndpointPath,
+				Endpoint: ParamsDiffEndpoint(svc),
+				Decoder:  decodeParamsDiffRequest,
+				Encoder:  api.EncodeResponse,
+			},
 			{
 				Path:     paramsEndpointPath,
 				Method:   http.MethodGet,
@@ -75,11 +100,39 @@ func NewTransport(svc Service) transport {
 				Decoder:  decodeBlockTimesRequest,
 				Encoder:  api.EncodeResponse,
 			},
+			{
+				Method:   http.MethodGet,
+				Path:     estimateBlockTimesEndpointPath,
+				Endpoint: EstimateBlockTimesEndpoint(svc),
+				Decoder:  decodeEstimateBlockTimesRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     timeToHeightEndpointPath,
+				Endpoint: TimeToHeightEndpoint(svc),
+				Decoder:  decodeTimeToHeightRequest,
+				Encoder:  api.EncodeResponse,
+			},
 			{
 				Method:   http.MethodGet,
 				Path:     monthlyRewardsEndpointPath,
-				Endpoint: MonthlyRewardsEndpoint(svc),
-				Decoder:  decodeMonthlyRewardsRequest,
+				Endpoint: RewardsEndpoint(svc),
+				Decoder:  decodeRewardsRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     pendingClaimsEndpointPath,
+				Endpoint: PendingClaimsEndpoint(svc),
+				Decoder:  decodeNodeRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodGet,
+				Path:     forecastEndpointPath,
+				Endpoint: ForecastEndpoint(svc),
+				Decoder:  decodeForecastRequest,
 				Encoder:  api.EncodeResponse,
 			},
 			{
@@ -97,11 +150,11 @@ func decodeParamsRequest(_ context.Context, req *http.Request) (request interfac
 	vars := mux.Vars(req)
 	height, ok := vars["height"]
 	if !ok {
-		return nil, errors.New("decodeParamsRequest: required param 'height' not found")
+		return nil, api.InvalidArgument("decodeParamsRequest: required param 'height' not found", api.Details{"param": "height"})
 	}
 	h, err := strconv.ParseInt(height, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("decodeParamsRequest: failed to parse height: %s", err)
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeParamsRequest: failed to parse height: %s", err), api.Details{"param": "height", "value": height})
 	}
 
 	var forceRefresh bool
@@ -109,7 +162,7 @@ func decodeParamsRequest(_ context.Context, req *http.Request) (request interfac
 	if ok && len(force[0]) > 0 {
 		forceRefresh, err = strconv.ParseBool(force[0])
 		if err != nil {
-			return nil, fmt.Errorf("decodeParamsRequest: failed to parse refresh param: %s", err)
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeParamsRequest: failed to parse refresh param: %s", err), api.Details{"param": "refresh", "value": force[0]})
 		}
 	}
 
@@ -118,11 +171,58 @@ func decodeParamsRequest(_ context.Context, req *http.Request) (request interfac
 		ForceRefresh: forceRefresh,
 	}, nil
 }
+
+// decodeParamHistoryRequest decodes a request for the changes of the param key between the from
+// and to heights. from defaults to the first block and to to the current height.
+func decodeParamHistoryRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	query := req.URL.Query()
+	key := query.Get("key")
+	if key == "" {
+		return nil, api.InvalidArgument("decodeParamHistoryRequest: required param 'key' not found", api.Details{"param": "key"})
+	}
+
+	historyReq := paramHistoryRequest{Key: key}
+	for param, height := range map[string]*uint{"from": &historyReq.From, "to": &historyReq.To} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+
+		h, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeParamHistoryRequest: failed to parse %s: %s", param, err), api.Details{"param": param, "value": v})
+		}
+		*height = uint(h)
+	}
+
+	return historyReq, nil
+}
+
+func decodeParamsDiffRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	query := req.URL.Query()
+
+	var diffReq paramsDiffRequest
+	for param, height := range map[string]*uint{"a": &diffReq.A, "b": &diffReq.B} {
+		v := query.Get(param)
+		if v == "" {
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeParamsDiffRequest: required param '%s' not found", param), api.Details{"param": param})
+		}
+
+		h, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeParamsDiffRequest: failed to parse %s: %s", param, err), api.Details{"param": param, "value": v})
+		}
+		*height = uint(h)
+	}
+
+	return diffReq, nil
+}
+
 func decodeTransactionRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
 	vars := mux.Vars(req)
 	hash, ok := vars["hash"]
 	if !ok {
-		return nil, errors.New("decodeTransactionRequest: required param 'hash' not found")
+		return nil, api.InvalidArgument("decodeTransactionRequest: required param 'hash' not found", api.Details{"param": "hash"})
 	}
 
 	return transactionRequest{Hash: hash}, nil
@@ -132,7 +232,7 @@ func decodeAccountTransactionsRequest(ctx context.Context, req *http.Request) (r
 	vars := mux.Vars(req)
 	address, ok := vars["address"]
 	if !ok {
-		return nil, errors.New("decodeTransactionRequest: required param 'address' not found")
+		return nil, api.InvalidArgument("decodeAccountTransactionsRequest: required param 'address' not found", api.Details{"param": "address"})
 	}
 
 	var page uint64
@@ -140,7 +240,7 @@ func decodeAccountTransactionsRequest(ctx context.Context, req *http.Request) (r
 	if reqPage != "" {
 		page, err = strconv.ParseUint(reqPage, 10, 32)
 		if err != nil {
-			return nil, fmt.Errorf("decodeAccountTransactionsRequest: %s", err)
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeAccountTransactionsRequest: failed to parse page: %s", err), api.Details{"param": "page", "value": reqPage})
 		}
 	}
 
@@ -149,7 +249,7 @@ func decodeAccountTransactionsRequest(ctx context.Context, req *http.Request) (r
 	if reqPerPage != "" {
 		perPage, err = strconv.ParseUint(reqPerPage, 10, 32)
 		if err != nil {
-			return nil, fmt.Errorf("decodeAccountTransactionsRequest: %s", err)
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeAccountTransactionsRequest: failed to parse per_page: %s", err), api.Details{"param": "per_page", "value": reqPerPage})
 		}
 	}
 
@@ -170,7 +270,7 @@ func decodeNodeRequest(_ context.Context, req *http.Request) (request interface{
 	vars := mux.Vars(req)
 	address, ok := vars["address"]
 	if !ok {
-		return nil, errors.New("decodeNodeRequest: required param 'address' not found")
+		return nil, api.InvalidArgument("decodeNodeRequest: required param 'address' not found", api.Details{"param": "address"})
 	}
 
 	return nodeRequest{Address: address}, nil
@@ -179,26 +279,189 @@ func decodeNodeRequest(_ context.Context, req *http.Request) (request interface{
 func decodeBlockTimesRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
 	var blockHeights blockTimesRequest
 	if err := json.NewDecoder(req.Body).Decode(&blockHeights); err != nil {
-		return nil, fmt.Errorf("decodeBlockTimesRequest: %s", err)
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeBlockTimesRequest: invalid request body: %s", err), nil)
 	}
 
 	return blockHeights, nil
 }
 
-func decodeMonthlyRewardsRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+// maxEstimateHeights bounds the heights that can be estimated in one request.
+const maxEstimateHeights = 1000
+
+// decodeEstimateBlockTimesRequest decodes a comma separated list of heights.
+func decodeEstimateBlockTimesRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	list := req.URL.Query().Get("heights")
+	if list == "" {
+		return nil, api.InvalidArgument("decodeEstimateBlockTimesRequest: required param 'heights' not found", api.Details{"param": "heights"})
+	}
+
+	values := strings.Split(list, ",")
+	if len(values) > maxEstimateHeights {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeEstimateBlockTimesRequest: at most %d heights can be estimated at once", maxEstimateHeights), api.Details{"param": "heights"})
+	}
+
+	heights := make([]uint, len(values))
+	for i, v := range values {
+		h, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil || h == 0 {
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeEstimateBlockTimesRequest: invalid height '%s'", v), api.Details{"param": "heights", "value": v})
+		}
+		heights[i] = uint(h)
+	}
+
+	return estimateBlockTimesRequest{Heights: heights}, nil
+}
+
+// decodeTimeToHeightRequest decodes time, an RFC 3339 time or a date in the tz time zone.
+func decodeTimeToHeightRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	query := req.URL.Query()
+	loc, err := parseLocationParam(query.Get("tz"))
+	if err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeTimeToHeightRequest: failed to parse tz: %s", err), api.Details{"param": "tz", "value": query.Get("tz")})
+	}
+
+	value := query.Get("time")
+	if value == "" {
+		return nil, api.InvalidArgument("decodeTimeToHeightRequest: required param 'time' not found", api.Details{"param": "time"})
+	}
+
+	t, err := parseTimeParam(value, false, loc)
+	if err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeTimeToHeightRequest: failed to parse time: %s", err), api.Details{"param": "time", "value": value})
+	}
+
+	return timeToHeightRequest{Time: t}, nil
+}
+
+// decodeRewardsRequest decodes a request for monthly rewards, or for rewards by period when the
+// period param is set. from and to accept RFC 3339 times or dates; a date given for to includes
+// that whole day. tz is an IANA time zone name that rewards are bucketed in, and dates are read in.
+func decodeRewardsRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
 	vars := mux.Vars(req)
 	address, ok := vars["address"]
 	if !ok {
-		return nil, errors.New("decodeMonthlyRewardsRequest: required param 'address' not found")
+		return nil, api.InvalidArgument("decodeRewardsRequest: required param 'address' not found", api.Details{"param": "address"})
+	}
+
+	query := req.URL.Query()
+	loc, err := parseLocationParam(query.Get("tz"))
+	if err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeRewardsRequest: failed to parse tz: %s", err), api.Details{"param": "tz", "value": query.Get("tz")})
+	}
+
+	period := query.Get("period")
+	if period == "" {
+		return monthlyRewardsRequest{Address: address, Location: loc}, nil
+	}
+
+	granularity, err := pocket.ParseGranularity(period)
+	if err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeRewardsRequest: %s", err), api.Details{"param": "period", "value": period})
+	}
+
+	from, err := parseTimeParam(query.Get("from"), false, loc)
+	if err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeRewardsRequest: failed to parse from: %s", err), api.Details{"param": "from", "value": query.Get("from")})
+	}
+
+	to, err := parseTimeParam(query.Get("to"), true, loc)
+	if err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeRewardsRequest: failed to parse to: %s", err), api.Details{"param": "to", "value": query.Get("to")})
+	}
+
+	return periodRewardsRequest{
+		Address:     address,
+		Granularity: granularity,
+		From:        from,
+		To:          to,
+		Location:    loc,
+	}, nil
+}
+
+// decodeForecastRequest decodes a forecast request. lookback_days sets the days of history the
+// relay rate is taken from, and rttm, dao_allocation and proposer_percentage override the
+// network's current reward params.
+func decodeForecastRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	vars := mux.Vars(req)
+	address, ok := vars["address"]
+	if !ok {
+		return nil, api.InvalidArgument("decodeForecastRequest: required param 'address' not found", api.Details{"param": "address"})
+	}
+
+	query := req.URL.Query()
+	forecastReq := forecastRequest{Address: address}
+
+	if v := query.Get("lookback_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 {
+			return nil, api.InvalidArgument("decodeForecastRequest: lookback_days must be a positive number of days", api.Details{"param": "lookback_days", "value": v})
+		}
+		forecastReq.LookbackDays = days
+	}
+
+	if v := query.Get("rttm"); v != "" {
+		rttm, err := strconv.ParseFloat(v, 64)
+		if err != nil || rttm < 0 {
+			return nil, api.InvalidArgument("decodeForecastRequest: rttm must be a non-negative number", api.Details{"param": "rttm", "value": v})
+		}
+		forecastReq.Overrides.RelaysToTokensMultiplier = &rttm
+	}
+
+	for param, override := range map[string]**uint8{
+		"dao_allocation":      &forecastReq.Overrides.DaoAllocation,
+		"proposer_percentage": &forecastReq.Overrides.ProposerPercentage,
+	} {
+		v := query.Get(param)
+		if v == "" {
+			continue
+		}
+
+		pct, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || pct > 100 {
+			return nil, api.InvalidArgument(fmt.Sprintf("decodeForecastRequest: %s must be a percentage from 0 to 100", param), api.Details{"param": param, "value": v})
+		}
+		p := uint8(pct)
+		*override = &p
+	}
+
+	return forecastReq, nil
+}
+
+// parseTimeParam parses an RFC 3339 time or a YYYY-MM-DD date in loc. When endOfDay is set, a date
+// is moved to the start of the following day so that a range ending on it includes the whole day.
+func parseTimeParam(value string, endOfDay bool, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date (%s) or an RFC 3339 time", dateLayout)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
+
+// parseLocationParam loads the IANA time zone named by value, defaulting to UTC.
+func parseLocationParam(value string) (*time.Location, error) {
+	if value == "" {
+		return time.UTC, nil
 	}
 
-	return monthlyRewardsRequest{Address: address}, nil
+	return time.LoadLocation(value)
 }
 
 func decodeSimulateRelaysRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
 	var simRequest relayRequest
 	if err := json.NewDecoder(req.Body).Decode(&simRequest); err != nil {
-		return nil, fmt.Errorf("decodeSimulateRelayRequest: %s", err)
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeSimulateRelayRequest: invalid request body: %s", err), nil)
 	}
 
 	return simRequest, nil
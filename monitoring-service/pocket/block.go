@@ -0,0 +1,11 @@
+package pocket
+
+import "time"
+
+// BlockEstimate pairs a height with a time. IsEstimated is set when the time wasn't read from a
+// block, but interpolated or extrapolated from the blocks around it.
+type BlockEstimate struct {
+	Height      uint
+	Time        time.Time
+	IsEstimated bool
+}
@@ -0,0 +1,41 @@
+package pocket
+
+import "time"
+
+// TargetBlockTime is the time between blocks the network aims for.
+const TargetBlockTime = 15 * time.Minute
+
+// UnprovenClaim is a claim that has no successful proof.
+type UnprovenClaim struct {
+	Claim              Transaction
+	Proof              *Transaction
+	BlocksUntilExpiry  uint
+	EstimatedExpiresAt time.Time
+}
+
+// ClaimsStatus splits the claims of a node that have no successful proof into those that can
+// still be proven, those that expired, and those whose proof failed.
+type ClaimsStatus struct {
+	Height       uint
+	BlockTime    time.Duration
+	Pending      []UnprovenClaim
+	Expired      []UnprovenClaim
+	FailedProofs []UnprovenClaim
+}
+
+func (s ClaimsStatus) PendingPoktAmount() float64 {
+	return claimsPoktAmount(s.Pending)
+}
+
+// LostPoktAmount is the reward lost to claims that expired or whose proof failed.
+func (s ClaimsStatus) LostPoktAmount() float64 {
+	return claimsPoktAmount(s.Expired) + claimsPoktAmount(s.FailedProofs)
+}
+
+func claimsPoktAmount(claims []UnprovenClaim) float64 {
+	var total float64
+	for _, c := range claims {
+		total += c.Claim.PoktAmount()
+	}
+	return total
+}
@@ -0,0 +1,11 @@
+package pocket
+
+import "time"
+
+// Fleet is a named group of node addresses that are reported on together.
+type Fleet struct {
+	Name      string
+	Addresses []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,62 @@
+package pocket
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParamOverrides replaces some of the reward params when forecasting, to answer what-if questions
+// such as a change to the RelaysToTokensMultiplier. Nil fields keep the network's value.
+type ParamOverrides struct {
+	RelaysToTokensMultiplier *float64
+	DaoAllocation            *uint8
+	ProposerPercentage       *uint8
+}
+
+func (o ParamOverrides) Apply(p Params) (Params, error) {
+	if o.RelaysToTokensMultiplier != nil {
+		p.RelaysToTokensMultiplier = *o.RelaysToTokensMultiplier
+	}
+	if o.DaoAllocation != nil {
+		p.DaoAllocation = *o.DaoAllocation
+	}
+	if o.ProposerPercentage != nil {
+		p.ProposerPercentage = *o.ProposerPercentage
+	}
+
+	if int(p.DaoAllocation)+int(p.ProposerPercentage) > 100 {
+		return Params{}, fmt.Errorf("ParamOverrides.Apply: DAO allocation %d and proposer percentage %d exceed 100", p.DaoAllocation, p.ProposerPercentage)
+	}
+
+	return p, nil
+}
+
+func (o ParamOverrides) IsEmpty() bool {
+	return o.RelaysToTokensMultiplier == nil && o.DaoAllocation == nil && o.ProposerPercentage == nil
+}
+
+// Forecast projects a node's relays and rewards from its recent daily relay rate.
+type Forecast struct {
+	From               time.Time
+	To                 time.Time
+	NumDays            int
+	Params             Params
+	StakedBalance      uint
+	DailyRelays        float64
+	DailyRelaysStdDev  float64
+	DailyRelaysByChain map[string]float64
+	Projections        []Projection
+}
+
+// Projection is the expected number of relays and POKT over the next Days days, with the bounds
+// of a confidence band around them.
+type Projection struct {
+	Days          int
+	Relays        float64
+	RelaysLow     float64
+	RelaysHigh    float64
+	PoktAmount    float64
+	PoktLow       float64
+	PoktHigh      float64
+	RelaysByChain map[string]float64
+}
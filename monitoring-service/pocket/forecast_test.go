@@ -0,0 +1,59 @@
+package pocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParamOverridesApply(t *testing.T) {
+	network := Params{RelaysToTokensMultiplier: 10000, DaoAllocation: 10, ProposerPercentage: 5, ClaimExpirationBlocks: 120}
+	float := func(v float64) *float64 { return &v }
+	percent := func(v uint8) *uint8 { return &v }
+
+	tests := []struct {
+		name      string
+		overrides ParamOverrides
+		want      Params
+		wantErr   bool
+	}{
+		{"none", ParamOverrides{}, network, false},
+		{
+			"multiplier",
+			ParamOverrides{RelaysToTokensMultiplier: float(20000)},
+			Params{RelaysToTokensMultiplier: 20000, DaoAllocation: 10, ProposerPercentage: 5, ClaimExpirationBlocks: 120},
+			false,
+		},
+		{
+			"every param",
+			ParamOverrides{RelaysToTokensMultiplier: float(5000), DaoAllocation: percent(20), ProposerPercentage: percent(1)},
+			Params{RelaysToTokensMultiplier: 5000, DaoAllocation: 20, ProposerPercentage: 1, ClaimExpirationBlocks: 120},
+			false,
+		},
+		{
+			"exactly 100",
+			ParamOverrides{DaoAllocation: percent(95)},
+			Params{RelaysToTokensMultiplier: 10000, DaoAllocation: 95, ProposerPercentage: 5, ClaimExpirationBlocks: 120},
+			false,
+		},
+		{"with the network's proposer percentage over 100", ParamOverrides{DaoAllocation: percent(96)}, Params{}, true},
+		{"with the network's DAO allocation over 100", ParamOverrides{ProposerPercentage: percent(91)}, Params{}, true},
+		{"together over 100", ParamOverrides{DaoAllocation: percent(60), ProposerPercentage: percent(41)}, Params{}, true},
+		// uint8s whose sum would wrap around
+		{"together over 255", ParamOverrides{DaoAllocation: percent(200), ProposerPercentage: percent(100)}, Params{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.overrides.Apply(network)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply: %v, want error %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply: %+v, want %+v", got, tt.want)
+			}
+			if tt.overrides.IsEmpty() != (tt.name == "none") {
+				t.Errorf("IsEmpty: %t", tt.overrides.IsEmpty())
+			}
+		})
+	}
+}
@@ -13,6 +13,14 @@ type Node struct {
 	IsSynced          bool
 	LatestBlockHeight uint
 	LatestBlockTime   time.Time
+	// IsReachable is whether the node's own RPC answered for its height, and RPCError why not.
+	IsReachable bool
+	RPCError    string
+	// NetworkHeight is the highest height of the reference RPCs that IsSynced is decided against,
+	// and LagBlocks and LagSeconds are how far the node's latest block is behind it.
+	NetworkHeight uint
+	LagBlocks     uint
+	LagSeconds    float64
 }
 
 type Session struct {
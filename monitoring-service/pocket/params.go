@@ -1,12 +1,22 @@
 package pocket
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type Params struct {
 	RelaysToTokensMultiplier float64
 	DaoAllocation            uint8
 	ProposerPercentage       uint8
 	ClaimExpirationBlocks    uint
+
+	// The servicer stake weighting params are only set at heights where they exist on the network.
+	IsStakeWeighted                      bool
+	ServicerStakeWeightMultiplier        float64
+	ServicerStakeFloorMultiplier         uint
+	ServicerStakeFloorMultiplierExponent float64
+	ServicerStakeWeightCeiling           uint
 }
 
 type AllParams struct {
@@ -41,6 +51,36 @@ func (a AllParams) Validate() error {
 	return nil
 }
 
+// ParamGroupNames are the groups of AllParams, in the order the network returns them.
+var ParamGroupNames = []string{"app_params", "auth_params", "gov_params", "node_params", "pocket_params"}
+
+// Group returns the params in the group with the given name, e.g. node_params.
+func (a AllParams) Group(name string) ParamGroup {
+	switch name {
+	case "app_params":
+		return a.AppParams
+	case "auth_params":
+		return a.AuthParams
+	case "gov_params":
+		return a.GovParams
+	case "node_params":
+		return a.NodeParams
+	case "pocket_params":
+		return a.PocketParams
+	}
+	return nil
+}
+
+// Get looks k up in every group.
+func (a AllParams) Get(k string) (string, bool) {
+	for _, name := range ParamGroupNames {
+		if v, ok := a.Group(name).Get(k); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
 type ParamGroup []Param
 
 func (a ParamGroup) Get(k string) (string, bool) {
@@ -57,6 +97,53 @@ type Param struct {
 	Value string `json:"param_value"`
 }
 
+// PoktPerRelay is the reward per relay before stake weighting. Where rewards are weighted by stake a
+// node earns StakeWeight times this, which for a node staked at the floor is 1/ServicerStakeWeightMultiplier.
 func (p Params) PoktPerRelay() float64 {
 	return (p.RelaysToTokensMultiplier / 1000000) * (float64(100-p.DaoAllocation-p.ProposerPercentage) / 100)
 }
+
+// PoktPerRelayForStake is the reward per relay for a node with stakedBalance uPOKT staked. A zero
+// stakedBalance means the stake is unknown, and the reward isn't weighted.
+func (p Params) PoktPerRelayForStake(stakedBalance uint) float64 {
+	return p.PoktPerRelay() * p.StakeWeight(stakedBalance)
+}
+
+// StakeWeight returns the multiplier applied to the rewards of a node with stakedBalance uPOKT staked.
+// The stake is rounded down to a multiple of the floor and capped at the ceiling, and the resulting
+// number of floors (the node's bin) is raised to the exponent and divided by the weight multiplier.
+func (p Params) StakeWeight(stakedBalance uint) float64 {
+	if !p.IsStakeWeighted || stakedBalance == 0 || p.ServicerStakeFloorMultiplier == 0 || p.ServicerStakeWeightMultiplier == 0 {
+		return 1
+	}
+
+	floor := p.ServicerStakeFloorMultiplier
+	ceiling := p.ServicerStakeWeightCeiling - p.ServicerStakeWeightCeiling%floor
+	flooredStake := stakedBalance - stakedBalance%floor
+	if ceiling > 0 && flooredStake > ceiling {
+		flooredStake = ceiling
+	}
+
+	bin := float64(flooredStake / floor)
+	return math.Pow(bin, p.ServicerStakeFloorMultiplierExponent) / p.ServicerStakeWeightMultiplier
+}
+
+// ParamChange is a change to the value of a param, first seen at Height.
+type ParamChange struct {
+	Key            string
+	Height         uint
+	Value          string
+	Exists         bool
+	PreviousValue  string
+	PreviousExists bool
+}
+
+// ParamDiff is a param whose value differs between two heights.
+type ParamDiff struct {
+	Group   string
+	Key     string
+	A       string
+	AExists bool
+	B       string
+	BExists bool
+}
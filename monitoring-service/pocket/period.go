@@ -0,0 +1,101 @@
+package pocket
+
+import (
+	"fmt"
+	"time"
+)
+
+type Granularity string
+
+const (
+	GranularityDay     Granularity = "day"
+	GranularityWeek    Granularity = "week"
+	GranularityMonth   Granularity = "month"
+	GranularityQuarter Granularity = "quarter"
+	GranularityYear    Granularity = "year"
+)
+
+func ParseGranularity(s string) (Granularity, error) {
+	switch g := Granularity(s); g {
+	case GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter, GranularityYear:
+		return g, nil
+	}
+
+	return "", fmt.Errorf("ParseGranularity: unknown period '%s'", s)
+}
+
+// Start returns the start of the period containing t, in t's location. Weeks are ISO weeks,
+// which start on a Monday.
+func (g Granularity) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+
+	switch g {
+	case GranularityWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-daysSinceMonday, 0, 0, 0, 0, loc)
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case GranularityQuarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)
+	case GranularityYear:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+}
+
+// End returns the start of the period following the one that starts at start.
+func (g Granularity) End(start time.Time) time.Time {
+	switch g {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	case GranularityQuarter:
+		return start.AddDate(0, 3, 0)
+	case GranularityYear:
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Key returns a label for the period containing t, e.g. 2022-03-14, 2022-W11, 2022-03, 2022-Q1 or 2022.
+func (g Granularity) Key(t time.Time) string {
+	switch g {
+	case GranularityWeek:
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	case GranularityMonth:
+		return fmt.Sprintf("%d-%02d", t.Year(), t.Month())
+	case GranularityQuarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case GranularityYear:
+		return fmt.Sprintf("%d", t.Year())
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+type PeriodReward struct {
+	Granularity             Granularity
+	Key                     string
+	Start                   time.Time
+	End                     time.Time
+	TotalProofs             uint
+	AvgSecsBetweenRewards   float64
+	TotalSecsBetweenRewards float64
+	RelaysByChain           map[string]uint
+	Transactions            []Transaction
+}
+
+func (r *PeriodReward) PoktAmount() float64 {
+	var total float64
+	for _, t := range r.Transactions {
+		if t.IsConfirmed {
+			total += t.PoktAmount()
+		}
+	}
+	return total
+}
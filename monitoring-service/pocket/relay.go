@@ -0,0 +1,10 @@
+package pocket
+
+// Relay is a request for one of the chains a node serves. Path is appended to the URL the node
+// has configured for the chain.
+type Relay struct {
+	ChainID string
+	Method  string
+	Path    string
+	Data    string
+}
@@ -7,6 +7,7 @@ type MonthlyReward struct {
 	AvgSecsBetweenRewards   float64
 	TotalSecsBetweenRewards float64
 	DaysOfWeek              map[int]*DayOfWeek
+	HoursOfDay              [24]uint
 	Transactions            []Transaction
 }
 
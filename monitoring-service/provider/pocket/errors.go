@@ -0,0 +1,134 @@
+package pocket
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// UpstreamError classifies a failed call to the Pocket RPC. The exported Err values can be
+// checked with errors.Is; StatusCode lets the transport choose a matching HTTP status.
+type UpstreamError struct {
+	msg  string
+	code int
+}
+
+func (e *UpstreamError) Error() string {
+	return e.msg
+}
+
+func (e *UpstreamError) StatusCode() int {
+	return e.code
+}
+
+var (
+	ErrNotFound            = &UpstreamError{msg: "upstream: not found", code: http.StatusNotFound}
+	ErrRateLimited         = &UpstreamError{msg: "upstream: rate limited", code: http.StatusTooManyRequests}
+	ErrUpstreamUnavailable = &UpstreamError{msg: "upstream: unavailable", code: http.StatusBadGateway}
+	ErrBadRequest          = &UpstreamError{msg: "upstream: bad request", code: http.StatusBadRequest}
+)
+
+type statusError struct {
+	StatusCode int
+	Status     string
+	URL        string
+	Message    string
+}
+
+func (e statusError) Error() string {
+	msg := "pocketProvider.doRequest: got unexpected response status " + e.Status + " from " + e.URL
+	if e.Message != "" {
+		msg += ": " + strings.TrimSpace(e.Message)
+	}
+	return msg
+}
+
+func (e statusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	// pocket core answers queries for unknown txs and nodes with a 400 and a "not found" message
+	case e.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(e.Message), "not found"):
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrUpstreamUnavailable
+	default:
+		return ErrBadRequest
+	}
+}
+
+// transportError is a request that never got a response, e.g. a refused connection or a timeout.
+type transportError struct {
+	err error
+}
+
+func (e transportError) Error() string {
+	return "pocketProvider.doRequest: " + e.err.Error()
+}
+
+func (e transportError) Unwrap() error {
+	return e.err
+}
+
+func (e transportError) Is(target error) bool {
+	return target == ErrUpstreamUnavailable
+}
+
+// StatusCode implements kithttp.StatusCoder, so that the API answers a request that never reached
+// the upstream like ErrUpstreamUnavailable.
+func (e transportError) StatusCode() int {
+	return ErrUpstreamUnavailable.StatusCode()
+}
+
+// StatusCode returns the HTTP status the upstream answered a failed call with, if it answered at all.
+func StatusCode(err error) (int, bool) {
+	var se statusError
+	if errors.As(err, &se) {
+		return se.StatusCode, true
+	}
+
+	return 0, false
+}
+
+// poolError is every endpoint of a pool failing a call. Its message lists the error of each
+// endpoint, and it unwraps to the last one, so that it is retried and reported like that error.
+type poolError struct {
+	errs []string
+	last error
+}
+
+func (e poolError) Error() string {
+	return "EndpointPool: all endpoints failed: " + strings.Join(e.errs, "; ")
+}
+
+func (e poolError) Unwrap() error {
+	return e.last
+}
+
+// isEndpointFailure reports whether err means the endpoint itself is unhealthy, as opposed
+// to the request being one no endpoint would be able to serve, or the endpoint asking us to slow down.
+func isEndpointFailure(err error) bool {
+	return isRetryable(err) && !isRateLimited(err)
+}
+
+// isRateLimited reports whether err is the upstream answering with a 429.
+func isRateLimited(err error) bool {
+	var se statusError
+	return errors.As(err, &se) && se.StatusCode == http.StatusTooManyRequests
+}
+
+// isRetryable reports whether a call that failed with err may succeed if tried again.
+func isRetryable(err error) bool {
+	var se statusError
+	var te transportError
+	switch {
+	case errors.As(err, &se):
+		return se.StatusCode >= http.StatusInternalServerError || se.StatusCode == http.StatusTooManyRequests
+	case errors.As(err, &te):
+		return true
+	default:
+		return false
+	}
+}
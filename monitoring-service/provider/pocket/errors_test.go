@@ -0,0 +1,78 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"monitoring-service/api"
+)
+
+// encodedError returns the status and code the API answers err with.
+func encodedError(t *testing.T, err error) (int, api.ErrorCode) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	api.EncodeError(context.Background(), fmt.Errorf("SomeEndpoint: %w", err), w)
+
+	var body struct {
+		Error struct {
+			Code api.ErrorCode `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding error response %q: %v", w.Body.String(), err)
+	}
+
+	return w.Code, body.Error.Code
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantSentinel error
+		wantStatus   int
+		wantCode     api.ErrorCode
+	}{
+		{"not found", statusError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}, ErrNotFound, http.StatusNotFound, api.CodeNotFound},
+		{"bad request not found", statusError{StatusCode: http.StatusBadRequest, Message: "node not found"}, ErrNotFound, http.StatusNotFound, api.CodeNotFound},
+		{"rate limited", statusError{StatusCode: http.StatusTooManyRequests}, ErrRateLimited, http.StatusTooManyRequests, api.CodeRateLimited},
+		{"server error", statusError{StatusCode: http.StatusServiceUnavailable}, ErrUpstreamUnavailable, http.StatusBadGateway, api.CodeUpstreamUnavailable},
+		{"bad request", statusError{StatusCode: http.StatusBadRequest, Message: "invalid height"}, ErrBadRequest, http.StatusBadRequest, api.CodeInvalidArgument},
+		{"transport", transportError{err: errors.New("connection refused")}, ErrUpstreamUnavailable, http.StatusBadGateway, api.CodeUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.wantSentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", tt.err, tt.wantSentinel)
+			}
+
+			status, code := encodedError(t, tt.err)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUnreachableUpstreamStatus(t *testing.T) {
+	// nothing listens on port 1
+	pool := NewEndpointPool([]string{"http://127.0.0.1:1"}, time.Minute, time.Second)
+	provider := NewPocketProvider(&http.Client{}, pool, RetryPolicy{MaxAttempts: 1}, nil, nil, nil)
+
+	_, err := provider.Height(context.Background())
+	if err == nil {
+		t.Fatal("Height of an unreachable endpoint: nil error")
+	}
+
+	if status, code := encodedError(t, err); status != http.StatusBadGateway || code != api.CodeUpstreamUnavailable {
+		t.Errorf("got %d %s, want %d %s", status, code, http.StatusBadGateway, api.CodeUpstreamUnavailable)
+	}
+}
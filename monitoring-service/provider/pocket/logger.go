@@ -1,6 +1,7 @@
 package pocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"monitoring-service/pocket"
@@ -34,116 +35,194 @@ func (p loggingProvider) WithLogger(l log.Logger) Provider {
 	}
 }
 
-func (p loggingProvider) NodeProvider(addr string) (Provider, error) {
-	return p.provider.NodeProvider(addr)
+func (p loggingProvider) NodeProvider(ctx context.Context, addr string) (Provider, error) {
+	return p.provider.NodeProvider(ctx, addr)
 }
 
-func (p loggingProvider) Height() (uint, error) {
+func (p loggingProvider) ServicerProvider(serviceURL string, timeout time.Duration) Provider {
+	return p.provider.ServicerProvider(serviceURL, timeout)
+}
+
+func (p loggingProvider) Height(ctx context.Context) (uint, error) {
 	t := timer.Start()
-	h, err := p.provider.Height()
+	ctx, via := withServedBy(ctx)
+	h, err := p.provider.Height(ctx)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
+		return 0, err
 	}
 
-	p.info("Height is %d (took %s)", h, t.Elapsed().String())
+	p.info("Height is %d via %s (took %s)", h, via, t.Elapsed().String())
 	return h, nil
 }
 
-func (p loggingProvider) AllParams(height int64, forceRefresh bool) (pocket.AllParams, error) {
+func (p loggingProvider) AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error) {
 	t := timer.Start()
-	params, err := p.provider.AllParams(height, forceRefresh)
+	ctx, via := withServedBy(ctx)
+	params, err := p.provider.AllParams(ctx, height, forceRefresh)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
+		return pocket.AllParams{}, err
 	}
 
-	p.info("AllParams at height %d (took %s)", height, t.Elapsed().String())
+	p.info("AllParams at height %d via %s (took %s)", height, via, t.Elapsed().String())
 	return params, nil
 }
 
-func (p loggingProvider) Param(name string, height int64) (string, error) {
+func (p loggingProvider) Param(ctx context.Context, name string, height int64) (string, error) {
 	t := timer.Start()
-	param, err := p.provider.Param(name, height)
+	ctx, via := withServedBy(ctx)
+	param, err := p.provider.Param(ctx, name, height)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
+		return "", err
 	}
 
-	p.info("Param %s at height %d is %s (took %s)", name, height, param, t.Elapsed().String())
+	p.info("Param %s at height %d is %s via %s (took %s)", name, height, param, via, t.Elapsed().String())
 	return param, nil
 }
 
-func (p loggingProvider) Node(address string) (pocket.Node, error) {
+func (p loggingProvider) Node(ctx context.Context, address string) (pocket.Node, error) {
+	t := timer.Start()
+	ctx, via := withServedBy(ctx)
+	n, err := p.provider.Node(ctx, address)
+	if err != nil {
+		p.failed(ctx, via, err)
+		return pocket.Node{}, err
+	}
+
+	p.info("Node for address %s via %s (took %s)", address, via, t.Elapsed().String())
+	return n, nil
+}
+
+func (p loggingProvider) NodeAtHeight(ctx context.Context, address string, height int64) (pocket.Node, error) {
 	t := timer.Start()
-	n, err := p.provider.Node(address)
+	ctx, via := withServedBy(ctx)
+	n, err := p.provider.NodeAtHeight(ctx, address, height)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
 		return pocket.Node{}, err
 	}
 
-	p.info("Node for address %s (took %s)", address, t.Elapsed().String())
+	p.info("Node for address %s at height %d via %s (took %s)", address, height, via, t.Elapsed().String())
 	return n, nil
 }
 
-func (p loggingProvider) Balance(address string) (uint, error) {
+func (p loggingProvider) StakeAtHeight(ctx context.Context, address string, height uint) (uint, error) {
+	t := timer.Start()
+	ctx, via := withServedBy(ctx)
+	stake, err := p.provider.StakeAtHeight(ctx, address, height)
+	if err != nil {
+		p.failed(ctx, via, err)
+		return 0, err
+	}
+
+	p.info("Stake of %s at height %d via %s (took %s)", address, height, via, t.Elapsed().String())
+	return stake, nil
+}
+
+func (p loggingProvider) Balance(ctx context.Context, address string) (uint, error) {
 	t := timer.Start()
-	b, err := p.provider.Balance(address)
+	ctx, via := withServedBy(ctx)
+	b, err := p.provider.Balance(ctx, address)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
 		return 0, err
 	}
 
-	p.info("Balance for address %s is %d (took %s)", address, b, t.Elapsed().String())
+	p.info("Balance for address %s is %d via %s (took %s)", address, b, via, t.Elapsed().String())
 	return b, nil
 }
 
-func (p loggingProvider) BlockTime(height uint) (time.Time, error) {
+func (p loggingProvider) BlockTime(ctx context.Context, height uint) (time.Time, error) {
 	//t := timer.Start()
-	bt, err := p.provider.BlockTime(height)
+	ctx, via := withServedBy(ctx)
+	bt, err := p.provider.BlockTime(ctx, height)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
 		return time.Time{}, err
 	}
 
-	//p.info("BlockTime for %d (took %s)", height, t.Elapsed().String())
+	//p.info("BlockTime for %d via %s (took %s)", height, via, t.Elapsed().String())
 	return bt, nil
 }
 
-func (p loggingProvider) Transaction(hash string) (pocket.Transaction, error) {
+func (p loggingProvider) CachedBlockTime(height uint) (time.Time, bool, error) {
+	return p.provider.CachedBlockTime(height)
+}
+
+func (p loggingProvider) CachedBlockTimes(from, to uint, fn func(height uint, t time.Time) error) error {
+	return p.provider.CachedBlockTimes(from, to, fn)
+}
+
+func (p loggingProvider) Transaction(ctx context.Context, hash string) (pocket.Transaction, error) {
 	t := timer.Start()
-	tx, err := p.provider.Transaction(hash)
+	ctx, via := withServedBy(ctx)
+	tx, err := p.provider.Transaction(ctx, hash)
 	if err != nil {
-		p.error(err.Error())
-		return pocket.Transaction{}, nil
+		p.failed(ctx, via, err)
+		return pocket.Transaction{}, err
 	}
 
-	p.info("Transaction hash %s (took %s)", hash, t.Elapsed().String())
+	p.info("Transaction hash %s via %s (took %s)", hash, via, t.Elapsed().String())
 	return tx, nil
 }
 
-func (p loggingProvider) AccountTransactions(address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error) {
+func (p loggingProvider) AccountTransactions(ctx context.Context, address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error) {
 	t := timer.Start()
-	txs, err := p.provider.AccountTransactions(address, page, perPage, sort)
+	ctx, via := withServedBy(ctx)
+	txs, err := p.provider.AccountTransactions(ctx, address, page, perPage, sort)
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
 		return nil, err
 	}
 
-	p.info("AccountTransactions for address %s page %d: %d results (took %s)",
-		address, page, len(txs), t.Elapsed().String())
+	p.info("AccountTransactions for address %s page %d: %d results via %s (took %s)",
+		address, page, len(txs), via, t.Elapsed().String())
 	return txs, nil
 }
 
-func (p loggingProvider) SimulateRelay(servicer_url, chainID string, payload json.RawMessage) (json.RawMessage, error) {
+func (p loggingProvider) SimulateRelay(ctx context.Context, servicer_url, chainID string, payload json.RawMessage) (json.RawMessage, error) {
 	t := timer.Start()
-	res, err := p.provider.SimulateRelay(servicer_url, chainID, payload)
+	ctx, via := withServedBy(ctx)
+	res, err := p.provider.SimulateRelay(ctx, servicer_url, chainID, payload)
 	p.info("SimulateRelay for %s: %s - %s (took %s)", chainID, servicer_url, string(payload), t.Elapsed())
 	if err != nil {
-		p.error(err.Error())
+		p.failed(ctx, via, err)
 		return nil, err
 	}
 
 	return res, nil
 }
 
+func (p loggingProvider) Relay(ctx context.Context, servicerUrl string, relay pocket.Relay) ([]byte, error) {
+	t := timer.Start()
+	ctx, via := withServedBy(ctx)
+	res, err := p.provider.Relay(ctx, servicerUrl, relay)
+	p.info("Relay for %s: %s %s%s (took %s)", relay.ChainID, relay.Method, servicerUrl, relay.Path, t.Elapsed())
+	if err != nil {
+		p.failed(ctx, via, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// failed logs a provider error. Calls that failed because the caller went away or
+// ran out of time are logged as cancelled rather than as errors.
+func (p loggingProvider) failed(ctx context.Context, via *servedBy, err error) {
+	if ctx.Err() != nil {
+		p.warn("cancelled (%s): %s", ctx.Err(), err)
+		return
+	}
+
+	if via.url != "" {
+		p.error("%s (via %s)", err, via)
+		return
+	}
+	p.error(err.Error())
+}
+
 func (p loggingProvider) error(format string, args ...interface{}) {
 	p.log(logTypeError, format, args...)
 }
@@ -2,6 +2,8 @@ package pocket
 
 type queryNodeRequest struct {
 	Address string `json:"address"`
+	// Height is the height to query the node at, 0 being the latest.
+	Height int64 `json:"height"`
 }
 
 type queryNodeResponse struct {
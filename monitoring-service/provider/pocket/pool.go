@@ -0,0 +1,213 @@
+package pocket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultEndpointCooldown = 30 * time.Second
+	DefaultEndpointTimeout  = 20 * time.Second
+)
+
+// EndpointPool spreads requests across a set of Pocket RPC endpoints. An endpoint that
+// errors or times out is marked unhealthy and skipped until its cooldown has passed.
+type EndpointPool struct {
+	mu        sync.Mutex
+	endpoints []*rpcEndpoint
+	next      int
+	cooldown  time.Duration
+	timeout   time.Duration
+}
+
+type rpcEndpoint struct {
+	url            string
+	failures       uint
+	lastError      string
+	unhealthyUntil time.Time
+}
+
+// EndpointStatus describes the health of a single endpoint in the pool.
+type EndpointStatus struct {
+	URL            string
+	Healthy        bool
+	Failures       uint
+	LastError      string
+	UnhealthyUntil time.Time
+}
+
+// NewEndpointPool returns a pool for urls. A cooldown or timeout of zero uses the defaults.
+func NewEndpointPool(urls []string, cooldown, timeout time.Duration) *EndpointPool {
+	if cooldown <= 0 {
+		cooldown = DefaultEndpointCooldown
+	}
+	if timeout <= 0 {
+		timeout = DefaultEndpointTimeout
+	}
+
+	endpoints := make([]*rpcEndpoint, 0, len(urls))
+	for _, u := range urls {
+		u = strings.TrimRight(strings.TrimSpace(u), "/")
+		if u == "" {
+			continue
+		}
+		endpoints = append(endpoints, &rpcEndpoint{url: u})
+	}
+
+	return &EndpointPool{
+		endpoints: endpoints,
+		cooldown:  cooldown,
+		timeout:   timeout,
+	}
+}
+
+// ParseEndpointURLs splits a comma separated list of RPC URLs.
+func ParseEndpointURLs(list string) []string {
+	var urls []string
+	for _, u := range strings.Split(list, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// Status returns the current health of every endpoint in the pool.
+func (p *EndpointPool) Status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := make([]EndpointStatus, len(p.endpoints))
+	for i, e := range p.endpoints {
+		status[i] = EndpointStatus{
+			URL:            e.url,
+			Healthy:        !now.Before(e.unhealthyUntil),
+			Failures:       e.failures,
+			LastError:      e.lastError,
+			UnhealthyUntil: e.unhealthyUntil,
+		}
+	}
+	return status
+}
+
+// candidates returns the endpoints to try for a single call: healthy endpoints in round-robin
+// order, followed by unhealthy endpoints ordered by how soon they come out of cooldown.
+func (p *EndpointPool) candidates() []*rpcEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.endpoints)
+	if n == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	healthy := make([]*rpcEndpoint, 0, n)
+	unhealthy := make([]*rpcEndpoint, 0)
+	for i := 0; i < n; i++ {
+		e := p.endpoints[(p.next+i)%n]
+		if now.Before(e.unhealthyUntil) {
+			unhealthy = append(unhealthy, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	p.next = (p.next + 1) % n
+
+	sort.Slice(unhealthy, func(i, j int) bool {
+		return unhealthy[i].unhealthyUntil.Before(unhealthy[j].unhealthyUntil)
+	})
+
+	return append(healthy, unhealthy...)
+}
+
+func (p *EndpointPool) markHealthy(e *rpcEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failures = 0
+	e.lastError = ""
+	e.unhealthyUntil = time.Time{}
+}
+
+func (p *EndpointPool) markUnhealthy(e *rpcEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failures++
+	e.lastError = err.Error()
+	e.unhealthyUntil = time.Now().Add(p.cooldown)
+}
+
+// do calls fn against each candidate endpoint until one succeeds. A rate limited endpoint is
+// skipped but stays healthy, so if every endpoint is rate limited the retry policy backs off.
+// Other errors for which isEndpointFailure returns false are returned straight away without
+// trying another endpoint.
+func (p *EndpointPool) do(ctx context.Context, fn func(ctx context.Context, baseURL string) error) error {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return fmt.Errorf("EndpointPool: no endpoints configured")
+	}
+
+	var errs []string
+	var lastErr error
+	for _, e := range candidates {
+		attemptCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err := fn(attemptCtx, e.url)
+		cancel()
+
+		if err == nil {
+			p.markHealthy(e)
+			setServedBy(ctx, e.url)
+			return nil
+		}
+
+		// the caller went away, so the endpoint is not to blame
+		if ctx.Err() != nil {
+			return err
+		}
+
+		switch {
+		case isRateLimited(err):
+		case !isEndpointFailure(err):
+			setServedBy(ctx, e.url)
+			return err
+		default:
+			p.markUnhealthy(e, err)
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", e.url, err))
+		lastErr = err
+	}
+
+	return poolError{errs: errs, last: lastErr}
+}
+
+type servedByKey struct{}
+
+type servedBy struct {
+	url string
+}
+
+// withServedBy returns a context that records which endpoint served a call made with it.
+func withServedBy(ctx context.Context) (context.Context, *servedBy) {
+	sb := &servedBy{}
+	return context.WithValue(ctx, servedByKey{}, sb), sb
+}
+
+func setServedBy(ctx context.Context, url string) {
+	if sb, ok := ctx.Value(servedByKey{}).(*servedBy); ok {
+		sb.url = url
+	}
+}
+
+func (s *servedBy) String() string {
+	if s.url == "" {
+		return "cache"
+	}
+	return s.url
+}
@@ -0,0 +1,316 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRPC is a local Pocket RPC that answers query/height with status, or hangs while hang is set.
+type fakeRPC struct {
+	*httptest.Server
+	status  int32
+	hang    int32
+	hits    int32
+	release chan struct{}
+}
+
+func newFakeRPC(t *testing.T, status int) *fakeRPC {
+	t.Helper()
+
+	f := &fakeRPC{status: int32(status), release: make(chan struct{})}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&f.hits, 1)
+		if atomic.LoadInt32(&f.hang) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-f.release:
+			}
+			return
+		}
+
+		status := int(atomic.LoadInt32(&f.status))
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"height":42}`))
+		} else {
+			_, _ = w.Write([]byte("failed at " + f.URL))
+		}
+	}))
+	t.Cleanup(func() {
+		close(f.release)
+		f.Close()
+	})
+
+	return f
+}
+
+func (f *fakeRPC) setStatus(status int) { atomic.StoreInt32(&f.status, int32(status)) }
+func (f *fakeRPC) setHang(hang bool) {
+	var v int32
+	if hang {
+		v = 1
+	}
+	atomic.StoreInt32(&f.hang, v)
+}
+func (f *fakeRPC) hitCount() int { return int(atomic.LoadInt32(&f.hits)) }
+
+func newTestProvider(pool *EndpointPool, attempts int) Provider {
+	retry := RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	return NewPocketProvider(&http.Client{}, pool, retry, nil, nil, nil)
+}
+
+func wantHeight(t *testing.T, p Provider) {
+	t.Helper()
+
+	h, err := p.Height(context.Background())
+	if err != nil || h != 42 {
+		t.Fatalf("Height: %d, %v, want 42, nil", h, err)
+	}
+}
+
+func endpointStatus(t *testing.T, pool *EndpointPool, url string) EndpointStatus {
+	t.Helper()
+
+	for _, s := range pool.Status() {
+		if s.URL == url {
+			return s
+		}
+	}
+	t.Fatalf("no endpoint %s in the pool", url)
+	return EndpointStatus{}
+}
+
+func TestPoolFailover(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			bad, good := newFakeRPC(t, status), newFakeRPC(t, http.StatusOK)
+			pool := NewEndpointPool([]string{bad.URL, good.URL}, 100*time.Millisecond, time.Second)
+			p := newTestProvider(pool, 1)
+
+			// the first endpoint is tried first, and the second one serves the call
+			wantHeight(t, p)
+			if bad.hitCount() != 1 || good.hitCount() != 1 {
+				t.Fatalf("hits: bad %d, good %d, want 1, 1", bad.hitCount(), good.hitCount())
+			}
+			if s := endpointStatus(t, pool, bad.URL); s.Healthy || s.Failures != 1 {
+				t.Errorf("failed endpoint: healthy %t, %d failures, want false, 1", s.Healthy, s.Failures)
+			}
+
+			// while it cools down, the failed endpoint is skipped
+			wantHeight(t, p)
+			wantHeight(t, p)
+			if bad.hitCount() != 1 {
+				t.Errorf("failed endpoint was called %d times during its cooldown, want 1", bad.hitCount())
+			}
+
+			// once the cooldown has passed it is tried again, and recovers
+			bad.setStatus(http.StatusOK)
+			time.Sleep(150 * time.Millisecond)
+			for i := 0; i < 2; i++ {
+				wantHeight(t, p)
+			}
+			if bad.hitCount() != 2 {
+				t.Errorf("recovered endpoint was called %d times in total, want 2", bad.hitCount())
+			}
+			if s := endpointStatus(t, pool, bad.URL); !s.Healthy || s.Failures != 0 {
+				t.Errorf("recovered endpoint: healthy %t, %d failures, want true, 0", s.Healthy, s.Failures)
+			}
+		})
+	}
+}
+
+func TestPoolRateLimitDoesNotMarkUnhealthy(t *testing.T) {
+	limited, good := newFakeRPC(t, http.StatusTooManyRequests), newFakeRPC(t, http.StatusOK)
+	pool := NewEndpointPool([]string{limited.URL, good.URL}, time.Minute, time.Second)
+	p := newTestProvider(pool, 1)
+
+	// the call goes on to the next endpoint, but the rate limited one isn't put in cooldown
+	wantHeight(t, p)
+	if s := endpointStatus(t, pool, limited.URL); !s.Healthy || s.Failures != 0 {
+		t.Errorf("rate limited endpoint: healthy %t, %d failures, want true, 0", s.Healthy, s.Failures)
+	}
+
+	// once it stops rate limiting it serves calls straight away, without waiting out a cooldown
+	limited.setStatus(http.StatusOK)
+	for i := 0; i < 2; i++ {
+		wantHeight(t, p)
+	}
+	if limited.hitCount() != 2 {
+		t.Errorf("rate limited endpoint was called %d times in total, want 2", limited.hitCount())
+	}
+}
+
+func TestPoolRateLimitBacksOff(t *testing.T) {
+	rpc := newFakeRPC(t, http.StatusTooManyRequests)
+	pool := NewEndpointPool([]string{rpc.URL}, time.Minute, time.Second)
+	retry := RetryPolicy{MaxAttempts: 3, BaseDelay: 20 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	p := NewPocketProvider(&http.Client{}, pool, retry, nil, nil, nil)
+
+	start := time.Now()
+	if _, err := p.Height(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Height: %v, want ErrRateLimited", err)
+	}
+	if rpc.hitCount() != 3 {
+		t.Errorf("rate limited endpoint was called %d times, want 3 attempts", rpc.hitCount())
+	}
+	// two retries, each after at least half of the 20ms delay
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("3 attempts took %s, want a backoff between them", elapsed)
+	}
+	if s := endpointStatus(t, pool, rpc.URL); !s.Healthy {
+		t.Error("rate limited endpoint was marked unhealthy")
+	}
+}
+
+func TestPoolUnhealthyEndpointsAreLastResort(t *testing.T) {
+	a, b := newFakeRPC(t, http.StatusInternalServerError), newFakeRPC(t, http.StatusInternalServerError)
+	pool := NewEndpointPool([]string{a.URL, b.URL}, time.Minute, time.Second)
+	p := newTestProvider(pool, 1)
+
+	if _, err := p.Height(context.Background()); err == nil {
+		t.Fatal("Height with every endpoint failing: nil error")
+	}
+
+	// every endpoint is in cooldown, so they are still tried rather than failing outright
+	b.setStatus(http.StatusOK)
+	wantHeight(t, p)
+}
+
+func TestPoolEndpointTimeout(t *testing.T) {
+	slow, good := newFakeRPC(t, http.StatusOK), newFakeRPC(t, http.StatusOK)
+	slow.setHang(true)
+	pool := NewEndpointPool([]string{slow.URL, good.URL}, time.Minute, 50*time.Millisecond)
+	p := newTestProvider(pool, 1)
+
+	start := time.Now()
+	wantHeight(t, p)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Height took %s with a 50ms endpoint timeout", elapsed)
+	}
+	if slow.hitCount() != 1 {
+		t.Errorf("slow endpoint hits: %d, want 1", slow.hitCount())
+	}
+	if s := endpointStatus(t, pool, slow.URL); s.Healthy {
+		t.Error("endpoint that timed out is still healthy")
+	}
+}
+
+func TestPoolCancelDoesNotMarkUnhealthy(t *testing.T) {
+	slow, good := newFakeRPC(t, http.StatusOK), newFakeRPC(t, http.StatusOK)
+	slow.setHang(true)
+	pool := NewEndpointPool([]string{slow.URL, good.URL}, time.Minute, 5*time.Second)
+	p := newTestProvider(pool, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Height(ctx); err == nil {
+		t.Fatal("Height with a cancelled context: nil error")
+	}
+
+	if good.hitCount() != 0 {
+		t.Errorf("next endpoint was called %d times after the caller went away, want 0", good.hitCount())
+	}
+	if s := endpointStatus(t, pool, slow.URL); !s.Healthy || s.Failures != 0 {
+		t.Errorf("endpoint of a cancelled call: healthy %t, %d failures, want true, 0", s.Healthy, s.Failures)
+	}
+}
+
+func TestPoolDoesNotFailOverBadRequests(t *testing.T) {
+	bad, good := newFakeRPC(t, http.StatusBadRequest), newFakeRPC(t, http.StatusOK)
+	pool := NewEndpointPool([]string{bad.URL, good.URL}, time.Minute, time.Second)
+	p := newTestProvider(pool, 3)
+
+	_, err := p.Height(context.Background())
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("Height: %v, want ErrBadRequest", err)
+	}
+	if bad.hitCount() != 1 || good.hitCount() != 0 {
+		t.Errorf("hits: bad %d, good %d, want 1, 0", bad.hitCount(), good.hitCount())
+	}
+	if s := endpointStatus(t, pool, bad.URL); !s.Healthy {
+		t.Error("endpoint that rejected the request was marked unhealthy")
+	}
+}
+
+func TestPoolErrorListsEachEndpointOnce(t *testing.T) {
+	a, b := newFakeRPC(t, http.StatusInternalServerError), newFakeRPC(t, http.StatusBadGateway)
+	pool := NewEndpointPool([]string{a.URL, b.URL}, time.Minute, time.Second)
+	p := newTestProvider(pool, 1)
+
+	_, err := p.Height(context.Background())
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("Height: %v, want ErrUpstreamUnavailable", err)
+	}
+	for _, f := range []*fakeRPC{a, b} {
+		if n := strings.Count(err.Error(), "failed at "+f.URL); n != 1 {
+			t.Errorf("error mentions %s %d times, want once: %s", f.URL, n, err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	rpc := newFakeRPC(t, http.StatusServiceUnavailable)
+	pool := NewEndpointPool([]string{rpc.URL}, time.Millisecond, time.Second)
+	p := newTestProvider(pool, 3)
+
+	if _, err := p.Height(context.Background()); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("Height: %v, want ErrUpstreamUnavailable", err)
+	}
+	if rpc.hitCount() != 3 {
+		t.Errorf("failing endpoint was called %d times, want 3 attempts", rpc.hitCount())
+	}
+
+	// a call that fails once and then succeeds is retried into success
+	calls := 0
+	err := RetryPolicy{MaxAttempts: 3}.do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return statusError{StatusCode: http.StatusTooManyRequests}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("retry after 429: %d calls, %v, want 2, nil", calls, err)
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}.do(ctx, func() error {
+		calls++
+		cancel()
+		return transportError{err: errors.New("connection refused")}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("retry with a cancelled context: %d calls, %v, want 1 and an error", calls, err)
+	}
+}
+
+func TestServicerProviderAsksOnce(t *testing.T) {
+	rpc := newFakeRPC(t, http.StatusOK)
+	rpc.setHang(true)
+	// the servicer gets neither the main pool's retries nor its timeout
+	main := newTestProvider(NewEndpointPool([]string{"http://127.0.0.1:1"}, time.Minute, time.Minute), 3)
+	p := main.ServicerProvider(rpc.URL, 50*time.Millisecond)
+
+	start := time.Now()
+	if _, err := p.Height(context.Background()); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("Height of a hanging servicer: %v, want ErrUpstreamUnavailable", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Height took %s with a 50ms timeout", elapsed)
+	}
+	if rpc.hitCount() != 1 {
+		t.Errorf("servicer was asked %d times, want once", rpc.hitCount())
+	}
+
+	rpc.setHang(false)
+	wantHeight(t, p)
+}
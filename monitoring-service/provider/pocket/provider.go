@@ -2,9 +2,11 @@ package pocket
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	pchttp "monitoring-service/http"
 	"monitoring-service/pocket"
@@ -17,6 +19,7 @@ import (
 
 const (
 	contentTypeJSON               = "application/json; charset=UTF-8"
+	maxErrorBodyBytes             = 512
 	urlPathGetAccountTransactions = "query/accounttxs"
 	urlPathGetTransaction         = "query/tx"
 	urlPathGetBlock               = "query/block"
@@ -31,6 +34,7 @@ const (
 type blockTimesRepo interface {
 	Get(height uint) (t time.Time, exists bool, err error)
 	Set(height uint, t time.Time) error
+	Range(from, to uint, fn func(height uint, t time.Time) error) error
 }
 
 type paramsRepo interface {
@@ -41,17 +45,28 @@ type paramsRepo interface {
 	DelAll(height int64) error
 }
 
+type stakesRepo interface {
+	Get(address string, height uint) (stake uint, exists bool, err error)
+	Set(address string, height uint, stake uint) error
+}
+
 type Provider interface {
-	NodeProvider(address string) (Provider, error)
-	Height() (uint, error)
-	Param(name string, height int64) (string, error)
-	AllParams(height int64, forceRefresh bool) (pocket.AllParams, error)
-	Node(address string) (pocket.Node, error)
-	Balance(address string) (uint, error)
-	BlockTime(height uint) (time.Time, error)
-	Transaction(hash string) (pocket.Transaction, error)
-	AccountTransactions(address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error)
-	SimulateRelay(servicerUrl, chainID string, payload json.RawMessage) (json.RawMessage, error)
+	NodeProvider(ctx context.Context, address string) (Provider, error)
+	ServicerProvider(serviceURL string, timeout time.Duration) Provider
+	Height(ctx context.Context) (uint, error)
+	Param(ctx context.Context, name string, height int64) (string, error)
+	AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error)
+	Node(ctx context.Context, address string) (pocket.Node, error)
+	NodeAtHeight(ctx context.Context, address string, height int64) (pocket.Node, error)
+	StakeAtHeight(ctx context.Context, address string, height uint) (uint, error)
+	Balance(ctx context.Context, address string) (uint, error)
+	BlockTime(ctx context.Context, height uint) (time.Time, error)
+	CachedBlockTime(height uint) (t time.Time, exists bool, err error)
+	CachedBlockTimes(from, to uint, fn func(height uint, t time.Time) error) error
+	Transaction(ctx context.Context, hash string) (pocket.Transaction, error)
+	AccountTransactions(ctx context.Context, address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error)
+	SimulateRelay(ctx context.Context, servicerUrl, chainID string, payload json.RawMessage) (json.RawMessage, error)
+	Relay(ctx context.Context, servicerUrl string, relay pocket.Relay) ([]byte, error)
 	WithLogger(l log.Logger) Provider
 }
 
@@ -59,16 +74,21 @@ type pocketProvider struct {
 	client         pchttp.Client
 	blockTimesRepo blockTimesRepo
 	paramsRepo     paramsRepo
-	pocketRpcURL   string
+	stakesRepo     stakesRepo
+	endpoints      *EndpointPool
+	retry          RetryPolicy
 }
 
-func NewPocketProvider(c pchttp.Client, pocketRpcURL string, blockTimesRepo blockTimesRepo, paramsRepo paramsRepo) Provider {
+// NewPocketProvider returns a Provider that reads from the RPC endpoints in pool.
+func NewPocketProvider(c pchttp.Client, pool *EndpointPool, retry RetryPolicy, blockTimesRepo blockTimesRepo, paramsRepo paramsRepo, stakesRepo stakesRepo) Provider {
 
 	return pocketProvider{
 		client:         c,
 		blockTimesRepo: blockTimesRepo,
 		paramsRepo:     paramsRepo,
-		pocketRpcURL:   pocketRpcURL,
+		stakesRepo:     stakesRepo,
+		endpoints:      pool,
+		retry:          retry,
 	}
 }
 
@@ -79,53 +99,59 @@ func (p pocketProvider) WithLogger(l log.Logger) Provider {
 	}
 }
 
-func (p pocketProvider) NodeProvider(addr string) (Provider, error) {
-	node, err := p.Node(addr)
+func (p pocketProvider) NodeProvider(ctx context.Context, addr string) (Provider, error) {
+	node, err := p.Node(ctx, addr)
 	if err != nil {
 		return pocketProvider{}, err
 	}
 
-	return NewPocketProvider(p.client, fmt.Sprintf("%s/v1", node.ServiceURL), p.blockTimesRepo, p.paramsRepo), nil
+	pool := NewEndpointPool([]string{fmt.Sprintf("%s/v1", node.ServiceURL)}, p.endpoints.cooldown, p.endpoints.timeout)
+	return NewPocketProvider(p.client, pool, p.retry, p.blockTimesRepo, p.paramsRepo, p.stakesRepo), nil
+}
+
+// ServicerProvider returns a provider for the RPC of the servicer at serviceURL, which is asked
+// once with timeout and not retried, for probes that should report a slow node rather than wait on it.
+func (p pocketProvider) ServicerProvider(serviceURL string, timeout time.Duration) Provider {
+	pool := NewEndpointPool([]string{fmt.Sprintf("%s/v1", serviceURL)}, p.endpoints.cooldown, timeout)
+	return NewPocketProvider(p.client, pool, RetryPolicy{MaxAttempts: 1}, p.blockTimesRepo, p.paramsRepo, p.stakesRepo)
 }
 
-func (p pocketProvider) Height() (uint, error) {
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetHeight)
+func (p pocketProvider) Height(ctx context.Context) (uint, error) {
 	//var req interface{}
 	var resp struct {
 		Height float64 `json:"height"`
 	}
 
-	body, err := p.doRequest(url, nil)
+	body, err := p.rpc(ctx, urlPathGetHeight, nil)
 	if err != nil {
-		return 0, fmt.Errorf("pocketProvider.Height: %s", err)
+		return 0, fmt.Errorf("pocketProvider.Height: %w", err)
 	}
 
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return 0, fmt.Errorf("pocketProvider.Height: %s", err)
+		return 0, fmt.Errorf("pocketProvider.Height: %w", err)
 	}
 
 	return uint(resp.Height), nil
 }
 
 // Param returns the value of a given parameter at the specified height. A height of 0 means the latest block.
-func (p pocketProvider) Param(name string, height int64) (string, error) {
+func (p pocketProvider) Param(ctx context.Context, name string, height int64) (string, error) {
 	fail := func(err error) (string, error) {
-		return "", fmt.Errorf("pocketProvider.Param(%s): %s", name, err)
+		return "", fmt.Errorf("pocketProvider.Param(%s): %w", name, err)
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetParam)
 	pReq := paramRequest{
 		Key:    name,
 		Height: height,
 	}
 	var pRes paramResponse
-	body, err := p.doRequest(url, pReq)
+	body, err := p.rpc(ctx, urlPathGetParam, pReq)
 	if err != nil {
-		fail(err)
+		return fail(err)
 	}
 
 	if err := json.Unmarshal(body, &pRes); err != nil {
-		fail(err)
+		return fail(err)
 	}
 
 	return pRes.Value, nil
@@ -136,34 +162,41 @@ type allParamsRequest struct {
 }
 
 // AllParams returns all network parameters.
-func (p pocketProvider) AllParams(height int64, forceRefresh bool) (pocket.AllParams, error) {
+func (p pocketProvider) AllParams(ctx context.Context, height int64, forceRefresh bool) (pocket.AllParams, error) {
 	fail := func(err error) (pocket.AllParams, error) {
-		return pocket.AllParams{}, fmt.Errorf("pocketProvider.AllParams(%d): %s", height, err)
+		return pocket.AllParams{}, fmt.Errorf("pocketProvider.AllParams(%d): %w", height, err)
 	}
 
-	if forceRefresh {
+	// height 0 is whatever the latest params are, which isn't cached
+	latest := height == 0
+	if forceRefresh && !latest {
 		if err := p.paramsRepo.DelAll(height); err != nil {
 			return pocket.AllParams{}, err
 		}
 	}
 
-	cached, exists, err := p.paramsRepo.GetAll(height)
-	if err == nil && exists {
-		return cached, nil
+	if !latest {
+		cached, exists, err := p.paramsRepo.GetAll(height)
+		if err == nil && exists {
+			return cached, nil
+		}
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetAllParams)
 	pReq := allParamsRequest{
 		Height: height,
 	}
 	var pRes pocket.AllParams
-	body, err := p.doRequest(url, pReq)
+	body, err := p.rpc(ctx, urlPathGetAllParams, pReq)
 	if err != nil {
 		return fail(err)
 	}
 
 	if err := json.Unmarshal(body, &pRes); err != nil {
-		return fail(fmt.Errorf("unmarshal allParamsResponse: %s", err))
+		return fail(fmt.Errorf("unmarshal allParamsResponse: %w", err))
+	}
+
+	if latest || pRes.Validate() != nil {
+		return pRes, nil
 	}
 
 	if err := p.paramsRepo.SetAll(height, pRes); err != nil {
@@ -172,16 +205,20 @@ func (p pocketProvider) AllParams(height int64, forceRefresh bool) (pocket.AllPa
 	return pRes, nil
 }
 
-func (p pocketProvider) Node(address string) (pocket.Node, error) {
+func (p pocketProvider) Node(ctx context.Context, address string) (pocket.Node, error) {
+	return p.NodeAtHeight(ctx, address, 0)
+}
+
+// NodeAtHeight returns the node at address as it was at height, or the latest if height is 0.
+func (p pocketProvider) NodeAtHeight(ctx context.Context, address string, height int64) (pocket.Node, error) {
 	var fail = func(err error) (pocket.Node, error) {
-		return pocket.Node{}, fmt.Errorf("pocketProvider.Node: %s", err)
+		return pocket.Node{}, fmt.Errorf("pocketProvider.Node: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetNode)
-	nodeRequest := queryNodeRequest{Address: address}
+	nodeRequest := queryNodeRequest{Address: address, Height: height}
 	var nodeResponse queryNodeResponse
 
-	body, err := p.doRequest(url, nodeRequest)
+	body, err := p.rpc(ctx, urlPathGetNode, nodeRequest)
 	if err != nil {
 		return fail(err)
 	}
@@ -202,7 +239,7 @@ func (p pocketProvider) Node(address string) (pocket.Node, error) {
 
 	stakedBal, err := strconv.ParseUint(nodeResponse.StakedBalance, 10, 64)
 	if err != nil {
-		return pocket.Node{}, fmt.Errorf("pocketProvider.Node: %s", err)
+		return pocket.Node{}, fmt.Errorf("pocketProvider.Node: %w", err)
 	}
 
 	return pocket.Node{
@@ -216,16 +253,15 @@ func (p pocketProvider) Node(address string) (pocket.Node, error) {
 	}, nil
 }
 
-func (p pocketProvider) Balance(address string) (uint, error) {
+func (p pocketProvider) Balance(ctx context.Context, address string) (uint, error) {
 	var fail = func(err error) (uint, error) {
-		return 0, fmt.Errorf("pocketProvider.Balance: %s", err)
+		return 0, fmt.Errorf("pocketProvider.Balance: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetBalance)
 	balRequest := balanceRequest{Address: address}
 	var balResponse balanceResponse
 
-	body, err := p.doRequest(url, balRequest)
+	body, err := p.rpc(ctx, urlPathGetBalance, balRequest)
 	if err != nil {
 		return fail(err)
 	}
@@ -238,9 +274,33 @@ func (p pocketProvider) Balance(address string) (uint, error) {
 	return balResponse.Balance, nil
 }
 
-func (p pocketProvider) BlockTime(height uint) (time.Time, error) {
+// StakeAtHeight returns the uPOKT staked by the node at address at height, or 0 if address wasn't a
+// node at height. height must be committed, as the stake is cached for good.
+func (p pocketProvider) StakeAtHeight(ctx context.Context, address string, height uint) (uint, error) {
+	cached, exists, _ := p.stakesRepo.Get(address, height)
+	if exists {
+		return cached, nil
+	}
+
+	var stake uint
+	node, err := p.NodeAtHeight(ctx, address, int64(height))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, fmt.Errorf("pocketProvider.StakeAtHeight: %w", err)
+	}
+	if err == nil {
+		stake = node.StakedBalance
+	}
+
+	if err = p.stakesRepo.Set(address, height, stake); err != nil {
+		return 0, fmt.Errorf("pocketProvider.StakeAtHeight: %w", err)
+	}
+
+	return stake, nil
+}
+
+func (p pocketProvider) BlockTime(ctx context.Context, height uint) (time.Time, error) {
 	var fail = func(err error) (time.Time, error) {
-		return time.Time{}, fmt.Errorf("pocketProvider.BlockTime: %s", err)
+		return time.Time{}, fmt.Errorf("pocketProvider.BlockTime: %w", err)
 	}
 
 	cached, exists, _ := p.blockTimesRepo.Get(height)
@@ -248,11 +308,10 @@ func (p pocketProvider) BlockTime(height uint) (time.Time, error) {
 		return cached, nil
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetBlock)
 	blkRequest := blockRequest{Height: height}
 	var blkResponse blockResponse
 
-	body, err := p.doRequest(url, blkRequest)
+	body, err := p.rpc(ctx, urlPathGetBlock, blkRequest)
 	if err != nil {
 		return fail(err)
 	}
@@ -263,22 +322,34 @@ func (p pocketProvider) BlockTime(height uint) (time.Time, error) {
 	}
 
 	if err = p.blockTimesRepo.Set(height, blkResponse.Block.Header.Time); err != nil {
-		return time.Time{}, fmt.Errorf("pocketProvider.BlockTime: %s", err)
+		return time.Time{}, fmt.Errorf("pocketProvider.BlockTime: %w", err)
 	}
 
 	return blkResponse.Block.Header.Time, nil
 }
 
-func (p pocketProvider) Transaction(hash string) (pocket.Transaction, error) {
+// CachedBlockTime returns the block time at height if it has been cached, without calling the network.
+func (p pocketProvider) CachedBlockTime(height uint) (time.Time, bool, error) {
+	// like BlockTime, a failed read is treated as a miss
+	t, exists, _ := p.blockTimesRepo.Get(height)
+	return t, exists, nil
+}
+
+// CachedBlockTimes calls fn with every cached block time in [from, to], in height order, without
+// calling the network. It stops at the first error fn returns, and returns it.
+func (p pocketProvider) CachedBlockTimes(from, to uint, fn func(height uint, t time.Time) error) error {
+	return p.blockTimesRepo.Range(from, to, fn)
+}
+
+func (p pocketProvider) Transaction(ctx context.Context, hash string) (pocket.Transaction, error) {
 	var fail = func(err error) (pocket.Transaction, error) {
-		return pocket.Transaction{}, fmt.Errorf("pocketProvider.Transaction: %s", err)
+		return pocket.Transaction{}, fmt.Errorf("pocketProvider.Transaction: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetTransaction)
 	txRequest := transactionRequest{Hash: hash}
 	var txnResponse transactionResponse
 
-	body, err := p.doRequest(url, txRequest)
+	body, err := p.rpc(ctx, urlPathGetTransaction, txRequest)
 	if err != nil {
 		return fail(err)
 	}
@@ -296,12 +367,11 @@ func (p pocketProvider) Transaction(hash string) (pocket.Transaction, error) {
 	return txn, nil
 }
 
-func (p pocketProvider) AccountTransactions(address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error) {
+func (p pocketProvider) AccountTransactions(ctx context.Context, address string, page uint, perPage uint, sort string) ([]pocket.Transaction, error) {
 	var fail = func(err error) ([]pocket.Transaction, error) {
-		return nil, fmt.Errorf("pocketProvider.AccountTransactions: %s", err)
+		return nil, fmt.Errorf("pocketProvider.AccountTransactions: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s", p.pocketRpcURL, urlPathGetAccountTransactions)
 	txsRequest := accountTransactionsRequest{
 		Address: address,
 		Height:  0,
@@ -311,7 +381,7 @@ func (p pocketProvider) AccountTransactions(address string, page uint, perPage u
 	}
 	var txsResponse accountTransactionsResponse
 
-	body, err := p.doRequest(url, txsRequest)
+	body, err := p.rpc(ctx, urlPathGetAccountTransactions, txsRequest)
 	if err != nil {
 		return fail(err)
 	}
@@ -334,8 +404,7 @@ func (p pocketProvider) AccountTransactions(address string, page uint, perPage u
 	return transactions, nil
 }
 
-func (p pocketProvider) SimulateRelay(servicerUrl, chainID string, payload json.RawMessage) (json.RawMessage, error) {
-	url := fmt.Sprintf("%s/%s", servicerUrl, urlPathSimulateRelay)
+func (p pocketProvider) SimulateRelay(ctx context.Context, servicerUrl, chainID string, payload json.RawMessage) (json.RawMessage, error) {
 	path := ""
 
 	switch chainID {
@@ -345,44 +414,79 @@ func (p pocketProvider) SimulateRelay(servicerUrl, chainID string, payload json.
 		path = "/v1/query/height"
 	}
 
+	resp, err := p.Relay(ctx, servicerUrl, pocket.Relay{ChainID: chainID, Method: http.MethodPost, Path: path, Data: string(payload)})
+	if err != nil {
+		return nil, fmt.Errorf("pocketProvider.SimulateRelay: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Relay sends relay to the /v1/client/sim endpoint of the node at servicerUrl, which passes it on
+// to its chain without a session, and returns the chain's response.
+func (p pocketProvider) Relay(ctx context.Context, servicerUrl string, relay pocket.Relay) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s", servicerUrl, urlPathSimulateRelay)
+
 	simRequest := relayRequest{
-		RelayNetworkID: chainID,
+		RelayNetworkID: relay.ChainID,
 		Payload: relayRequestPayload{
-			Data:    string(payload),
-			Method:  "POST",
-			Path:    path,
+			Data:    relay.Data,
+			Method:  relay.Method,
+			Path:    relay.Path,
 			Headers: make(map[string]string, 0),
 		},
 	}
 
-	resp, err := p.doRequest(url, simRequest)
+	resp, err := p.doRequest(pchttp.WithRPCPath(ctx, urlPathSimulateRelay), url, simRequest)
 	if err != nil {
-		return nil, fmt.Errorf("pocketProvider.SimulateRelay: %s", err)
+		return nil, fmt.Errorf("pocketProvider.Relay: %w", err)
 	}
 
 	return resp, nil
 }
 
-func (p pocketProvider) doRequest(url string, reqObj interface{}) ([]byte, error) {
+// rpc posts reqObj to path on the first endpoint in the pool able to serve it, backing off
+// and trying again according to the provider's RetryPolicy when every endpoint failed.
+func (p pocketProvider) rpc(ctx context.Context, path string, reqObj interface{}) ([]byte, error) {
+	var body []byte
+	ctx = pchttp.WithRPCPath(ctx, path)
+	err := p.retry.do(ctx, func() error {
+		return p.endpoints.do(ctx, func(ctx context.Context, baseURL string) error {
+			var err error
+			body, err = p.doRequest(ctx, fmt.Sprintf("%s/%s", baseURL, path), reqObj)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func (p pocketProvider) doRequest(ctx context.Context, url string, reqObj interface{}) ([]byte, error) {
 	var reqBody []byte
 	var err error
 	if reqObj != nil {
 		reqBody, err = json.Marshal(reqObj)
 		if err != nil {
-			return nil, fmt.Errorf("doRequest: %s", err)
+			return nil, fmt.Errorf("doRequest: %w", err)
 		}
 	}
 	req := bytes.NewBuffer(reqBody)
 
-	clientReq, err := http.NewRequest(http.MethodPost, url, req)
+	clientReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, req)
 	if err != nil {
-		return nil, fmt.Errorf("doRequest: %s", err)
+		return nil, fmt.Errorf("doRequest: %w", err)
 	}
 	clientReq.Header.Set("Content-Type", contentTypeJSON)
 
 	resp, err := p.client.Do(clientReq)
 	if err != nil {
-		return nil, fmt.Errorf("doRequest: %s", err)
+		return nil, transportError{err: err}
+	}
+	if resp == nil {
+		return nil, transportError{err: errors.New("got empty response for " + url)}
 	}
 	defer func() {
 		if resp.Body != nil {
@@ -390,16 +494,14 @@ func (p pocketProvider) doRequest(url string, reqObj interface{}) ([]byte, error
 		}
 	}()
 
-	if resp == nil {
-		return nil, errors.New("pocketProvider.doRequest: got empty response for " + url)
-	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(fmt.Sprintf("pocketProvider.doRequest: got unexpected response status %s - %s", resp.Status, string(reqBody)))
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, statusError{StatusCode: resp.StatusCode, Status: resp.Status, URL: url, Message: string(msg)}
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("doRequest: %s", err)
+		return nil, transportError{err: err}
 	}
 
 	return body, nil
@@ -0,0 +1,73 @@
+package pocket
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRetryAttempts  = 3
+	DefaultRetryBaseDelay = 250 * time.Millisecond
+	DefaultRetryMaxDelay  = 5 * time.Second
+)
+
+// RetryPolicy controls how often a failed RPC call is tried again. Only rate limiting,
+// 5xx responses and network errors are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// jitter is seeded once per process, so that clients started together don't retry in step.
+// A rand.Rand isn't safe for concurrent use, hence the lock.
+var (
+	jitterMu sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryAttempts,
+		BaseDelay:   DefaultRetryBaseDelay,
+		MaxDelay:    DefaultRetryMaxDelay,
+	}
+}
+
+func (r RetryPolicy) do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(); err == nil || !isRetryable(err) || attempt+1 >= r.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(r.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before retry number attempt+1: exponential in attempt,
+// capped at MaxDelay, with up to half of it replaced by random jitter.
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	d := r.BaseDelay
+	for i := 0; i < attempt && d < r.MaxDelay; i++ {
+		d *= 2
+	}
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	half := d / 2
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return half + time.Duration(jitter.Int63n(int64(half)+1))
+}
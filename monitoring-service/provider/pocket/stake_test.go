@@ -0,0 +1,69 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"monitoring-service/inmem"
+)
+
+func TestStakeAtHeightIsCached(t *testing.T) {
+	const (
+		node     = "aaaa"
+		notANode = "bbbb"
+	)
+
+	var (
+		mu      sync.Mutex
+		queries = make(map[string]int)
+	)
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req queryNodeRequest
+		if r.URL.Path != "/v1/query/node" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		queries[fmt.Sprintf("%s@%d", req.Address, req.Height)]++
+		mu.Unlock()
+
+		if req.Address != node {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(queryNodeResponse{Address: node, StakedBalance: fmt.Sprint(req.Height * 1000)})
+	}))
+	defer rpc.Close()
+
+	pool := NewEndpointPool([]string{rpc.URL + "/v1"}, time.Minute, time.Second)
+	provider := NewPocketProvider(&http.Client{}, pool, RetryPolicy{MaxAttempts: 1}, nil, nil, inmem.NewStakesRepo())
+
+	for i := 0; i < 3; i++ {
+		for _, h := range []uint{10, 20} {
+			stake, err := provider.StakeAtHeight(context.Background(), node, h)
+			if err != nil || stake != h*1000 {
+				t.Errorf("StakeAtHeight(%d): %d, %v, want %d, nil", h, stake, err, h*1000)
+			}
+		}
+
+		// not being a node is cached like any stake
+		if stake, err := provider.StakeAtHeight(context.Background(), notANode, 10); err != nil || stake != 0 {
+			t.Errorf("StakeAtHeight of an address that isn't a node: %d, %v, want 0, nil", stake, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, q := range []string{node + "@10", node + "@20", notANode + "@10"} {
+		if queries[q] != 1 {
+			t.Errorf("%s was queried %d times, want once", q, queries[q])
+		}
+	}
+}
@@ -43,7 +43,7 @@ func (t *transactionResponse) Transaction() (pocket.Transaction, error) {
 	} else {
 		numProofs, err = strconv.ParseUint(t.StdTx.Message.Value.TotalProofs, 10, 32)
 		if err != nil {
-			return pocket.Transaction{}, fmt.Errorf("transactionResponse.Transaction: %s", err)
+			return pocket.Transaction{}, fmt.Errorf("transactionResponse.Transaction: %w", err)
 		}
 	}
 
@@ -60,7 +60,7 @@ func (t *transactionResponse) Transaction() (pocket.Transaction, error) {
 	case pocket.TypeProof:
 		sessionHeight, err := strconv.ParseUint(t.StdTx.Message.Value.Leaf.Value.SessionHeight, 10, 32)
 		if err != nil {
-			return pocket.Transaction{}, fmt.Errorf("transactionResponse.Transaction: %s", err)
+			return pocket.Transaction{}, fmt.Errorf("transactionResponse.Transaction: %w", err)
 		}
 		tx.SessionHeight = uint(sessionHeight)
 		tx.AppPubkey = t.StdTx.Message.Value.Leaf.Value.AAT.AppPubkey
@@ -70,7 +70,7 @@ func (t *transactionResponse) Transaction() (pocket.Transaction, error) {
 	case pocket.TypeClaim:
 		sessionHeight, err := strconv.ParseUint(t.StdTx.Message.Value.Header.SessionHeight, 10, 32)
 		if err != nil {
-			return pocket.Transaction{}, fmt.Errorf("transactionResponse.Transaction: %s", err)
+			return pocket.Transaction{}, fmt.Errorf("transactionResponse.Transaction: %w", err)
 		}
 
 		tx.SessionHeight = uint(sessionHeight)
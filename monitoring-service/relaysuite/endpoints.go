@@ -0,0 +1,195 @@
+package relaysuite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	RelaySuite endpoint.Endpoint
+	Ping       endpoint.Endpoint
+}
+
+type relaySuiteRequest struct {
+	NodeURL   string   `json:"node_url"`
+	Chains    []string `json:"chains"`
+	NumRelays int      `json:"num_relays"`
+}
+
+type relaySuiteResponse struct {
+	NodeURL string                         `json:"node_url"`
+	Results map[string]chainResultResponse `json:"results"`
+}
+
+type chainResultResponse struct {
+	ChainID         string                `json:"chain_id"`
+	ChainName       string                `json:"chain_name"`
+	Success         bool                  `json:"success"`
+	StatusCode      int                   `json:"status_code"`
+	Message         string                `json:"message"`
+	DurationAvgMs   float64               `json:"duration_avg_ms"`
+	DurationMinMs   float64               `json:"duration_min_ms"`
+	DurationMaxMs   float64               `json:"duration_max_ms"`
+	Height          *uint                 `json:"height,omitempty"`
+	ReferenceHeight *uint                 `json:"reference_height,omitempty"`
+	HeightLag       *int64                `json:"height_lag,omitempty"`
+	ReferenceError  string                `json:"reference_error,omitempty"`
+	RelayRequest    relayRequestResponse  `json:"relay_request"`
+	RelayResponses  []relayResultResponse `json:"relay_responses"`
+}
+
+type relayRequestResponse struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Data   string `json:"data"`
+}
+
+type relayResultResponse struct {
+	DurationMs float64 `json:"duration_ms"`
+	StatusCode int     `json:"status_code"`
+	Valid      bool    `json:"valid"`
+	Height     *uint   `json:"height,omitempty"`
+	Data       string  `json:"data"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type pingRequest struct {
+	NodeURL  string `json:"node_url"`
+	NumPings int    `json:"num_pings"`
+}
+
+type pingResponse struct {
+	NodeURL      string               `json:"node_url"`
+	NumSent      int                  `json:"num_sent"`
+	NumOK        int                  `json:"num_ok"`
+	MinTimeMs    float64              `json:"min_time_ms"`
+	MaxTimeMs    float64              `json:"max_time_ms"`
+	AvgTimeMs    float64              `json:"avg_time_ms"`
+	MedianTimeMs float64              `json:"median_time_ms"`
+	Results      []pingResultResponse `json:"results"`
+}
+
+type pingResultResponse struct {
+	DurationMs float64 `json:"duration_ms"`
+	StatusCode int     `json:"status_code"`
+	Error      string  `json:"error,omitempty"`
+}
+
+func RelaySuiteEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("RelaySuiteEndpoint: %w", err)
+		}
+
+		req, ok := request.(relaySuiteRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		results, err := svc.Run(ctx, req.NodeURL, req.Chains, req.NumRelays)
+		if err != nil {
+			return fail(err)
+		}
+
+		resp := relaySuiteResponse{NodeURL: req.NodeURL, Results: make(map[string]chainResultResponse, len(results))}
+		for _, r := range results {
+			resp.Results[r.Chain.ID] = toChainResultResponse(r)
+		}
+
+		return resp, nil
+	}
+}
+
+func PingEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		fail := func(err error) (interface{}, error) {
+			return nil, fmt.Errorf("PingEndpoint: %w", err)
+		}
+
+		req, ok := request.(pingRequest)
+		if !ok {
+			err := fmt.Errorf("failed to parse request: %v", request)
+			return fail(err)
+		}
+
+		pings, err := svc.Ping(ctx, req.NodeURL, req.NumPings)
+		if err != nil {
+			return fail(err)
+		}
+
+		stats := Stats(pings)
+		resp := pingResponse{
+			NodeURL:      req.NodeURL,
+			NumSent:      stats.NumSent,
+			NumOK:        stats.NumOK,
+			MinTimeMs:    milliseconds(stats.Min),
+			MaxTimeMs:    milliseconds(stats.Max),
+			AvgTimeMs:    milliseconds(stats.Avg),
+			MedianTimeMs: milliseconds(stats.Median),
+			Results:      make([]pingResultResponse, len(pings)),
+		}
+		for i, p := range pings {
+			resp.Results[i] = pingResultResponse{DurationMs: milliseconds(p.Duration), StatusCode: p.StatusCode, Error: p.Error}
+		}
+
+		return resp, nil
+	}
+}
+
+func toChainResultResponse(r ChainResult) chainResultResponse {
+	avg, min, max := r.Durations()
+	resp := chainResultResponse{
+		ChainID:        r.Chain.ID,
+		ChainName:      r.Chain.Name,
+		Success:        r.Success,
+		DurationAvgMs:  milliseconds(avg),
+		DurationMinMs:  milliseconds(min),
+		DurationMaxMs:  milliseconds(max),
+		ReferenceError: r.ReferenceError,
+		RelayRequest:   relayRequestResponse{Method: r.Probe.Method, Path: r.Probe.Path, Data: r.Probe.Data},
+		RelayResponses: make([]relayResultResponse, len(r.Relays)),
+	}
+
+	for i, relay := range r.Relays {
+		resp.RelayResponses[i] = relayResultResponse{
+			DurationMs: milliseconds(relay.Duration),
+			StatusCode: relay.StatusCode,
+			Valid:      relay.Valid,
+			Data:       relay.Response,
+			Error:      relay.Error,
+		}
+		if relay.HasHeight {
+			height := relay.Height
+			resp.RelayResponses[i].Height = &height
+		}
+	}
+
+	// the status and message are those of the last relay, or of the first one that failed
+	for _, relay := range r.Relays {
+		resp.StatusCode, resp.Message = relay.StatusCode, relay.Error
+		if !relay.Valid {
+			break
+		}
+	}
+	if r.HasHeight {
+		height := r.Height
+		resp.Height = &height
+	}
+	if r.HasReference {
+		height := r.ReferenceHeight
+		resp.ReferenceHeight = &height
+	}
+	if lag, ok := r.HeightLag(); ok {
+		resp.HeightLag = &lag
+	}
+
+	return resp
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,126 @@
+package relaysuite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"monitoring-service/api"
+	"monitoring-service/timer"
+)
+
+const (
+	DefaultPings = 10
+	MaxPings     = 50
+)
+
+// PingResult is the outcome of one request to a node's /v1 endpoint. StatusCode is 0 when the
+// node didn't answer at all.
+type PingResult struct {
+	Duration   time.Duration
+	StatusCode int
+	Error      string
+}
+
+// OK is whether the node answered with 200 OK.
+func (p PingResult) OK() bool {
+	return p.StatusCode == http.StatusOK
+}
+
+// PingStats summarizes the durations of the pings that got 200 OK.
+type PingStats struct {
+	NumSent int
+	NumOK   int
+	Min     time.Duration
+	Max     time.Duration
+	Avg     time.Duration
+	Median  time.Duration
+}
+
+// Stats returns the summary of pings.
+func Stats(pings []PingResult) PingStats {
+	stats := PingStats{NumSent: len(pings)}
+
+	var durations []time.Duration
+	for _, p := range pings {
+		if p.OK() {
+			durations = append(durations, p.Duration)
+		}
+	}
+	stats.NumOK = len(durations)
+	if len(durations) == 0 {
+		return stats
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	stats.Min, stats.Max = durations[0], durations[len(durations)-1]
+	stats.Avg = total / time.Duration(len(durations))
+	stats.Median = durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		stats.Median = (durations[len(durations)/2-1] + stats.Median) / 2
+	}
+
+	return stats
+}
+
+// Ping sends numPings GET requests, one after the other, to the /v1 endpoint of the node at
+// nodeURL. Each is given the service's timeout.
+func (s *Service) Ping(ctx context.Context, nodeURL string, numPings int) ([]PingResult, error) {
+	nodeURL, err := validateNodeURL(nodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("Ping: %w", err)
+	}
+
+	if numPings == 0 {
+		numPings = DefaultPings
+	}
+	if numPings < 1 || numPings > MaxPings {
+		return nil, api.InvalidArgument(fmt.Sprintf("Ping: num_pings must be between 1 and %d", MaxPings), api.Details{"param": "num_pings", "value": numPings})
+	}
+
+	pings := make([]PingResult, 0, numPings)
+	for i := 0; i < numPings; i++ {
+		pings = append(pings, s.ping(ctx, nodeURL+"/v1"))
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("Ping: %w", ctx.Err())
+		}
+	}
+
+	return pings, nil
+}
+
+func (s *Service) ping(ctx context.Context, url string) PingResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return PingResult{Error: err.Error()}
+	}
+
+	t := timer.Start()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return PingResult{Duration: t.Elapsed(), Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	// the body is read so that the duration covers the whole response
+	_, err = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	result := PingResult{Duration: t.Elapsed(), StatusCode: resp.StatusCode}
+	if err != nil {
+		result.Error = err.Error()
+	} else if !result.OK() {
+		result.Error = resp.Status
+	}
+
+	return result
+}
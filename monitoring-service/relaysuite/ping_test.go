@@ -0,0 +1,70 @@
+package relaysuite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	// every third request to /v1 fails
+	var n int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1" {
+			http.NotFound(w, r)
+			return
+		}
+		if atomic.AddInt32(&n, 1)%3 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`"1.0.0"`))
+	}))
+	defer node.Close()
+
+	svc := NewService(nil, http.DefaultClient, "", time.Second)
+	pings, err := svc.Ping(context.Background(), node.URL+"/", 6)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if len(pings) != 6 {
+		t.Fatalf("Ping: %d results, want 6", len(pings))
+	}
+	if p := pings[2]; p.OK() || p.StatusCode != http.StatusServiceUnavailable || p.Error == "" {
+		t.Errorf("third ping: %+v, want a failed 503", p)
+	}
+	if stats := Stats(pings); stats.NumSent != 6 || stats.NumOK != 4 {
+		t.Errorf("Stats: %+v, want 6 sent and 4 ok", stats)
+	}
+
+	for _, numPings := range []int{-1, MaxPings + 1} {
+		if _, err = svc.Ping(context.Background(), node.URL, numPings); err == nil {
+			t.Errorf("Ping of %d: nil error", numPings)
+		}
+	}
+	if _, err = svc.Ping(context.Background(), "ftp://node", 1); err == nil {
+		t.Error("Ping of a non http URL: nil error")
+	}
+}
+
+func TestStats(t *testing.T) {
+	pings := []PingResult{
+		{Duration: 40 * time.Millisecond, StatusCode: http.StatusOK},
+		{Duration: 10 * time.Millisecond, StatusCode: http.StatusOK},
+		{Duration: time.Millisecond},
+		{Duration: 20 * time.Millisecond, StatusCode: http.StatusOK},
+		{Duration: 30 * time.Millisecond, StatusCode: http.StatusOK},
+	}
+
+	// the ping that got no answer is left out of the durations
+	want := PingStats{NumSent: 5, NumOK: 4, Min: 10 * time.Millisecond, Max: 40 * time.Millisecond, Avg: 25 * time.Millisecond, Median: 25 * time.Millisecond}
+	if got := Stats(pings); got != want {
+		t.Errorf("Stats: %+v, want %+v", got, want)
+	}
+	if got := Stats(nil); got != (PingStats{}) {
+		t.Errorf("Stats of no pings: %+v, want zero", got)
+	}
+}
@@ -0,0 +1,177 @@
+package relaysuite
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Probe is the request sent to a chain to check that it answers, and how to read its answer.
+type Probe struct {
+	Method string
+	Path   string
+	Data   string
+	// parse returns the chain's height from a response, and hasHeight false for probes that
+	// don't report one. It fails for responses that aren't a valid answer to the probe.
+	parse func(body []byte) (height uint, hasHeight bool, err error)
+}
+
+const (
+	jsonRPCBlockNumber   = `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`
+	jsonRPCGetHealth     = `{"jsonrpc":"2.0","id":1,"method":"getHealth"}`
+	jsonRPCStatus        = `{"jsonrpc":"2.0","id":1,"method":"status","params":[]}`
+	jsonRPCGetBlockCount = `{"jsonrpc":"1.0","id":1,"method":"getblockcount","params":[]}`
+)
+
+var (
+	evmProbe       = Probe{Method: http.MethodPost, Data: jsonRPCBlockNumber, parse: parseEVM}
+	avalancheProbe = Probe{Method: http.MethodPost, Path: "/ext/bc/C/rpc", Data: jsonRPCBlockNumber, parse: parseEVM}
+	algorandProbe  = Probe{Method: http.MethodGet, Path: "/v2/status", parse: parseAlgorand}
+	arweaveProbe   = Probe{Method: http.MethodGet, Path: "/info", parse: parseArweave}
+	bitcoinProbe   = Probe{Method: http.MethodPost, Data: jsonRPCGetBlockCount, parse: parseBitcoin}
+	nearProbe      = Probe{Method: http.MethodPost, Data: jsonRPCStatus, parse: parseNEAR}
+	pocketProbe    = Probe{Method: http.MethodPost, Path: "/v1/query/height", Data: "{}", parse: parsePocket}
+	solanaProbe    = Probe{Method: http.MethodPost, Data: jsonRPCGetHealth, parse: parseSolana}
+)
+
+// probes are the chains that aren't EVM compatible, or are served on a path of their own.
+var probes = map[string]Probe{
+	"0029": algorandProbe,
+	"000D": algorandProbe,
+	"0045": algorandProbe,
+	"0A45": algorandProbe,
+	"0030": arweaveProbe,
+	"0003": avalancheProbe,
+	"00A3": avalancheProbe,
+	"000E": avalancheProbe,
+	"0002": bitcoinProbe,
+	"0052": nearProbe,
+	"0001": pocketProbe,
+	"0006": solanaProbe,
+	"0031": solanaProbe,
+}
+
+// ProbeFor returns the probe for chainID. Chains without a probe of their own are sent eth_blockNumber,
+// as most chains relayed by Pocket are EVM compatible.
+func ProbeFor(chainID string) Probe {
+	if p, ok := probes[chainID]; ok {
+		return p
+	}
+
+	return evmProbe
+}
+
+type jsonRPCResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// jsonRPCResult decodes the result of a JSON-RPC response into result.
+func jsonRPCResult(body []byte, result interface{}) error {
+	var resp jsonRPCResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("invalid JSON-RPC response: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("JSON-RPC error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return fmt.Errorf("JSON-RPC response has no result")
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("unexpected JSON-RPC result %s: %w", resp.Result, err)
+	}
+
+	return nil
+}
+
+func parseEVM(body []byte) (uint, bool, error) {
+	var hex string
+	if err := jsonRPCResult(body, &hex); err != nil {
+		return 0, false, err
+	}
+
+	height, err := strconv.ParseUint(strings.TrimPrefix(hex, "0x"), 16, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid block number %q", hex)
+	}
+
+	return uint(height), true, nil
+}
+
+func parseBitcoin(body []byte) (uint, bool, error) {
+	var height uint
+	if err := jsonRPCResult(body, &height); err != nil {
+		return 0, false, err
+	}
+
+	return height, true, nil
+}
+
+func parseNEAR(body []byte) (uint, bool, error) {
+	var status struct {
+		SyncInfo struct {
+			LatestBlockHeight *uint `json:"latest_block_height"`
+		} `json:"sync_info"`
+	}
+	if err := jsonRPCResult(body, &status); err != nil {
+		return 0, false, err
+	}
+	if status.SyncInfo.LatestBlockHeight == nil {
+		return 0, false, fmt.Errorf("response has no sync_info.latest_block_height")
+	}
+
+	return *status.SyncInfo.LatestBlockHeight, true, nil
+}
+
+func parseSolana(body []byte) (uint, bool, error) {
+	var health string
+	if err := jsonRPCResult(body, &health); err != nil {
+		return 0, false, err
+	}
+	if health != "ok" {
+		return 0, false, fmt.Errorf("node is unhealthy: %s", health)
+	}
+
+	return 0, false, nil
+}
+
+func parseAlgorand(body []byte) (uint, bool, error) {
+	var status struct {
+		LastRound *uint `json:"last-round"`
+	}
+	return heightField(body, &status, func() *uint { return status.LastRound }, "last-round")
+}
+
+func parseArweave(body []byte) (uint, bool, error) {
+	var info struct {
+		Height *uint `json:"height"`
+	}
+	return heightField(body, &info, func() *uint { return info.Height }, "height")
+}
+
+func parsePocket(body []byte) (uint, bool, error) {
+	var resp struct {
+		Height *uint `json:"height"`
+	}
+	return heightField(body, &resp, func() *uint { return resp.Height }, "height")
+}
+
+// heightField decodes body into v, and returns the height that field reads from it once decoded.
+func heightField(body []byte, v interface{}, field func() *uint, name string) (uint, bool, error) {
+	if err := json.Unmarshal(body, v); err != nil {
+		return 0, false, fmt.Errorf("invalid response: %w", err)
+	}
+
+	height := field()
+	if height == nil {
+		return 0, false, fmt.Errorf("response has no %s", name)
+	}
+
+	return *height, true, nil
+}
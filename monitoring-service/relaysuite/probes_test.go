@@ -0,0 +1,108 @@
+package relaysuite
+
+import (
+	"testing"
+)
+
+type parseTest struct {
+	name       string
+	body       string
+	wantHeight uint
+	wantErr    bool
+}
+
+func checkParse(t *testing.T, parse func([]byte) (uint, bool, error), hasHeight bool, tests []parseTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			height, gotHasHeight, err := parse([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse(%s): %v, want error %t", tt.body, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if height != 0 || gotHasHeight {
+					t.Errorf("parse(%s) failed with height %d, %t", tt.body, height, gotHasHeight)
+				}
+				return
+			}
+			if height != tt.wantHeight || gotHasHeight != hasHeight {
+				t.Errorf("parse(%s): %d, %t, want %d, %t", tt.body, height, gotHasHeight, tt.wantHeight, hasHeight)
+			}
+		})
+	}
+}
+
+// jsonRPCErrors are the responses every JSON-RPC probe rejects.
+var jsonRPCErrors = []parseTest{
+	{"error", `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`, 0, true},
+	{"no result", `{"jsonrpc":"2.0","id":1}`, 0, true},
+	{"null result", `{"jsonrpc":"2.0","id":1,"result":null}`, 0, true},
+	{"not json", `<html>502 Bad Gateway</html>`, 0, true},
+	{"empty", ``, 0, true},
+}
+
+func TestParseEVM(t *testing.T) {
+	checkParse(t, parseEVM, true, append([]parseTest{
+		{"block number", `{"jsonrpc":"2.0","id":1,"result":"0xe4e1c0"}`, 15000000, false},
+		{"without 0x", `{"jsonrpc":"2.0","id":1,"result":"10"}`, 16, false},
+		{"not hex", `{"jsonrpc":"2.0","id":1,"result":"0xzz"}`, 0, true},
+		{"not a string", `{"jsonrpc":"2.0","id":1,"result":123}`, 0, true},
+	}, jsonRPCErrors...))
+}
+
+func TestParseSolana(t *testing.T) {
+	checkParse(t, parseSolana, false, append([]parseTest{
+		{"healthy", `{"jsonrpc":"2.0","id":1,"result":"ok"}`, 0, false},
+		{"unhealthy", `{"jsonrpc":"2.0","id":1,"result":"behind"}`, 0, true},
+		{"behind", `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Node is behind by 42 slots"}}`, 0, true},
+		{"not a string", `{"jsonrpc":"2.0","id":1,"result":{"status":"ok"}}`, 0, true},
+	}, jsonRPCErrors...))
+}
+
+func TestParseNEAR(t *testing.T) {
+	checkParse(t, parseNEAR, true, append([]parseTest{
+		{"status", `{"jsonrpc":"2.0","id":1,"result":{"chain_id":"mainnet","sync_info":{"latest_block_height":72014345,"syncing":false}}}`, 72014345, false},
+		{"height zero", `{"jsonrpc":"2.0","id":1,"result":{"sync_info":{"latest_block_height":0}}}`, 0, false},
+		{"no sync info", `{"jsonrpc":"2.0","id":1,"result":{"chain_id":"mainnet"}}`, 0, true},
+		{"no height", `{"jsonrpc":"2.0","id":1,"result":{"sync_info":{"syncing":true}}}`, 0, true},
+		{"height not a number", `{"jsonrpc":"2.0","id":1,"result":{"sync_info":{"latest_block_height":"72014345"}}}`, 0, true},
+	}, jsonRPCErrors...))
+}
+
+func TestParseBitcoin(t *testing.T) {
+	checkParse(t, parseBitcoin, true, append([]parseTest{
+		{"block count", `{"result":750000,"error":null,"id":1}`, 750000, false},
+		{"negative", `{"result":-1,"error":null,"id":1}`, 0, true},
+		{"not a number", `{"result":"750000","error":null,"id":1}`, 0, true},
+		{"error", `{"result":null,"error":{"code":-28,"message":"Loading block index..."},"id":1}`, 0, true},
+	}, jsonRPCErrors...))
+}
+
+func TestHeightField(t *testing.T) {
+	// parseAlgorand, parseArweave and parsePocket read one field with heightField
+	t.Run("algorand", func(t *testing.T) {
+		checkParse(t, parseAlgorand, true, []parseTest{
+			{"status", `{"last-round":25000000,"catchup-time":0}`, 25000000, false},
+			{"round zero", `{"last-round":0}`, 0, false},
+			{"no round", `{"catchup-time":0}`, 0, true},
+			{"null round", `{"last-round":null}`, 0, true},
+			{"not a number", `{"last-round":"25000000"}`, 0, true},
+			{"not json", `not found`, 0, true},
+		})
+	})
+	t.Run("arweave", func(t *testing.T) {
+		checkParse(t, parseArweave, true, []parseTest{
+			{"info", `{"network":"arweave.N.1","height":1000000,"blocks":1000001}`, 1000000, false},
+			{"no height", `{"network":"arweave.N.1"}`, 0, true},
+			{"array", `[1000000]`, 0, true},
+		})
+	})
+	t.Run("pocket", func(t *testing.T) {
+		checkParse(t, parsePocket, true, []parseTest{
+			{"height", `{"height":65000}`, 65000, false},
+			{"no height", `{}`, 0, true},
+			{"empty", ``, 0, true},
+		})
+	})
+}
@@ -0,0 +1,323 @@
+// Package relaysuite tests that a node serves its chains, by sending each of them a probe
+// suited to the chain through the node's /v1/client/sim endpoint.
+package relaysuite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"monitoring-service/api"
+	pchttp "monitoring-service/http"
+	"monitoring-service/pocket"
+	pocketnode "monitoring-service/provider/pocket"
+	"monitoring-service/timer"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRelays  = 1
+	MaxRelays      = 10
+
+	// ChainPlaceholder is replaced by a chain's portal prefix in the reference URL.
+	ChainPlaceholder = "{chain}"
+
+	maxChains         = 64
+	maxResponseBytes  = 1024
+	maxReferenceBytes = 1 << 20
+)
+
+// Relayer sends a relay through a node's /v1/client/sim endpoint.
+type Relayer interface {
+	Relay(ctx context.Context, servicerUrl string, relay pocket.Relay) ([]byte, error)
+}
+
+// Service runs relay suites. Heights are compared with those of the reference URL, a template in
+// which ChainPlaceholder is replaced by the chain's portal prefix, e.g.
+// https://{chain}.gateway.pokt.network/v1/lb/<app id>. An empty reference URL skips the comparison.
+type Service struct {
+	relayer      Relayer
+	client       pchttp.Client
+	referenceURL string
+	timeout      time.Duration
+}
+
+// NewService returns a relay suite Service. Every relay and reference request is given timeout,
+// which defaults to DefaultTimeout.
+func NewService(relayer Relayer, client pchttp.Client, referenceURL string, timeout time.Duration) Service {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return Service{
+		relayer:      relayer,
+		client:       client,
+		referenceURL: referenceURL,
+		timeout:      timeout,
+	}
+}
+
+// RelayResult is the outcome of one relay. StatusCode is 0 when the node didn't answer at all.
+type RelayResult struct {
+	Duration   time.Duration
+	StatusCode int
+	Valid      bool
+	Height     uint
+	HasHeight  bool
+	Response   string
+	Error      string
+}
+
+// ChainResult is the outcome of the relays sent for one chain.
+type ChainResult struct {
+	Chain  pocket.Chain
+	Probe  Probe
+	Relays []RelayResult
+	// Success is whether every relay got a valid response.
+	Success bool
+	// Height is the highest height of the relays, when the probe reports one.
+	Height    uint
+	HasHeight bool
+	// ReferenceHeight is the height of the chain at the reference URL, and ReferenceError why it
+	// couldn't be fetched.
+	ReferenceHeight uint
+	HasReference    bool
+	ReferenceError  string
+}
+
+// HeightLag is how many blocks the node is behind the reference, negative when it is ahead. It is
+// only known when both heights are.
+func (c ChainResult) HeightLag() (lag int64, ok bool) {
+	if !c.HasHeight || !c.HasReference {
+		return 0, false
+	}
+
+	return int64(c.ReferenceHeight) - int64(c.Height), true
+}
+
+// Durations returns the average, fastest and slowest relay.
+func (c ChainResult) Durations() (avg, min, max time.Duration) {
+	if len(c.Relays) == 0 {
+		return 0, 0, 0
+	}
+
+	var total time.Duration
+	min = c.Relays[0].Duration
+	for _, r := range c.Relays {
+		total += r.Duration
+		if r.Duration < min {
+			min = r.Duration
+		}
+		if r.Duration > max {
+			max = r.Duration
+		}
+	}
+
+	return total / time.Duration(len(c.Relays)), min, max
+}
+
+// Run sends numRelays probes, one after the other, to every chain in chainIDs of the node at
+// nodeURL. The chains are tested concurrently, each alongside its reference height.
+func (s *Service) Run(ctx context.Context, nodeURL string, chainIDs []string, numRelays int) ([]ChainResult, error) {
+	nodeURL, err := validateNodeURL(nodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("Run: %w", err)
+	}
+
+	chainIDs = normalizeChainIDs(chainIDs)
+	if len(chainIDs) == 0 {
+		return nil, api.InvalidArgument("Run: Missing required param 'chains'", api.Details{"param": "chains"})
+	}
+	if len(chainIDs) > maxChains {
+		return nil, api.InvalidArgument(fmt.Sprintf("Run: at most %d chains can be tested at once", maxChains), api.Details{"param": "chains"})
+	}
+
+	if numRelays == 0 {
+		numRelays = DefaultRelays
+	}
+	if numRelays < 1 || numRelays > MaxRelays {
+		return nil, api.InvalidArgument(fmt.Sprintf("Run: num_relays must be between 1 and %d", MaxRelays), api.Details{"param": "num_relays", "value": numRelays})
+	}
+
+	results := make([]ChainResult, len(chainIDs))
+	var wg sync.WaitGroup
+	for i, id := range chainIDs {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			results[i] = s.testChain(ctx, nodeURL, id, numRelays)
+		}(i, id)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("Run: %w", ctx.Err())
+	}
+
+	return results, nil
+}
+
+func (s *Service) testChain(ctx context.Context, nodeURL, chainID string, numRelays int) ChainResult {
+	chain, err := pocket.ChainFromID(chainID)
+	if err != nil {
+		// unknown chains are still tested, only without a name or a reference
+		chain = pocket.Chain{ID: chainID}
+	}
+
+	result := ChainResult{Chain: chain, Probe: ProbeFor(chainID), Success: true}
+
+	var (
+		wg           sync.WaitGroup
+		refHeight    uint
+		hasReference bool
+		refErr       error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		refHeight, hasReference, refErr = s.referenceHeight(ctx, chain, result.Probe)
+	}()
+
+	for i := 0; i < numRelays; i++ {
+		r := s.relay(ctx, nodeURL, chainID, result.Probe)
+		result.Relays = append(result.Relays, r)
+		result.Success = result.Success && r.Valid
+		if r.HasHeight && r.Height >= result.Height {
+			result.Height, result.HasHeight = r.Height, true
+		}
+	}
+	wg.Wait()
+
+	result.ReferenceHeight, result.HasReference = refHeight, hasReference
+	if refErr != nil {
+		result.ReferenceError = refErr.Error()
+	}
+
+	return result
+}
+
+func (s *Service) relay(ctx context.Context, nodeURL, chainID string, probe Probe) RelayResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	t := timer.Start()
+	body, err := s.relayer.Relay(ctx, nodeURL, pocket.Relay{ChainID: chainID, Method: probe.Method, Path: probe.Path, Data: probe.Data})
+	result := RelayResult{Duration: t.Elapsed()}
+	if err != nil {
+		result.StatusCode, _ = pocketnode.StatusCode(err)
+		result.Error = err.Error()
+		return result
+	}
+
+	body = unquote(body)
+	result.StatusCode = http.StatusOK
+	result.Response = truncate(body)
+	if result.Height, result.HasHeight, err = probe.parse(body); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Valid = true
+
+	return result
+}
+
+// referenceHeight sends probe straight to the reference URL of chain.
+func (s *Service) referenceHeight(ctx context.Context, chain pocket.Chain, probe Probe) (uint, bool, error) {
+	if s.referenceURL == "" || chain.PortalPrefix == "" {
+		return 0, false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	url := strings.ReplaceAll(s.referenceURL, ChainPlaceholder, chain.PortalPrefix) + probe.Path
+	var reqBody io.Reader
+	if probe.Data != "" {
+		reqBody = bytes.NewBufferString(probe.Data)
+	}
+	req, err := http.NewRequestWithContext(ctx, probe.Method, url, reqBody)
+	if err != nil {
+		return 0, false, fmt.Errorf("referenceHeight: %w", err)
+	}
+	if probe.Data != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("referenceHeight: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxReferenceBytes))
+	if err != nil {
+		return 0, false, fmt.Errorf("referenceHeight: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("referenceHeight: got %s from %s: %s", resp.Status, chain.PortalPrefix, truncate(body))
+	}
+
+	height, ok, err := probe.parse(body)
+	if err != nil {
+		return 0, false, fmt.Errorf("referenceHeight: %w", err)
+	}
+
+	return height, ok, nil
+}
+
+// validateNodeURL returns nodeURL without surrounding space or a trailing slash, or an invalid
+// argument error if it isn't an http or https URL.
+func validateNodeURL(nodeURL string) (string, error) {
+	nodeURL = strings.TrimRight(strings.TrimSpace(nodeURL), "/")
+	if nodeURL == "" {
+		return "", api.InvalidArgument("Missing required param 'node_url'", api.Details{"param": "node_url"})
+	}
+	if !strings.HasPrefix(nodeURL, "http://") && !strings.HasPrefix(nodeURL, "https://") {
+		return "", api.InvalidArgument(fmt.Sprintf("node_url must be an http or https URL, got '%s'", nodeURL), api.Details{"param": "node_url", "value": nodeURL})
+	}
+
+	return nodeURL, nil
+}
+
+// unquote returns the chain's response when body holds it as a JSON string, which is how some
+// versions of pocket core send it back.
+func unquote(body []byte) []byte {
+	var s string
+	if len(body) > 0 && body[0] == '"' && json.Unmarshal(body, &s) == nil {
+		return []byte(s)
+	}
+
+	return body
+}
+
+func truncate(body []byte) string {
+	if len(body) > maxResponseBytes {
+		return string(body[:maxResponseBytes]) + "..."
+	}
+
+	return string(body)
+}
+
+func normalizeChainIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	normalized := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		normalized = append(normalized, id)
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
@@ -0,0 +1,60 @@
+package relaysuite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"monitoring-service/api"
+)
+
+const (
+	relaySuiteEndpointPath = "/tests/relay-suite"
+	pingEndpointPath       = "/tests/ping"
+)
+
+type transport struct {
+	Service Service
+	Routes  []api.Route
+}
+
+func NewTransport(svc Service) transport {
+	return transport{
+		Service: svc,
+		Routes: []api.Route{
+			{
+				Method:   http.MethodPost,
+				Path:     relaySuiteEndpointPath,
+				Endpoint: RelaySuiteEndpoint(svc),
+				Decoder:  decodeRelaySuiteRequest,
+				Encoder:  api.EncodeResponse,
+			},
+			{
+				Method:   http.MethodPost,
+				Path:     pingEndpointPath,
+				Endpoint: PingEndpoint(svc),
+				Decoder:  decodePingRequest,
+				Encoder:  api.EncodeResponse,
+			},
+		},
+	}
+}
+
+func decodeRelaySuiteRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	var suiteRequest relaySuiteRequest
+	if err := json.NewDecoder(req.Body).Decode(&suiteRequest); err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodeRelaySuiteRequest: invalid request body: %s", err), nil)
+	}
+
+	return suiteRequest, nil
+}
+
+func decodePingRequest(_ context.Context, req *http.Request) (request interface{}, err error) {
+	var pingRequest pingRequest
+	if err := json.NewDecoder(req.Body).Decode(&pingRequest); err != nil {
+		return nil, api.InvalidArgument(fmt.Sprintf("decodePingRequest: invalid request body: %s", err), nil)
+	}
+
+	return pingRequest, nil
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"monitoring-service/store"
+)
+
+const highWaterMarkKey = "block_times_high_water_mark"
+
+type BlockTimesRepo struct {
+	db *sql.DB
+}
+
+func NewBlockTimesRepo(db *sql.DB) BlockTimesRepo {
+	return BlockTimesRepo{db: db}
+}
+
+// Get returns the block time of height. A row that doesn't parse is reported with an error wrapping
+// store.ErrCorrupt and exists == false, so that the caller refetches and overwrites it.
+func (r BlockTimesRepo) Get(height uint) (t time.Time, exists bool, err error) {
+	var value string
+	err = r.db.QueryRow(`SELECT time FROM block_times WHERE height = ?`, height).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("BlockTimesRepo.Get [%d]: %w", height, err)
+	}
+
+	if t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+		return time.Time{}, false, fmt.Errorf("BlockTimesRepo.Get [%d]: %w: failed to parse time: %s", height, store.ErrCorrupt, err)
+	}
+
+	return t, true, nil
+}
+
+func (r BlockTimesRepo) Set(height uint, t time.Time) error {
+	_, err := r.db.Exec(`INSERT OR REPLACE INTO block_times (height, time) VALUES (?, ?)`, height, t.Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("BlockTimesRepo.Set [%d]: %w", height, err)
+	}
+
+	return nil
+}
+
+// HighWaterMark returns the height up to which every block time has been stored.
+func (r BlockTimesRepo) HighWaterMark() (height uint, exists bool, err error) {
+	var value string
+	err = r.db.QueryRow(`SELECT value FROM meta WHERE key = ?`, highWaterMarkKey).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("BlockTimesRepo.HighWaterMark: %w", err)
+	}
+
+	h, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("BlockTimesRepo.HighWaterMark: invalid value: %w", err)
+	}
+
+	return uint(h), true, nil
+}
+
+func (r BlockTimesRepo) SetHighWaterMark(height uint) error {
+	_, err := r.db.Exec(`INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)`, highWaterMarkKey, strconv.FormatUint(uint64(height), 10))
+	if err != nil {
+		return fmt.Errorf("BlockTimesRepo.SetHighWaterMark [%d]: %w", height, err)
+	}
+
+	return nil
+}
+
+// Range calls fn with every stored block time in [from, to], in height order. A zero to means no
+// upper bound. The rows are read before fn is called, so that fn may use the repo. Rows that don't
+// parse are skipped, as they will be refetched.
+func (r BlockTimesRepo) Range(from, to uint, fn func(height uint, t time.Time) error) error {
+	upper := uint64(math.MaxInt64)
+	if to > 0 {
+		upper = uint64(to)
+	}
+
+	rows, err := r.db.Query(`SELECT height, time FROM block_times WHERE height >= ? AND height <= ? ORDER BY height`, from, upper)
+	if err != nil {
+		return fmt.Errorf("BlockTimesRepo.Range: %w", err)
+	}
+
+	type blockTime struct {
+		height uint
+		time   time.Time
+	}
+	var blockTimes []blockTime
+	for rows.Next() {
+		var (
+			b     blockTime
+			value string
+		)
+		if err = rows.Scan(&b.height, &value); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("BlockTimesRepo.Range: %w", err)
+		}
+		if b.time, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			continue
+		}
+		blockTimes = append(blockTimes, b)
+	}
+	if err = rows.Close(); err != nil {
+		return fmt.Errorf("BlockTimesRepo.Range: %w", err)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("BlockTimesRepo.Range: %w", err)
+	}
+
+	for _, b := range blockTimes {
+		if err = fn(b.height, b.time); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
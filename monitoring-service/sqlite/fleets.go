@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"monitoring-service/pocket"
+)
+
+type FleetsRepo struct {
+	db *sql.DB
+}
+
+func NewFleetsRepo(db *sql.DB) FleetsRepo {
+	return FleetsRepo{db: db}
+}
+
+func (r FleetsRepo) Get(name string) (f pocket.Fleet, exists bool, err error) {
+	var value string
+	err = r.db.QueryRow(`SELECT fleet FROM fleets WHERE name = ?`, name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pocket.Fleet{}, false, nil
+	}
+	if err != nil {
+		return pocket.Fleet{}, false, fmt.Errorf("FleetsRepo.Get [%s]: %w", name, err)
+	}
+
+	if err = json.Unmarshal([]byte(value), &f); err != nil {
+		return pocket.Fleet{}, false, fmt.Errorf("FleetsRepo.Get: failed to parse json for %s: %w", name, err)
+	}
+
+	return f, true, nil
+}
+
+func (r FleetsRepo) Set(f pocket.Fleet) error {
+	fleetB, _ := json.Marshal(f)
+	if _, err := r.db.Exec(`INSERT OR REPLACE INTO fleets (name, fleet) VALUES (?, ?)`, f.Name, string(fleetB)); err != nil {
+		return fmt.Errorf("FleetsRepo.Set [%s]: %w", f.Name, err)
+	}
+
+	return nil
+}
+
+func (r FleetsRepo) Delete(name string) error {
+	if _, err := r.db.Exec(`DELETE FROM fleets WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("FleetsRepo.Delete [%s]: %w", name, err)
+	}
+
+	return nil
+}
+
+// List returns every fleet, ordered by name.
+func (r FleetsRepo) List() ([]pocket.Fleet, error) {
+	rows, err := r.db.Query(`SELECT name, fleet FROM fleets ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("FleetsRepo.List: %w", err)
+	}
+	defer rows.Close()
+
+	fleets := make([]pocket.Fleet, 0)
+	for rows.Next() {
+		var name, value string
+		if err = rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("FleetsRepo.List: %w", err)
+		}
+
+		var f pocket.Fleet
+		if err = json.Unmarshal([]byte(value), &f); err != nil {
+			return nil, fmt.Errorf("FleetsRepo.List: failed to parse json for %s: %w", name, err)
+		}
+		fleets = append(fleets, f)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("FleetsRepo.List: %w", err)
+	}
+
+	return fleets, nil
+}
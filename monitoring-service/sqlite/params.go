@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+)
+
+// ParamsRepo stores network params in SQLite. The params of every height are stored as
+// validity intervals in params_intervals, see store.ParamsIntervals.
+type ParamsRepo struct {
+	*store.ParamsIntervals
+	db *sql.DB
+}
+
+func NewParamsRepo(db *sql.DB) ParamsRepo {
+	return ParamsRepo{ParamsIntervals: store.NewParamsIntervals(sqliteIntervals{q: db}), db: db}
+}
+
+func (r ParamsRepo) Get(name string, height int64) (p pocket.Params, exists bool, err error) {
+	var value string
+	err = r.db.QueryRow(`SELECT params FROM params WHERE name = ? AND height = ?`, name, height).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pocket.Params{}, false, nil
+	}
+	if err != nil {
+		return pocket.Params{}, false, fmt.Errorf("ParamsRepo.Get [%s, %d]: %w", name, height, err)
+	}
+
+	if err = json.Unmarshal([]byte(value), &p); err != nil {
+		return pocket.Params{}, false, fmt.Errorf("ParamsRepo.Get [%s, %d]: %w: failed to parse json: %s", name, height, store.ErrCorrupt, err)
+	}
+
+	return p, true, nil
+}
+
+func (r ParamsRepo) Set(name string, height int64, p pocket.Params) error {
+	paramsB, _ := json.Marshal(p)
+	_, err := r.db.Exec(`INSERT OR REPLACE INTO params (name, height, params) VALUES (?, ?, ?)`, name, height, string(paramsB))
+	if err != nil {
+		return fmt.Errorf("ParamsRepo.Set [%s, %d]: %w", name, height, err)
+	}
+
+	return nil
+}
+
+// queryer is what sqliteIntervals needs of a *sql.DB or *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// sqliteIntervals is a store.SharedIntervalStorage, as other processes may open the same file.
+type sqliteIntervals struct {
+	q queryer
+}
+
+// Update runs fn in one database transaction. Transactions take the write lock when they begin
+// (see Open), so no other process can change the intervals between fn reading and writing them.
+func (s sqliteIntervals) Update(fn func(tx store.IntervalStorage) error) error {
+	db, ok := s.q.(*sql.DB)
+	if !ok {
+		return fn(s)
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dbTx.Rollback() }()
+
+	if err = fn(sqliteIntervals{q: dbTx}); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+// LoadIntervals returns the stored intervals. Rows that don't parse are deleted, so that the params
+// of their heights are refetched rather than failing every lookup.
+func (s sqliteIntervals) LoadIntervals() ([]store.ParamsInterval, error) {
+	rows, err := s.q.Query(`SELECT from_height, to_height, params FROM params_intervals ORDER BY from_height`)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		list    []store.ParamsInterval
+		corrupt []uint
+	)
+	for rows.Next() {
+		var (
+			interval store.ParamsInterval
+			value    string
+		)
+		if err = rows.Scan(&interval.From, &interval.To, &value); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		if json.Unmarshal([]byte(value), &interval.Params) != nil || interval.To <= interval.From {
+			corrupt = append(corrupt, interval.From)
+			continue
+		}
+		list = append(list, interval)
+	}
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// deleted once the rows are closed, rather than while the query holds its connection
+	for _, from := range corrupt {
+		if err = s.DeleteInterval(from); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+func (s sqliteIntervals) PutInterval(interval store.ParamsInterval) error {
+	paramsB, _ := json.Marshal(interval.Params)
+	_, err := s.q.Exec(`INSERT OR REPLACE INTO params_intervals (from_height, to_height, params) VALUES (?, ?, ?)`,
+		interval.From, interval.To, string(paramsB))
+	return err
+}
+
+func (s sqliteIntervals) DeleteInterval(from uint) error {
+	_, err := s.q.Exec(`DELETE FROM params_intervals WHERE from_height = ?`, from)
+	return err
+}
@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"monitoring-service/store"
+)
+
+// StakesRepo caches the stake of a node per height, keyed by the lower case address.
+type StakesRepo struct {
+	db *sql.DB
+}
+
+func NewStakesRepo(db *sql.DB) StakesRepo {
+	return StakesRepo{db: db}
+}
+
+func (r StakesRepo) Get(address string, height uint) (stake uint, exists bool, err error) {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return 0, false, fmt.Errorf("StakesRepo.Get: %w", err)
+	}
+
+	err = r.db.QueryRow(`SELECT stake FROM stakes WHERE address = ? AND height = ?`, addr, height).Scan(&stake)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("StakesRepo.Get [%s, %d]: %w", address, height, err)
+	}
+
+	return stake, true, nil
+}
+
+func (r StakesRepo) Set(address string, height uint, stake uint) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("StakesRepo.Set: %w", err)
+	}
+
+	if _, err = r.db.Exec(`INSERT OR REPLACE INTO stakes (address, height, stake) VALUES (?, ?, ?)`, addr, height, stake); err != nil {
+		return fmt.Errorf("StakesRepo.Set [%s, %d]: %w", address, height, err)
+	}
+
+	return nil
+}
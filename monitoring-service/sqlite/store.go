@@ -0,0 +1,155 @@
+// Package sqlite implements the store repositories on SQLite, through a pure Go driver so that
+// the service still builds without cgo.
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"monitoring-service/store"
+
+	_ "modernc.org/sqlite"
+)
+
+// SchemaVersion is the version of the tables created by this package, recorded in meta.
+const SchemaVersion = 1
+
+const schemaVersionKey = "schema_version"
+
+// busyTimeoutMillis is how long a connection waits for another's write lock before failing.
+const busyTimeoutMillis = 5000
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS block_times (
+		height INTEGER PRIMARY KEY,
+		time   TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS meta (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS params (
+		name   TEXT NOT NULL,
+		height INTEGER NOT NULL,
+		params TEXT NOT NULL,
+		PRIMARY KEY (name, height)
+	)`,
+	`CREATE TABLE IF NOT EXISTS params_intervals (
+		from_height INTEGER PRIMARY KEY,
+		to_height   INTEGER NOT NULL,
+		params      TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS transactions (
+		address TEXT NOT NULL,
+		hash    TEXT NOT NULL,
+		height  INTEGER NOT NULL,
+		tx      TEXT NOT NULL,
+		PRIMARY KEY (address, hash)
+	)`,
+	`CREATE INDEX IF NOT EXISTS transactions_by_height ON transactions (address, height)`,
+	`CREATE TABLE IF NOT EXISTS checkpoints (
+		address TEXT PRIMARY KEY,
+		height  INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS stakes (
+		address TEXT NOT NULL,
+		height  INTEGER NOT NULL,
+		stake   INTEGER NOT NULL,
+		PRIMARY KEY (address, height)
+	)`,
+	`CREATE TABLE IF NOT EXISTS fleets (
+		name  TEXT PRIMARY KEY,
+		fleet TEXT NOT NULL
+	)`,
+}
+
+// Store is a SQLite DB and the repositories kept in it.
+type Store struct {
+	db           *sql.DB
+	blockTimes   BlockTimesRepo
+	params       ParamsRepo
+	transactions TransactionsRepo
+	stakes       StakesRepo
+	fleets       FleetsRepo
+}
+
+// Open opens, or creates, the SQLite DB in the file at path, and creates any missing tables.
+func Open(path string) (*Store, error) {
+	// transactions take the write lock when they begin, rather than failing if another process
+	// writes between their first read and their first write
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_txlock=immediate", url.PathEscape(path), busyTimeoutMillis)
+	sqlDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("Open [%s]: %w", path, err)
+	}
+
+	for _, stmt := range schema {
+		if _, err = sqlDB.Exec(stmt); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("Open [%s]: failed to create schema: %w", path, err)
+		}
+	}
+
+	if err = checkSchemaVersion(sqlDB); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("Open [%s]: %w", path, err)
+	}
+
+	return &Store{
+		db:           sqlDB,
+		blockTimes:   NewBlockTimesRepo(sqlDB),
+		params:       NewParamsRepo(sqlDB),
+		transactions: NewTransactionsRepo(sqlDB),
+		stakes:       NewStakesRepo(sqlDB),
+		fleets:       NewFleetsRepo(sqlDB),
+	}, nil
+}
+
+// checkSchemaVersion records SchemaVersion in a new DB, and fails for one written by a newer version.
+func checkSchemaVersion(db *sql.DB) error {
+	var value string
+	err := db.QueryRow(`SELECT value FROM meta WHERE key = ?`, schemaVersionKey).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = db.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)`, schemaVersionKey, strconv.Itoa(SchemaVersion))
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid schema version %q", value)
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("schema version %d is newer than the supported %d", version, SchemaVersion)
+	}
+
+	return nil
+}
+
+func (s *Store) BlockTimes() store.BlockTimesRepo     { return s.blockTimes }
+func (s *Store) Params() store.ParamsRepo             { return s.params }
+func (s *Store) Transactions() store.TransactionsRepo { return s.transactions }
+func (s *Store) Stakes() store.StakesRepo             { return s.stakes }
+func (s *Store) Fleets() store.FleetsRepo             { return s.fleets }
+
+func (s *Store) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("Store.Close: %w", err)
+	}
+
+	return nil
+}
+
+// Compact rebuilds the DB file without the space left by deleted rows.
+func (s *Store) Compact() error {
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("Store.Compact: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+	"monitoring-service/store/storetest"
+)
+
+func TestCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	reopen := func() store.Store {
+		s, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		return s
+	}
+
+	storetest.CheckCorruption(t, reopen, storetest.Corruption{
+		BlockTime: func(s store.Store, height uint) error {
+			_, err := s.(*Store).db.Exec(`UPDATE block_times SET time = 'not a time' WHERE height = ?`, height)
+			return err
+		},
+		ParamsInterval: func(s store.Store, from uint) error {
+			_, err := s.(*Store).db.Exec(`UPDATE params_intervals SET params = '{' WHERE from_height = ?`, from)
+			return err
+		},
+	})
+}
+
+func TestParamsIntervalsSharedBetweenProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sqlite")
+	open := func() *Store {
+		s, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	}
+	params := func(value string) pocket.AllParams {
+		return pocket.AllParams{NodeParams: pocket.ParamGroup{{Key: "pos/RelaysToTokensMultiplier", Value: value}}}
+	}
+
+	// two processes sharing the file, each with its own cached intervals
+	a, b := open(), open()
+	set := func(s *Store, height int64, value string) {
+		t.Helper()
+		if err := s.Params().SetAll(height, params(value)); err != nil {
+			t.Fatalf("SetAll [%d]: %v", height, err)
+		}
+	}
+
+	set(a, 10, "x")
+	set(b, 11, "x")
+	// a has cached [10, 11), and finds the height b stored by reloading on the miss
+	if p, exists, err := a.Params().GetAll(11); err != nil || !exists || !reflect.DeepEqual(p, params("x")) {
+		t.Fatalf("GetAll [11] of the other process: %v, %t, %v", p, exists, err)
+	}
+
+	set(b, 12, "x")
+	set(a, 13, "x")
+	set(b, 14, "y")
+	set(a, 15, "y")
+	if err := b.Params().DelAll(14); err != nil {
+		t.Fatalf("DelAll [14]: %v", err)
+	}
+	set(a, 16, "y")
+
+	// every change merged into what the other process had stored
+	want := []store.ParamsInterval{
+		{From: 10, To: 14, Params: params("x")},
+		{From: 16, To: 17, Params: params("y")},
+	}
+	for name, s := range map[string]*Store{"a": a, "b": b, "reopened": open()} {
+		got, err := s.Params().Intervals()
+		if err != nil {
+			t.Fatalf("Intervals: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("intervals of %s: %+v, want %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+)
+
+// TransactionsRepo stores enriched transactions per account, keyed by the lower case address
+// and tx hash.
+type TransactionsRepo struct {
+	db *sql.DB
+}
+
+func NewTransactionsRepo(db *sql.DB) TransactionsRepo {
+	return TransactionsRepo{db: db}
+}
+
+// AccountTransactions returns every stored transaction for address, newest first.
+func (r TransactionsRepo) AccountTransactions(address string) ([]pocket.Transaction, error) {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions: %w", err)
+	}
+
+	rows, err := r.db.Query(`SELECT tx FROM transactions WHERE address = ? ORDER BY height DESC, hash`, addr)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions [%s]: %w", address, err)
+	}
+	defer rows.Close()
+
+	txs := make([]pocket.Transaction, 0)
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("TransactionsRepo.AccountTransactions [%s]: %w", address, err)
+		}
+
+		var tx pocket.Transaction
+		if err = json.Unmarshal([]byte(value), &tx); err != nil {
+			return nil, fmt.Errorf("TransactionsRepo.AccountTransactions: failed to parse json for %s: %w", address, err)
+		}
+		txs = append(txs, tx)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.AccountTransactions [%s]: %w", address, err)
+	}
+
+	return txs, nil
+}
+
+// SetTransactions stores txs for address, replacing any stored transaction with the same hash.
+// Either every transaction is stored or none is.
+func (r TransactionsRepo) SetTransactions(address string, txs []pocket.Transaction) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.SetTransactions: %w", err)
+	}
+
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.SetTransactions [%s]: %w", address, err)
+	}
+	defer func() { _ = dbTx.Rollback() }()
+
+	if err = insertTransactions(dbTx, addr, txs); err != nil {
+		return fmt.Errorf("TransactionsRepo.SetTransactions [%s]: %w", address, err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("TransactionsRepo.SetTransactions [%s]: %w", address, err)
+	}
+
+	return nil
+}
+
+// ReplaceTransactionsAbove removes the stored transactions for address with a height greater than
+// height and stores txs, in one database transaction.
+func (r TransactionsRepo) ReplaceTransactionsAbove(address string, height uint, txs []pocket.Transaction) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove: %w", err)
+	}
+
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove [%s]: %w", address, err)
+	}
+	defer func() { _ = dbTx.Rollback() }()
+
+	if _, err = dbTx.Exec(`DELETE FROM transactions WHERE address = ? AND height > ?`, addr, height); err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove [%s, %d]: %w", address, height, err)
+	}
+	if err = insertTransactions(dbTx, addr, txs); err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove [%s]: %w", address, err)
+	}
+
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("TransactionsRepo.ReplaceTransactionsAbove [%s]: %w", address, err)
+	}
+
+	return nil
+}
+
+func insertTransactions(dbTx *sql.Tx, addr string, txs []pocket.Transaction) error {
+	for _, tx := range txs {
+		hash, err := store.NormalizeHex(tx.Hash)
+		if err != nil {
+			return err
+		}
+
+		txB, _ := json.Marshal(tx)
+		_, err = dbTx.Exec(`INSERT OR REPLACE INTO transactions (address, hash, height, tx) VALUES (?, ?, ?, ?)`,
+			addr, hash, tx.Height, string(txB))
+		if err != nil {
+			return fmt.Errorf("%s: %w", tx.Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteTransactionsAbove removes the stored transactions for address with a height greater than height.
+func (r TransactionsRepo) DeleteTransactionsAbove(address string, height uint) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove: %w", err)
+	}
+
+	if _, err = r.db.Exec(`DELETE FROM transactions WHERE address = ? AND height > ?`, addr, height); err != nil {
+		return fmt.Errorf("TransactionsRepo.DeleteTransactionsAbove [%s, %d]: %w", address, height, err)
+	}
+
+	return nil
+}
+
+// Checkpoint returns the height up to which transactions for address have been indexed.
+func (r TransactionsRepo) Checkpoint(address string) (height uint, exists bool, err error) {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return 0, false, fmt.Errorf("TransactionsRepo.Checkpoint: %w", err)
+	}
+
+	err = r.db.QueryRow(`SELECT height FROM checkpoints WHERE address = ?`, addr).Scan(&height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("TransactionsRepo.Checkpoint [%s]: %w", address, err)
+	}
+
+	return height, true, nil
+}
+
+func (r TransactionsRepo) SetCheckpoint(address string, height uint) error {
+	addr, err := store.NormalizeHex(address)
+	if err != nil {
+		return fmt.Errorf("TransactionsRepo.SetCheckpoint: %w", err)
+	}
+
+	if _, err = r.db.Exec(`INSERT OR REPLACE INTO checkpoints (address, height) VALUES (?, ?)`, addr, height); err != nil {
+		return fmt.Errorf("TransactionsRepo.SetCheckpoint [%s, %d]: %w", address, height, err)
+	}
+
+	return nil
+}
+
+// Addresses returns the addresses with a checkpoint, in lower case and sorted.
+func (r TransactionsRepo) Addresses() ([]string, error) {
+	rows, err := r.db.Query(`SELECT address FROM checkpoints ORDER BY address`)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.Addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := make([]string, 0)
+	for rows.Next() {
+		var addr string
+		if err = rows.Scan(&addr); err != nil {
+			return nil, fmt.Errorf("TransactionsRepo.Addresses: %w", err)
+		}
+		addresses = append(addresses, addr)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.Addresses: %w", err)
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,67 @@
+// Package backend opens a store.Store of any kind, for commands that let the user choose.
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"monitoring-service/db"
+	"monitoring-service/inmem"
+	"monitoring-service/sqlite"
+	"monitoring-service/store"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	_ store.Store = (*db.Store)(nil)
+	_ store.Store = (*sqlite.Store)(nil)
+	_ store.Store = (*inmem.Store)(nil)
+)
+
+// DefaultPath returns where a store of kind keeps its data in dir.
+func DefaultPath(kind store.Kind, dir string) string {
+	switch kind {
+	case store.SQLite:
+		return filepath.Join(dir, ".pokt-calculator.sqlite")
+	case store.Memory:
+		return ""
+	default:
+		return filepath.Join(dir, ".pokt-calculator-db")
+	}
+}
+
+// Open opens the store of kind at path: a directory for bitcask, a file for SQLite, and nothing
+// for the in-memory store. A bitcask DB of an earlier schema is migrated, see db.Store.Migrate.
+func Open(kind store.Kind, path string, logger log.Logger) (store.Store, error) {
+	switch kind {
+	case store.Bitcask:
+		s, err := db.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("backend.Open: %w", err)
+		}
+
+		if migrated, err := s.Migrate(); err != nil {
+			_ = s.Close()
+			return nil, fmt.Errorf("backend.Open: %w", err)
+		} else if migrated > 0 {
+			_ = logger.Log("db", "migrated to schema version", "version", db.SchemaVersion, "entries", migrated)
+			// reclaim the space of the rewritten entries
+			if err = s.Merge(); err != nil {
+				_ = logger.Log("ERROR merging database", "err", err)
+			}
+		}
+
+		return s, nil
+	case store.SQLite:
+		s, err := sqlite.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("backend.Open: %w", err)
+		}
+		return s, nil
+	case store.Memory:
+		return inmem.NewStore(), nil
+	default:
+		return nil, fmt.Errorf("backend.Open: unknown store %q", kind)
+	}
+}
@@ -0,0 +1,25 @@
+package backend
+
+import (
+	"testing"
+
+	"monitoring-service/store"
+	"monitoring-service/store/storetest"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestBackends(t *testing.T) {
+	for _, kind := range []store.Kind{store.Bitcask, store.SQLite, store.Memory} {
+		kind := kind
+		t.Run(string(kind), func(t *testing.T) {
+			storetest.Check(t, func() store.Store {
+				s, err := Open(kind, DefaultPath(kind, t.TempDir()), log.NewNopLogger())
+				if err != nil {
+					t.Fatalf("Open: %v", err)
+				}
+				return s
+			})
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"monitoring-service/pocket"
+)
+
+// ParamsInterval holds the params that applied to every height in [From, To).
+type ParamsInterval struct {
+	From   uint
+	To     uint
+	Params pocket.AllParams
+}
+
+// IntervalStorage persists the intervals of a ParamsIntervals.
+type IntervalStorage interface {
+	LoadIntervals() ([]ParamsInterval, error)
+	PutInterval(interval ParamsInterval) error
+	DeleteInterval(from uint) error
+}
+
+// SharedIntervalStorage is an IntervalStorage that other processes can write to as well, such as
+// a SQLite file used by several services. ParamsIntervals then reloads the intervals before every
+// change, inside Update, so that it merges into what is stored rather than into a stale copy.
+type SharedIntervalStorage interface {
+	IntervalStorage
+	// Update calls fn with a storage whose reads and writes are one transaction that no other
+	// writer can interleave with. The writes are only kept if fn returns nil.
+	Update(fn func(tx IntervalStorage) error) error
+}
+
+// ParamsIntervals implements GetAll, SetAll and DelAll of ParamsRepo on top of an
+// IntervalStorage. Params fetched for a height next to an interval with the same values extend
+// it, so a backend holds about one interval per governance change rather than one snapshot per
+// height. The intervals are loaded the first time they are needed and kept in memory; with a
+// SharedIntervalStorage they are also reloaded before each change, and when a lookup misses.
+type ParamsIntervals struct {
+	storage IntervalStorage
+
+	mu     sync.Mutex
+	loaded bool
+	list   []ParamsInterval // sorted by From, never overlapping
+}
+
+func NewParamsIntervals(storage IntervalStorage) *ParamsIntervals {
+	return &ParamsIntervals{storage: storage}
+}
+
+// GetAll returns the params of the interval containing height.
+func (iv *ParamsIntervals) GetAll(height int64) (params pocket.AllParams, exists bool, err error) {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	if err = iv.load(); err != nil {
+		return pocket.AllParams{}, false, fmt.Errorf("ParamsIntervals.GetAll: %s", err)
+	}
+
+	i, found := iv.find(uint(height))
+	if !found && iv.shared() {
+		// another process may have fetched it since
+		if err = iv.reload(); err != nil {
+			return pocket.AllParams{}, false, fmt.Errorf("ParamsIntervals.GetAll: %s", err)
+		}
+		i, found = iv.find(uint(height))
+	}
+	if !found {
+		return pocket.AllParams{}, false, nil
+	}
+
+	return iv.list[i].Params, true, nil
+}
+
+// DelAll removes the interval containing height, so that every height in it is fetched again.
+func (iv *ParamsIntervals) DelAll(height int64) error {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	err := iv.change(func(storage IntervalStorage) error {
+		i, found := iv.find(uint(height))
+		if !found {
+			return nil
+		}
+
+		if err := storage.DeleteInterval(iv.list[i].From); err != nil {
+			return err
+		}
+		iv.list = append(iv.list[:i], iv.list[i+1:]...)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ParamsIntervals.DelAll: %s", err)
+	}
+
+	return nil
+}
+
+// SetAll records params for height. When the interval ending just below height or starting just
+// above it has the same params it is extended to cover height; otherwise a new single height
+// interval is added. Heights that weren't fetched are never covered, even between equal params.
+func (iv *ParamsIntervals) SetAll(height int64, params pocket.AllParams) error {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	err := iv.change(func(storage IntervalStorage) error {
+		return iv.setAll(storage, uint(height), params)
+	})
+	if err != nil {
+		return fmt.Errorf("ParamsIntervals.SetAll [%d]: %s", height, err)
+	}
+
+	return nil
+}
+
+// SetInterval records interval.Params for every height in [interval.From, interval.To), for
+// intervals known to be valid as a whole, such as those of another store. Stored intervals with
+// other params are cut back around it, and adjacent ones with the same params are merged into it.
+func (iv *ParamsIntervals) SetInterval(interval ParamsInterval) error {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	if interval.From >= interval.To {
+		return fmt.Errorf("ParamsIntervals.SetInterval [%d, %d): empty interval", interval.From, interval.To)
+	}
+
+	err := iv.change(func(storage IntervalStorage) error {
+		return iv.setInterval(storage, interval)
+	})
+	if err != nil {
+		return fmt.Errorf("ParamsIntervals.SetInterval [%d, %d): %s", interval.From, interval.To, err)
+	}
+
+	return nil
+}
+
+// Intervals returns a copy of the stored intervals, ordered by From.
+func (iv *ParamsIntervals) Intervals() ([]ParamsInterval, error) {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+
+	load := iv.load
+	if iv.shared() {
+		load = iv.reload
+	}
+	if err := load(); err != nil {
+		return nil, fmt.Errorf("ParamsIntervals.Intervals: %s", err)
+	}
+
+	return append([]ParamsInterval{}, iv.list...), nil
+}
+
+func (iv *ParamsIntervals) setAll(storage IntervalStorage, h uint, params pocket.AllParams) error {
+	if i, found := iv.find(h); found && sameParams(iv.list[i].Params, params) {
+		return nil
+	}
+
+	return iv.setInterval(storage, ParamsInterval{From: h, To: h + 1, Params: params})
+}
+
+// setInterval stores n in storage, cutting the intervals it overlaps that have other params, and
+// merging those it overlaps or touches that have the same. The caller must hold the lock.
+func (iv *ParamsIntervals) setInterval(storage IntervalStorage, n ParamsInterval) error {
+	var before, after, deleted, cut []ParamsInterval
+	for _, old := range iv.list {
+		switch {
+		case old.To < n.From:
+			before = append(before, old)
+		case old.From > n.To:
+			after = append(after, old)
+		case sameParams(old.Params, n.Params):
+			// overlapping or adjacent, with the same params
+			deleted = append(deleted, old)
+			if old.From < n.From {
+				n.From = old.From
+			}
+			if old.To > n.To {
+				n.To = old.To
+			}
+		case old.To == n.From:
+			before = append(before, old)
+		case old.From == n.To:
+			after = append(after, old)
+		default:
+			// overlapping, with other params: keep what lies outside n
+			deleted = append(deleted, old)
+			if old.From < n.From {
+				left := ParamsInterval{From: old.From, To: n.From, Params: old.Params}
+				before, cut = append(before, left), append(cut, left)
+			}
+			if old.To > n.To {
+				right := ParamsInterval{From: n.To, To: old.To, Params: old.Params}
+				after, cut = append(after, right), append(cut, right)
+			}
+		}
+	}
+
+	for _, old := range deleted {
+		if err := storage.DeleteInterval(old.From); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range append(cut, n) {
+		if err := storage.PutInterval(p); err != nil {
+			return err
+		}
+	}
+	iv.list = append(append(before, n), after...)
+
+	return nil
+}
+
+func sameParams(a, b pocket.AllParams) bool {
+	aB, _ := json.Marshal(a)
+	bB, _ := json.Marshal(b)
+	return bytes.Equal(aB, bB)
+}
+
+// find returns the index of the interval containing h. The caller must hold the lock.
+func (iv *ParamsIntervals) find(h uint) (int, bool) {
+	i := sort.Search(len(iv.list), func(i int) bool { return iv.list[i].To > h })
+	if i < len(iv.list) && iv.list[i].From <= h {
+		return i, true
+	}
+	return 0, false
+}
+
+// change calls fn with the storage to write to, once the in-memory intervals match what is stored.
+// For a SharedIntervalStorage that means reloading them in the same transaction as fn's writes.
+// The caller must hold the lock.
+func (iv *ParamsIntervals) change(fn func(storage IntervalStorage) error) error {
+	shared, ok := iv.storage.(SharedIntervalStorage)
+	if !ok {
+		if err := iv.load(); err != nil {
+			return err
+		}
+		return fn(iv.storage)
+	}
+
+	err := shared.Update(func(tx IntervalStorage) error {
+		if err := iv.loadFrom(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+	if err != nil {
+		// the writes were rolled back, so the in-memory intervals may no longer match
+		iv.loaded = false
+	}
+
+	return err
+}
+
+func (iv *ParamsIntervals) shared() bool {
+	_, ok := iv.storage.(SharedIntervalStorage)
+	return ok
+}
+
+// load reads the stored intervals the first time they are needed. The caller must hold the lock.
+func (iv *ParamsIntervals) load() error {
+	if iv.loaded {
+		return nil
+	}
+
+	return iv.loadFrom(iv.storage)
+}
+
+// reload reads the stored intervals again. The caller must hold the lock.
+func (iv *ParamsIntervals) reload() error {
+	return iv.loadFrom(iv.storage)
+}
+
+// loadFrom replaces the in-memory intervals with those of storage. The caller must hold the lock.
+func (iv *ParamsIntervals) loadFrom(storage IntervalStorage) error {
+	list, err := storage.LoadIntervals()
+	if err != nil {
+		iv.loaded = false
+		return err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].From < list[j].From
+	})
+
+	iv.list = list
+	iv.loaded = true
+	return nil
+}
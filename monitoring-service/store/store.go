@@ -0,0 +1,127 @@
+// Package store defines the repositories the service caches network data in. The db package
+// implements them on bitcask, the sqlite package on SQLite and the inmem package in memory.
+package store
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"monitoring-service/pocket"
+)
+
+// Kind names a storage backend.
+type Kind string
+
+const (
+	Bitcask Kind = "bitcask"
+	SQLite  Kind = "sqlite"
+	Memory  Kind = "memory"
+)
+
+// Kinds lists the supported backends.
+var Kinds = []Kind{Bitcask, SQLite, Memory}
+
+// BlockTimesRepo caches the time of each block, along with the height up to which every block
+// time has been stored.
+type BlockTimesRepo interface {
+	Get(height uint) (t time.Time, exists bool, err error)
+	Set(height uint, t time.Time) error
+	HighWaterMark() (height uint, exists bool, err error)
+	SetHighWaterMark(height uint) error
+
+	// Range calls fn with every stored block time in [from, to], in height order, and stops at
+	// the first error fn returns. A zero to means no upper bound.
+	Range(from, to uint, fn func(height uint, t time.Time) error) error
+}
+
+// ParamsRepo caches network params. GetAll, SetAll and DelAll work on the params of every
+// height, which are stored as validity intervals (see ParamsIntervals).
+type ParamsRepo interface {
+	Get(name string, height int64) (p pocket.Params, exists bool, err error)
+	Set(name string, height int64, p pocket.Params) error
+	GetAll(height int64) (params pocket.AllParams, exists bool, err error)
+	SetAll(height int64, params pocket.AllParams) error
+	SetInterval(interval ParamsInterval) error
+	DelAll(height int64) error
+	Intervals() ([]ParamsInterval, error)
+}
+
+// TransactionsRepo stores enriched transactions per account and a checkpoint per account.
+type TransactionsRepo interface {
+	AccountTransactions(address string) ([]pocket.Transaction, error)
+	SetTransactions(address string, txs []pocket.Transaction) error
+	DeleteTransactionsAbove(address string, height uint) error
+	ReplaceTransactionsAbove(address string, height uint, txs []pocket.Transaction) error
+	Checkpoint(address string) (height uint, exists bool, err error)
+	SetCheckpoint(address string, height uint) error
+
+	// Addresses returns the addresses with a checkpoint, in lower case and sorted.
+	Addresses() ([]string, error)
+}
+
+// StakesRepo caches the uPOKT a node had staked at a height, which can't change once the height
+// is committed. A stake of 0 records that the address wasn't a node at the height.
+type StakesRepo interface {
+	Get(address string, height uint) (stake uint, exists bool, err error)
+	Set(address string, height uint, stake uint) error
+}
+
+// FleetsRepo stores named groups of node addresses.
+type FleetsRepo interface {
+	Get(name string) (f pocket.Fleet, exists bool, err error)
+	Set(f pocket.Fleet) error
+	Delete(name string) error
+	List() ([]pocket.Fleet, error)
+}
+
+// Store is an open backend and its repositories. Lookups of missing entries return
+// exists == false and a nil error on every backend.
+type Store interface {
+	BlockTimes() BlockTimesRepo
+	Params() ParamsRepo
+	Transactions() TransactionsRepo
+	Stakes() StakesRepo
+	Fleets() FleetsRepo
+	Close() error
+}
+
+// Compacter is implemented by stores that can reclaim the space of deleted and overwritten data.
+type Compacter interface {
+	Compact() error
+}
+
+// ParseKind returns the backend named s.
+func ParseKind(s string) (Kind, error) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown store %q, expected one of %s", s, strings.Join(kindNames(), ", "))
+}
+
+func kindNames() []string {
+	names := make([]string, len(Kinds))
+	for i, k := range Kinds {
+		names[i] = string(k)
+	}
+	return names
+}
+
+// NormalizeHex validates a hex encoded address or tx hash and returns it in lower case, so that
+// every backend treats addresses and hashes that differ only in case as the same.
+func NormalizeHex(s string) (string, error) {
+	if _, err := hex.DecodeString(s); err != nil {
+		return "", fmt.Errorf("invalid hex %s: %s", s, err)
+	}
+
+	return strings.ToLower(s), nil
+}
+
+// ErrCorrupt is wrapped by the errors of lookups that found an entry failing its integrity
+// check. Callers that can refetch the entry should treat it as missing and overwrite it.
+var ErrCorrupt = errors.New("corrupt entry")
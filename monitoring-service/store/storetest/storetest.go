@@ -0,0 +1,546 @@
+// Package storetest checks that a store.Store behaves the way the rest of the service expects,
+// so that every backend can be held to the same contract. Each backend's tests call Check with a
+// function that opens an empty store of their kind:
+//
+//	storetest.Check(t, func() store.Store {
+//		s, err := sqlite.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+//		...
+//		return s
+//	})
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"monitoring-service/pocket"
+	"monitoring-service/store"
+)
+
+// concurrentWriters is the number of goroutines used to check that a store is safe for concurrent use.
+const concurrentWriters = 8
+
+// Check runs every conformance check as a subtest of t, each against an empty store returned by
+// open. The stores are closed when their subtest ends.
+func Check(t *testing.T, open func() store.Store) {
+	run := func(name string, check func(t *testing.T, s store.Store)) {
+		t.Run(name, func(t *testing.T) {
+			s := open()
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			check(t, s)
+		})
+	}
+
+	run("BlockTimes", func(t *testing.T, s store.Store) { blockTimes(t, s.BlockTimes()) })
+	run("Params", func(t *testing.T, s store.Store) { params(t, s.Params()) })
+	run("Transactions", func(t *testing.T, s store.Store) { transactions(t, s.Transactions()) })
+	run("Stakes", func(t *testing.T, s store.Store) { stakes(t, s.Stakes()) })
+	run("Fleets", func(t *testing.T, s store.Store) { fleets(t, s.Fleets()) })
+	run("Concurrency", func(t *testing.T, s store.Store) { concurrency(t, s.BlockTimes()) })
+	run("Keys", keys)
+}
+
+// Corruption damages the stored entries of a store in place, the way a bad disk or an interrupted
+// write would.
+type Corruption struct {
+	BlockTime      func(s store.Store, height uint) error
+	ParamsInterval func(s store.Store, from uint) error
+}
+
+// CheckCorruption checks that corrupt entries are treated as missing, so that they are refetched
+// and overwritten rather than failing every lookup. reopen must return the same store each time it
+// is called, after the previous one was closed, so that nothing is served from memory.
+func CheckCorruption(t *testing.T, reopen func() store.Store, corrupt Corruption) {
+	at := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	a, b := allParams("a"), allParams("b")
+
+	s := reopen()
+	for _, h := range []uint{5, 6} {
+		if err := s.BlockTimes().Set(h, at.Add(time.Duration(h)*time.Minute)); err != nil {
+			t.Fatalf("BlockTimes.Set(%d): %v", h, err)
+		}
+	}
+	if err := s.Params().SetAll(10, a); err != nil {
+		t.Fatalf("Params.SetAll(10): %v", err)
+	}
+	if err := s.Params().SetAll(20, b); err != nil {
+		t.Fatalf("Params.SetAll(20): %v", err)
+	}
+	if err := corrupt.BlockTime(s, 5); err != nil {
+		t.Fatalf("corrupting the block time of 5: %v", err)
+	}
+	if err := corrupt.ParamsInterval(s, 10); err != nil {
+		t.Fatalf("corrupting the params interval from 10: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s = reopen()
+	defer s.Close()
+
+	r := s.BlockTimes()
+	if _, exists, err := r.Get(5); exists || !errors.Is(err, store.ErrCorrupt) {
+		t.Errorf("BlockTimes.Get of a corrupt entry: exists %t, err %v, want false, %v", exists, err, store.ErrCorrupt)
+	}
+	wantRange(t, r, 0, 0, []uint{6})
+	if err := r.Set(5, at); err != nil {
+		t.Errorf("BlockTimes.Set over a corrupt entry: %v", err)
+	}
+	if got, exists, err := r.Get(5); err != nil || !exists || !got.Equal(at) {
+		t.Errorf("BlockTimes.Get of a refetched entry: %s, %t, %v, want %s, true, nil", got, exists, err, at)
+	}
+
+	// the other intervals are still served, and the params of the corrupt one are fetched again
+	p := s.Params()
+	if _, exists, err := p.GetAll(10); err != nil || exists {
+		t.Errorf("Params.GetAll in a corrupt interval: exists %t, err %v, want false, nil", exists, err)
+	}
+	wantAll(t, p, 20, b)
+	if err := p.SetAll(10, a); err != nil {
+		t.Errorf("Params.SetAll over a corrupt interval: %v", err)
+	}
+	wantAll(t, p, 10, a)
+}
+
+func blockTimes(t *testing.T, r store.BlockTimesRepo) {
+	if _, exists, err := r.Get(1); err != nil || exists {
+		t.Errorf("BlockTimes.Get of a missing height: exists %t, err %v, want false, nil", exists, err)
+	}
+
+	at := time.Date(2022, 3, 1, 12, 30, 15, 123456789, time.UTC)
+	if err := r.Set(1, at); err != nil {
+		t.Errorf("BlockTimes.Set: %v", err)
+	}
+	if got, exists, err := r.Get(1); err != nil || !exists || !got.Equal(at) {
+		t.Errorf("BlockTimes.Get after Set: %s, %t, %v, want %s, true, nil", got, exists, err, at)
+	}
+
+	overwritten := at.Add(time.Minute)
+	if err := r.Set(1, overwritten); err != nil {
+		t.Errorf("BlockTimes.Set overwrite: %v", err)
+	}
+	if got, _, _ := r.Get(1); !got.Equal(overwritten) {
+		t.Errorf("BlockTimes.Get after overwrite: %s, want %s", got, overwritten)
+	}
+
+	for _, h := range []uint{2, 3, 5} {
+		if err := r.Set(h, at.Add(time.Duration(h)*time.Minute)); err != nil {
+			t.Errorf("BlockTimes.Set(%d): %v", h, err)
+		}
+	}
+	wantRange(t, r, 2, 4, []uint{2, 3})
+	wantRange(t, r, 0, 0, []uint{1, 2, 3, 5})
+
+	if _, exists, err := r.HighWaterMark(); err != nil || exists {
+		t.Errorf("BlockTimes.HighWaterMark before it was set: exists %t, err %v, want false, nil", exists, err)
+	}
+	for _, mark := range []uint{10, 5} {
+		if err := r.SetHighWaterMark(mark); err != nil {
+			t.Errorf("BlockTimes.SetHighWaterMark(%d): %v", mark, err)
+		}
+		if got, exists, err := r.HighWaterMark(); err != nil || !exists || got != mark {
+			t.Errorf("BlockTimes.HighWaterMark: %d, %t, %v, want %d, true, nil", got, exists, err, mark)
+		}
+	}
+}
+
+func wantRange(t *testing.T, r store.BlockTimesRepo, from, to uint, want []uint) {
+	t.Helper()
+	got := make([]uint, 0, len(want))
+	err := r.Range(from, to, func(height uint, _ time.Time) error {
+		got = append(got, height)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("BlockTimes.Range(%d, %d): %v, %v, want %v, nil", from, to, got, err, want)
+	}
+}
+
+func params(t *testing.T, r store.ParamsRepo) {
+	if _, exists, err := r.Get("pocketParams", 1); err != nil || exists {
+		t.Errorf("Params.Get of a missing param: exists %t, err %v, want false, nil", exists, err)
+	}
+
+	p := pocket.Params{RelaysToTokensMultiplier: 8461, DaoAllocation: 10, ProposerPercentage: 1, ClaimExpirationBlocks: 120}
+	if err := r.Set("pocketParams", 1, p); err != nil {
+		t.Errorf("Params.Set: %v", err)
+	}
+	if got, exists, err := r.Get("pocketParams", 1); err != nil || !exists || got != p {
+		t.Errorf("Params.Get after Set: %+v, %t, %v, want %+v, true, nil", got, exists, err, p)
+	}
+	if _, exists, _ := r.Get("pocketParams", 2); exists {
+		t.Errorf("Params.Get at another height: exists, want missing")
+	}
+
+	// names starting with digits mustn't be mistaken for another height's
+	q := pocket.Params{RelaysToTokensMultiplier: 1}
+	if err := r.Set("2x", 1, p); err != nil {
+		t.Errorf("Params.Set: %v", err)
+	}
+	if err := r.Set("x", 12, q); err != nil {
+		t.Errorf("Params.Set: %v", err)
+	}
+	if got, _, err := r.Get("2x", 1); err != nil || got != p {
+		t.Errorf("Params.Get(2x, 1): %+v, %v, want %+v, nil", got, err, p)
+	}
+
+	a, b := allParams("a"), allParams("b")
+	if _, exists, err := r.GetAll(10); err != nil || exists {
+		t.Errorf("Params.GetAll of a missing height: exists %t, err %v, want false, nil", exists, err)
+	}
+
+	// 10 and 12 hold the same params, but 11 wasn't fetched, so it isn't covered
+	for _, h := range []int64{10, 12} {
+		if err := r.SetAll(h, a); err != nil {
+			t.Errorf("Params.SetAll(%d): %v", h, err)
+		}
+	}
+	if _, exists, _ := r.GetAll(11); exists {
+		t.Errorf("Params.GetAll(11) between equal params: exists, want missing")
+	}
+	wantIntervals(t, r, [2]uint{10, 11}, [2]uint{12, 13})
+
+	// once fetched, 11 joins both into one interval
+	if err := r.SetAll(11, a); err != nil {
+		t.Errorf("Params.SetAll(11): %v", err)
+	}
+	wantAll(t, r, 11, a)
+	if _, exists, _ := r.GetAll(13); exists {
+		t.Errorf("Params.GetAll(13) past the interval: exists, want missing")
+	}
+	wantIntervals(t, r, [2]uint{10, 13})
+
+	// differing params at 11 split the interval
+	if err := r.SetAll(11, b); err != nil {
+		t.Errorf("Params.SetAll(11): %v", err)
+	}
+	wantAll(t, r, 10, a)
+	wantAll(t, r, 11, b)
+	wantAll(t, r, 12, a)
+
+	if err := r.DelAll(11); err != nil {
+		t.Errorf("Params.DelAll(11): %v", err)
+	}
+	if _, exists, _ := r.GetAll(11); exists {
+		t.Errorf("Params.GetAll(11) after DelAll: exists, want missing")
+	}
+	wantAll(t, r, 12, a)
+
+	wantIntervals(t, r, [2]uint{10, 11}, [2]uint{12, 13})
+
+	// a whole interval cuts those with other params around it, and merges those it touches with the same
+	for _, interval := range []store.ParamsInterval{
+		{From: 20, To: 30, Params: a},
+		{From: 25, To: 27, Params: b},
+		{From: 30, To: 40, Params: a},
+		{From: 13, To: 18, Params: a},
+	} {
+		if err := r.SetInterval(interval); err != nil {
+			t.Errorf("Params.SetInterval([%d, %d)): %v", interval.From, interval.To, err)
+		}
+	}
+	wantAll(t, r, 24, a)
+	wantAll(t, r, 26, b)
+	wantAll(t, r, 35, a)
+	wantIntervals(t, r, [2]uint{10, 11}, [2]uint{12, 18}, [2]uint{20, 25}, [2]uint{25, 27}, [2]uint{27, 40})
+}
+
+// wantIntervals checks the [From, To) of every stored params interval.
+func wantIntervals(t *testing.T, r store.ParamsRepo, want ...[2]uint) {
+	t.Helper()
+	intervals, err := r.Intervals()
+	got := make([][2]uint, len(intervals))
+	for i, interval := range intervals {
+		got[i] = [2]uint{interval.From, interval.To}
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Params.Intervals: %v, %v, want %v", got, err, want)
+	}
+}
+
+func wantAll(t *testing.T, r store.ParamsRepo, height int64, want pocket.AllParams) {
+	t.Helper()
+	got, exists, err := r.GetAll(height)
+	if err != nil || !exists || !reflect.DeepEqual(got, want) {
+		t.Errorf("Params.GetAll(%d): %+v, %t, %v, want %+v, true, nil", height, got, exists, err, want)
+	}
+}
+
+func transactions(t *testing.T, r store.TransactionsRepo) {
+	const address = "AB12CD34"
+
+	if txs, err := r.AccountTransactions(address); err != nil || len(txs) != 0 {
+		t.Errorf("Transactions.AccountTransactions of an unknown address: %d txs, %v, want 0, nil", len(txs), err)
+	}
+
+	txs := []pocket.Transaction{
+		{Hash: "AA01", Height: 5, Type: pocket.TypeClaim, NumRelays: 10},
+		{Hash: "AA02", Height: 7, Type: pocket.TypeProof, NumRelays: 10},
+		{Hash: "AA03", Height: 6, Type: pocket.TypeClaim, NumRelays: 20},
+	}
+	if err := r.SetTransactions(address, txs); err != nil {
+		t.Errorf("Transactions.SetTransactions: %v", err)
+	}
+
+	// a stored hash is replaced, and the address is case insensitive
+	replaced := txs[0]
+	replaced.NumRelays = 11
+	if err := r.SetTransactions(strings.ToLower(address), []pocket.Transaction{replaced}); err != nil {
+		t.Errorf("Transactions.SetTransactions replace: %v", err)
+	}
+
+	got, err := r.AccountTransactions(address)
+	if err != nil {
+		t.Errorf("Transactions.AccountTransactions: %v", err)
+	}
+	if heights := txHeights(got); !reflect.DeepEqual(heights, []uint{7, 6, 5}) {
+		t.Errorf("Transactions.AccountTransactions heights: %v, want [7 6 5], newest first", heights)
+	} else if got[2].NumRelays != 11 {
+		t.Errorf("Transactions.SetTransactions didn't replace the stored tx: %d relays, want 11", got[2].NumRelays)
+	}
+
+	if err = r.DeleteTransactionsAbove(address, 5); err != nil {
+		t.Errorf("Transactions.DeleteTransactionsAbove: %v", err)
+	}
+	got, _ = r.AccountTransactions(address)
+	if heights := txHeights(got); !reflect.DeepEqual(heights, []uint{5}) {
+		t.Errorf("Transactions.AccountTransactions after DeleteTransactionsAbove(5): %v, want [5]", heights)
+	}
+
+	// the tx at 5 was reorged away, and one above the replaced range is kept
+	if err = r.SetTransactions(address, []pocket.Transaction{{Hash: "AA06", Height: 3}}); err != nil {
+		t.Errorf("Transactions.SetTransactions: %v", err)
+	}
+	replacement := []pocket.Transaction{{Hash: "AA04", Height: 6}, {Hash: "AA05", Height: 8}}
+	if err = r.ReplaceTransactionsAbove(address, 4, replacement); err != nil {
+		t.Errorf("Transactions.ReplaceTransactionsAbove: %v", err)
+	}
+	got, _ = r.AccountTransactions(address)
+	if heights := txHeights(got); !reflect.DeepEqual(heights, []uint{8, 6, 3}) {
+		t.Errorf("Transactions.AccountTransactions after ReplaceTransactionsAbove(4): %v, want [8 6 3]", heights)
+	}
+
+	// a replace that fails changes nothing
+	if err = r.ReplaceTransactionsAbove(address, 0, []pocket.Transaction{{Hash: "AA07", Height: 9}, {Hash: "not hex", Height: 10}}); err == nil {
+		t.Errorf("Transactions.ReplaceTransactionsAbove with an invalid hash: nil error")
+	}
+	got, _ = r.AccountTransactions(address)
+	if heights := txHeights(got); !reflect.DeepEqual(heights, []uint{8, 6, 3}) {
+		t.Errorf("Transactions.AccountTransactions after a failed ReplaceTransactionsAbove: %v, want [8 6 3]", heights)
+	}
+
+	// a busy node replaces far more than fits in one 64KB value
+	many := make([]pocket.Transaction, 1000)
+	for i := range many {
+		many[i] = pocket.Transaction{Hash: fmt.Sprintf("%064x", i+1), Height: uint(10 + i), Type: pocket.TypeClaim, NumRelays: 10}
+	}
+	if err = r.ReplaceTransactionsAbove(address, 6, many); err != nil {
+		t.Errorf("Transactions.ReplaceTransactionsAbove of %d txs: %v", len(many), err)
+	}
+	got, _ = r.AccountTransactions(address)
+	if len(got) != len(many)+2 || got[0].Height != 1009 || got[len(got)-1].Height != 3 {
+		t.Errorf("Transactions.AccountTransactions after replacing %d txs: %d txs, want %d", len(many), len(got), len(many)+2)
+	}
+
+	if _, exists, err := r.Checkpoint(address); err != nil || exists {
+		t.Errorf("Transactions.Checkpoint before it was set: exists %t, err %v, want false, nil", exists, err)
+	}
+	if err = r.SetCheckpoint(address, 42); err != nil {
+		t.Errorf("Transactions.SetCheckpoint: %v", err)
+	}
+	if h, exists, err := r.Checkpoint(address); err != nil || !exists || h != 42 {
+		t.Errorf("Transactions.Checkpoint: %d, %t, %v, want 42, true, nil", h, exists, err)
+	}
+	if addresses, err := r.Addresses(); err != nil || !reflect.DeepEqual(addresses, []string{strings.ToLower(address)}) {
+		t.Errorf("Transactions.Addresses: %v, %v, want [%s], nil", addresses, err, strings.ToLower(address))
+	}
+	if _, exists, _ := r.Checkpoint("FF"); exists {
+		t.Errorf("Transactions.Checkpoint of another address: exists, want missing")
+	}
+
+	if err = r.SetTransactions("not hex", txs); err == nil {
+		t.Errorf("Transactions.SetTransactions with an invalid address: nil error")
+	}
+}
+
+func txHeights(txs []pocket.Transaction) []uint {
+	heights := make([]uint, len(txs))
+	for i, tx := range txs {
+		heights[i] = tx.Height
+	}
+	return heights
+}
+
+func stakes(t *testing.T, r store.StakesRepo) {
+	const address = "AB12CD34"
+
+	if _, exists, err := r.Get(address, 10); err != nil || exists {
+		t.Errorf("Stakes.Get of a missing stake: exists %t, err %v, want false, nil", exists, err)
+	}
+
+	// a stake of 0 is stored like any other, and the address is case insensitive
+	for h, stake := range map[uint]uint{10: 15000000000, 11: 0} {
+		if err := r.Set(address, h, stake); err != nil {
+			t.Errorf("Stakes.Set(%d): %v", h, err)
+		}
+		if got, exists, err := r.Get(strings.ToLower(address), h); err != nil || !exists || got != stake {
+			t.Errorf("Stakes.Get(%d): %d, %t, %v, want %d, true, nil", h, got, exists, err, stake)
+		}
+	}
+	if _, exists, _ := r.Get("FF", 10); exists {
+		t.Errorf("Stakes.Get of another address: exists, want missing")
+	}
+	if err := r.Set("not hex", 10, 1); err == nil {
+		t.Errorf("Stakes.Set with an invalid address: nil error")
+	}
+}
+
+func fleets(t *testing.T, r store.FleetsRepo) {
+	if _, exists, err := r.Get("main"); err != nil || exists {
+		t.Errorf("Fleets.Get of a missing fleet: exists %t, err %v, want false, nil", exists, err)
+	}
+
+	created := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	fleets := []pocket.Fleet{
+		{Name: "main", Addresses: []string{"AA", "BB"}, CreatedAt: created, UpdatedAt: created},
+		{Name: "backup", Addresses: []string{"CC"}, CreatedAt: created, UpdatedAt: created},
+	}
+	for _, f := range fleets {
+		if err := r.Set(f); err != nil {
+			t.Errorf("Fleets.Set(%s): %v", f.Name, err)
+		}
+	}
+
+	got, exists, err := r.Get("main")
+	if err != nil || !exists || got.Name != "main" || !reflect.DeepEqual(got.Addresses, fleets[0].Addresses) || !got.CreatedAt.Equal(created) {
+		t.Errorf("Fleets.Get after Set: %+v, %t, %v, want %+v, true, nil", got, exists, err, fleets[0])
+	}
+
+	list, err := r.List()
+	if err != nil || len(list) != 2 || list[0].Name != "backup" || list[1].Name != "main" {
+		t.Errorf("Fleets.List: %+v, %v, want backup and main, ordered by name", list, err)
+	}
+
+	if err = r.Delete("main"); err != nil {
+		t.Errorf("Fleets.Delete: %v", err)
+	}
+	if _, exists, _ := r.Get("main"); exists {
+		t.Errorf("Fleets.Get after Delete: exists, want missing")
+	}
+	if err = r.Delete("missing"); err != nil {
+		t.Errorf("Fleets.Delete of a missing fleet: %v, want nil", err)
+	}
+}
+
+// keys stores an entry in every repository under keys that share a height or a number, and whose
+// parts would run together if they were joined, and checks that none of them overwrites another.
+func keys(t *testing.T, s store.Store) {
+	at := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.BlockTimes().Set(71, at); err != nil {
+		t.Errorf("BlockTimes.Set: %v", err)
+	}
+	if err := s.BlockTimes().SetHighWaterMark(7); err != nil {
+		t.Errorf("BlockTimes.SetHighWaterMark: %v", err)
+	}
+
+	// "7" + "1pos" and "71" + "pos" are the same string
+	params := map[string]pocket.Params{
+		"1pos": {RelaysToTokensMultiplier: 1, ClaimExpirationBlocks: 120},
+		"pos":  {RelaysToTokensMultiplier: 2, ClaimExpirationBlocks: 240},
+	}
+	if err := s.Params().Set("1pos", 7, params["1pos"]); err != nil {
+		t.Errorf("Params.Set: %v", err)
+	}
+	if err := s.Params().Set("pos", 71, params["pos"]); err != nil {
+		t.Errorf("Params.Set: %v", err)
+	}
+	if err := s.Params().SetAll(71, allParams("c")); err != nil {
+		t.Errorf("Params.SetAll: %v", err)
+	}
+
+	const address = "71"
+	if err := s.Transactions().SetTransactions(address, []pocket.Transaction{{Hash: "71", Height: 71}}); err != nil {
+		t.Errorf("Transactions.SetTransactions: %v", err)
+	}
+	if err := s.Transactions().SetCheckpoint(address, 7); err != nil {
+		t.Errorf("Transactions.SetCheckpoint: %v", err)
+	}
+	if err := s.Fleets().Set(pocket.Fleet{Name: "71", Addresses: []string{address}}); err != nil {
+		t.Errorf("Fleets.Set: %v", err)
+	}
+
+	if got, exists, err := s.BlockTimes().Get(71); err != nil || !exists || !got.Equal(at) {
+		t.Errorf("BlockTimes.Get: %s, %t, %v, want %s", got, exists, err, at)
+	}
+	wantRange(t, s.BlockTimes(), 0, 0, []uint{71})
+	if got, exists, err := s.BlockTimes().HighWaterMark(); err != nil || !exists || got != 7 {
+		t.Errorf("BlockTimes.HighWaterMark: %d, %t, %v, want 7", got, exists, err)
+	}
+	if got, exists, err := s.Params().Get("1pos", 7); err != nil || !exists || got != params["1pos"] {
+		t.Errorf("Params.Get(1pos, 7): %+v, %t, %v, want %+v", got, exists, err, params["1pos"])
+	}
+	if got, exists, err := s.Params().Get("pos", 71); err != nil || !exists || got != params["pos"] {
+		t.Errorf("Params.Get(pos, 71): %+v, %t, %v, want %+v", got, exists, err, params["pos"])
+	}
+	wantAll(t, s.Params(), 71, allParams("c"))
+	if txs, err := s.Transactions().AccountTransactions(address); err != nil || len(txs) != 1 || txs[0].Height != 71 {
+		t.Errorf("Transactions.AccountTransactions: %+v, %v, want the tx at 71", txs, err)
+	}
+	if got, exists, err := s.Transactions().Checkpoint(address); err != nil || !exists || got != 7 {
+		t.Errorf("Transactions.Checkpoint: %d, %t, %v, want 7", got, exists, err)
+	}
+	if f, exists, err := s.Fleets().Get("71"); err != nil || !exists || !reflect.DeepEqual(f.Addresses, []string{address}) {
+		t.Errorf("Fleets.Get: %+v, %t, %v, want the fleet of %s", f, exists, err, address)
+	}
+}
+
+// concurrency writes and reads block times from several goroutines at once.
+func concurrency(t *testing.T, r store.BlockTimesRepo) {
+	const perWriter = 50
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrentWriters; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				h := uint(1000 + w*perWriter + i)
+				at := base.Add(time.Duration(h) * time.Minute)
+				if err := r.Set(h, at); err != nil {
+					t.Errorf("concurrent BlockTimes.Set(%d): %v", h, err)
+					return
+				}
+				if got, exists, err := r.Get(h); err != nil || !exists || !got.Equal(at) {
+					t.Errorf("concurrent BlockTimes.Get(%d): %s, %t, %v, want %s", h, got, exists, err, at)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+func allParams(value string) pocket.AllParams {
+	group := func(key string) pocket.ParamGroup {
+		return pocket.ParamGroup{{Key: key, Value: value}}
+	}
+
+	return pocket.AllParams{
+		AppParams:    group("application/MaxApplications"),
+		AuthParams:   group("auth/MaxMemoCharacters"),
+		GovParams:    group("gov/acl"),
+		NodeParams:   group("pos/RelaysToTokensMultiplier"),
+		PocketParams: group("pocketcore/ClaimExpiration"),
+	}
+}
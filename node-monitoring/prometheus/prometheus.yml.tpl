@@ -38,3 +38,6 @@ scrape_configs:
     scrape_interval: 5s
     static_configs:
     - targets: [__PROMETHEUS_TARGETS__]
+  - job_name: 'monitoring-service'
+    static_configs:
+    - targets: [__MONITORING_SERVICE_TARGETS__]
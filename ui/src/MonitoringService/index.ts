@@ -34,7 +34,8 @@ export const getNode = async (address: string): Promise<CryptoNode> => {
 }
 
 export  const getClaims = async (address: string): Promise<MonthlyReward[]> => {
-    const url = `${RPC_URL}/node/${address}/rewards`;
+    const tz = Intl.DateTimeFormat().resolvedOptions().timeZone;
+    const url = `${RPC_URL}/node/${address}/rewards?tz=${encodeURIComponent(tz)}`;
     return axios.get(url).then((result) => {
         let rewards: MonthlyReward[] = result.data.data as MonthlyReward[];
 
@@ -1,20 +1,20 @@
 import axios from "axios";
+import {RPC_URL} from "../configuration";
 import {RelayTestResponse} from "../types/relay-test-response";
 import {PingTestResponse} from "../types/ping-test-response";
 
-const POKT_LINT_URL = 'https://2eqrf8goof.execute-api.us-east-1.amazonaws.com/prod';
 const HTTP_STATUS_OK = 200;
 const httpClientTimeout = 30000;
-const RELAY_TEST_PATH = 'relay-test-qa';
+const RELAY_SUITE_PATH = 'tests/relay-suite';
+const PING_PATH = 'tests/ping';
 
-export const simulateRelays = async (nodeURL: string, nodeID: string, chains: string[]): Promise<any> => {
-    const url = `${POKT_LINT_URL}/${RELAY_TEST_PATH}`
+export const simulateRelays = async (nodeURL: string, chains: string[]): Promise<any> => {
+    const url = `${RPC_URL}/${RELAY_SUITE_PATH}`
     var data;
     console.log("simulateRelays", chains)
     data =  {
         node_url: nodeURL,
-        node_id: nodeID,
-        chain_ids: chains
+        chains: chains
     }
 
     return axios.post(url,data, {timeout: httpClientTimeout})
@@ -23,7 +23,7 @@ export const simulateRelays = async (nodeURL: string, nodeID: string, chains: st
                 throw new Error(`${result.status}: ${result.statusText}`)
             }
 
-            return result.data as Record<string, RelayTestResponse>;
+            return result.data.data.results as Record<string, RelayTestResponse>;
         })
         .catch((err) => {
             console.error(url, err);
@@ -32,20 +32,21 @@ export const simulateRelays = async (nodeURL: string, nodeID: string, chains: st
 }
 
 export const pingTest = async (nodeURL: string, numPings: number): Promise<any> => {
-    const url = `${POKT_LINT_URL}/ping-test-qa`
+    const url = `${RPC_URL}/${PING_PATH}`
 
     return axios.post(url, {
         node_url: nodeURL,
         num_pings: numPings
-    })
+    }, {timeout: httpClientTimeout})
         .then(async (result) => {
-            console.log(result);
-            return result.data as PingTestResponse;
+            if(result.status !== HTTP_STATUS_OK){
+                throw new Error(`${result.status}: ${result.statusText}`)
+            }
+
+            return result.data.data as PingTestResponse;
         })
         .catch((err) => {
-            console.error(err);
+            console.error(url, err);
             throw err;
         })
 }
-
-
@@ -43,7 +43,7 @@ const SingleResult = (props: SingleResultProps) => {
             <GridItem>{props.relayTestResponse.duration_avg_ms.toFixed(2)} ms</GridItem>
             <GridItem textAlign={"right"}>{props.relayTestResponse.status_code}</GridItem>
             <GridItem textAlign={"right"}>
-                {props.relayTestResponse.status_code === 200 ?
+                {props.relayTestResponse.success ?
                     (<CheckCircleIcon color={"green.300"}/>) :
                     (<WarningTwoIcon color={"red.400"}/>)
                 }
@@ -69,8 +69,19 @@ const SingleResult = (props: SingleResultProps) => {
             <GridItem colSpan={5} p={3} lineHeight={1}>
                 <Collapse in={isOpen} animateOpacity={true}>
                     <Box textAlign={"center"} mb={2}>
-                        Avg: {props.relayTestResponse.duration_min_ms} / Min: {props.relayTestResponse.duration_min_ms} / Max: {props.relayTestResponse.duration_max_ms}
+                        Avg: {props.relayTestResponse.duration_avg_ms.toFixed(2)} / Min: {props.relayTestResponse.duration_min_ms} / Max: {props.relayTestResponse.duration_max_ms}
                     </Box>
+                    {props.relayTestResponse.height !== undefined && (
+                        <Box textAlign={"center"} mb={2}>
+                            Height: {props.relayTestResponse.height}
+                            {props.relayTestResponse.height_lag !== undefined && (
+                                <> / Reference: {props.relayTestResponse.reference_height} (lag: {props.relayTestResponse.height_lag} blocks)</>
+                            )}
+                        </Box>
+                    )}
+                    {props.relayTestResponse.message && (
+                        <Box textAlign={"center"} mb={2}>{props.relayTestResponse.message}</Box>
+                    )}
                     <ReactJson
                         src={props.relayTestResponse.relay_request}
                         displayDataTypes={false}
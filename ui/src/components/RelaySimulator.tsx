@@ -46,7 +46,7 @@ export const RelaySimulator = () => {
 
             console.log("runTests", chains);
 
-            return simulateRelays(nodeURL, node.address, chains).then((result) => {
+            return simulateRelays(nodeURL, chains).then((result) => {
                 console.log("Done", result);
                 if(result.errorMessage) {
                     fail(result.errorMessage)
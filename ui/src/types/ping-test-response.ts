@@ -1,4 +1,5 @@
 export type PingTestResponse = {
+    node_url: string
     num_sent: number
     num_ok: number
     min_time_ms: number
@@ -11,4 +12,5 @@ export type PingTestResponse = {
 export type PingResponse = {
     duration_ms: number
     status_code: number
+    error?: string
 }
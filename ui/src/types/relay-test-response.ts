@@ -9,6 +9,10 @@ export type RelayTestResponse = {
     relay_responses: RelayResponse[]
     status_code: number
     success: boolean
+    height?: number
+    reference_height?: number
+    height_lag?: number
+    reference_error?: string
 }
 
 export type RelayRequest = {
@@ -20,5 +24,8 @@ export type RelayRequest = {
 export type RelayResponse = {
     duration_ms: number
     status_code: number
+    valid: boolean
+    height?: number
     data: any
+    error?: string
 }
\ No newline at end of file